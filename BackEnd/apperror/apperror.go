@@ -0,0 +1,192 @@
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Kind classifies an application error. Each kind is answered with one HTTP status
+type Kind string
+
+const (
+	KindBadRequest       Kind = "bad-request"
+	KindValidation       Kind = "validation"
+	KindUnauthorized     Kind = "unauthorized"
+	KindForbidden        Kind = "forbidden"
+	KindNotFound         Kind = "not-found"
+	KindMethodNotAllowed Kind = "method-not-allowed"
+	KindConflict         Kind = "conflict"
+	KindTooLarge         Kind = "too-large"
+	KindRateLimited      Kind = "rate-limited"
+	KindInternal         Kind = "internal"
+)
+
+var kindStatus = map[Kind]int{
+	KindBadRequest:       http.StatusBadRequest,
+	KindValidation:       http.StatusUnprocessableEntity,
+	KindUnauthorized:     http.StatusUnauthorized,
+	KindForbidden:        http.StatusForbidden,
+	KindNotFound:         http.StatusNotFound,
+	KindMethodNotAllowed: http.StatusMethodNotAllowed,
+	KindConflict:         http.StatusConflict,
+	KindTooLarge:         http.StatusRequestEntityTooLarge,
+	KindRateLimited:      http.StatusTooManyRequests,
+	KindInternal:         http.StatusInternalServerError,
+}
+
+// TypePrefix starts the type URI of every problem; the kind completes it
+const TypePrefix = "urn:forum:problem:"
+
+// Error is an error meant for the user who made the request. Detail is shown to them, while Err,
+// the cause, is only logged
+type Error struct {
+	Kind   Kind
+	Detail string
+	// Fields maps the invalid fields of a validation error to what is wrong with them
+	Fields map[string]string
+	// Extensions are further members of the problem, such as the limit a request went over
+	Extensions map[string]interface{}
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status is the HTTP status the error is answered with
+func (e *Error) Status() int {
+	if status, ok := kindStatus[e.Kind]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Wrap records the cause of the error
+func (e *Error) Wrap(err error) *Error {
+	e.Err = err
+	return e
+}
+
+// With adds the extension member key to the problem
+func (e *Error) With(key string, value interface{}) *Error {
+	if e.Extensions == nil {
+		e.Extensions = make(map[string]interface{})
+	}
+	e.Extensions[key] = value
+	return e
+}
+
+// Problem describes the error as RFC 7807 problem details of a request to instance
+func (e *Error) Problem(instance string) Problem {
+	return Problem{
+		Type:       TypePrefix + string(e.Kind),
+		Title:      http.StatusText(e.Status()),
+		Status:     e.Status(),
+		Detail:     e.Detail,
+		Instance:   instance,
+		Errors:     e.Fields,
+		Extensions: e.Extensions,
+	}
+}
+
+// Problem is an RFC 7807 problem details object, served as application/problem+json
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Errors maps invalid fields to what is wrong with them
+	Errors map[string]string `json:"errors,omitempty"`
+	// Extensions are written as members of the problem next to the standard ones
+	Extensions map[string]interface{} `json:"-"`
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	standard, err := json.Marshal(problem(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return standard, err
+	}
+
+	members := make(map[string]interface{}, len(p.Extensions))
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+	if err := json.Unmarshal(standard, &members); err != nil {
+		return nil, err
+	}
+	return json.Marshal(members)
+}
+
+func New(kind Kind, detail string) *Error {
+	return &Error{Kind: kind, Detail: detail}
+}
+
+func BadRequest(detail string) *Error {
+	return New(KindBadRequest, detail)
+}
+
+// Validation reports invalid input, with what is wrong with each field in fields
+func Validation(detail string, fields map[string]string) *Error {
+	return &Error{Kind: KindValidation, Detail: detail, Fields: fields}
+}
+
+func Unauthorized(detail string) *Error {
+	return New(KindUnauthorized, detail)
+}
+
+func Forbidden(detail string) *Error {
+	return New(KindForbidden, detail)
+}
+
+func NotFound(detail string) *Error {
+	return New(KindNotFound, detail)
+}
+
+func TooLarge(detail string) *Error {
+	return New(KindTooLarge, detail)
+}
+
+func Conflict(detail string) *Error {
+	return New(KindConflict, detail)
+}
+
+func RateLimited(detail string) *Error {
+	return New(KindRateLimited, detail)
+}
+
+func Internal(detail string) *Error {
+	return New(KindInternal, detail)
+}
+
+// FromStatus returns the error answered with status, for code that decided on a status already
+func FromStatus(status int, detail string) *Error {
+	for kind, kindStatus := range kindStatus {
+		if kindStatus == status {
+			return New(kind, detail)
+		}
+	}
+	if status >= 400 && status < 500 {
+		return New(KindBadRequest, detail)
+	}
+	return New(KindInternal, detail)
+}
+
+// From returns err as an application error. Errors that are not one become internal errors whose
+// detail does not leak the cause
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal("Something went wrong.").Wrap(err)
+}
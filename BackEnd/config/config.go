@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+)
+
+// String returns the value of the environment variable key, or def when it is unset or empty
+func String(key, def string) string {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// List returns the comma-separated values of the environment variable key
+func List(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Int returns the integer value of the environment variable key, or def when it is unset or invalid
+func Int(key string, def int) int {
+	value := String(key, "")
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		logger.Warning("Ignoring invalid integer %q for %s", value, key)
+		return def
+	}
+	return n
+}
+
+// Bool returns the boolean value of the environment variable key, or def when it is unset or invalid
+func Bool(key string, def bool) bool {
+	value := String(key, "")
+	if value == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.Warning("Ignoring invalid boolean %q for %s", value, key)
+		return def
+	}
+	return b
+}
+
+// Bytes parses a size such as "512KB", "100MB" or "1GB" from the environment variable key
+func Bytes(key string, def int64) int64 {
+	value := strings.ToUpper(String(key, ""))
+	if value == "" {
+		return def
+	}
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(value, unit.suffix) {
+			multiplier = unit.size
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		logger.Warning("Ignoring invalid size %q for %s", os.Getenv(key), key)
+		return def
+	}
+	return n * multiplier
+}
+
+// Duration parses a duration such as "90m", "72h" or "30d" from the environment variable key
+func Duration(key string, def time.Duration) time.Duration {
+	value := String(key, "")
+	if value == "" {
+		return def
+	}
+
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err == nil && n >= 0 {
+			return time.Duration(n) * 24 * time.Hour
+		}
+	} else if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d
+	}
+
+	logger.Warning("Ignoring invalid duration %q for %s", value, key)
+	return def
+}
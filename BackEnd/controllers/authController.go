@@ -6,12 +6,16 @@ import (
 	"net/mail"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/Raymond9734/forum.git/BackEnd/logger"
 	"github.com/Raymond9734/forum.git/BackEnd/models"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrAccountTaken is returned when registering with an email or username another account uses
+var ErrAccountTaken = errors.New("email or username already taken")
+
 type AuthController struct {
 	DB *sql.DB
 }
@@ -32,10 +36,11 @@ func (ac *AuthController) RegisterUser(email, username, password string) (int64,
 		return 0, errors.New("internal server error")
 	}
 
-	result, err := ac.DB.Exec("INSERT INTO users (email, username, password) VALUES (?, ?, ?)", email, username, hashedPassword)
+	result, err := ac.DB.Exec("INSERT INTO users (email, username, password, created_at) VALUES (?, ?, ?, ?)",
+		email, username, hashedPassword, time.Now())
 	if err != nil {
 		logger.Warning("Registration failed - duplicate email or username: %v", err)
-		return 0, errors.New("email or username already taken")
+		return 0, ErrAccountTaken
 	}
 
 	// Get the auto-generated user ID
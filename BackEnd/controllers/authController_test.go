@@ -12,7 +12,7 @@ import (
 
 func init() {
 	// Initialize the logger for tests
-	logger.Init()
+	logger.Init(logger.Options{})
 }
 
 func TestAuthController_RegisterUser(t *testing.T) {
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+var (
+	// ErrCannotBlockSelf is returned when a user tries to block or mute themselves
+	ErrCannotBlockSelf = errors.New("you cannot block yourself")
+	// ErrBlockedByAuthor is returned when a user replies to someone who blocked them
+	ErrBlockedByAuthor = errors.New("you cannot reply to this user")
+)
+
+// BlockUser adds blockedID to the blocker's block list, turning a mute into a block;
+// blocking twice is not an error
+func BlockUser(db *sql.DB, blockerID, blockedID int) error {
+	if blockerID == blockedID {
+		return ErrCannotBlockSelf
+	}
+	_, err := db.Exec(`
+		INSERT INTO user_blocks (blocker_id, blocked_id, kind, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(blocker_id, blocked_id) DO UPDATE SET kind = excluded.kind
+	`, blockerID, blockedID, models.BlockKindBlock, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to block user %d: %w", blockedID, err)
+	}
+	return nil
+}
+
+// MuteUser hides mutedID's content from the muter; muting a blocked user keeps the block
+func MuteUser(db *sql.DB, muterID, mutedID int) error {
+	if muterID == mutedID {
+		return ErrCannotBlockSelf
+	}
+	_, err := db.Exec(`
+		INSERT OR IGNORE INTO user_blocks (blocker_id, blocked_id, kind, created_at) VALUES (?, ?, ?, ?)
+	`, muterID, mutedID, models.BlockKindMute, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to mute user %d: %w", mutedID, err)
+	}
+	return nil
+}
+
+// UnblockUser removes blockedID from the blocker's block list, whether blocked or muted
+func UnblockUser(db *sql.DB, blockerID, blockedID int) error {
+	_, err := db.Exec(`DELETE FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?`, blockerID, blockedID)
+	if err != nil {
+		return fmt.Errorf("failed to unblock user %d: %w", blockedID, err)
+	}
+	return nil
+}
+
+// IsBlocked reports whether blockerID has blocked (not merely muted) blockedID
+func IsBlocked(db *sql.DB, blockerID, blockedID int) (bool, error) {
+	var blocked bool
+	err := db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM user_blocks WHERE blocker_id = ? AND blocked_id = ? AND kind = ?)
+	`, blockerID, blockedID, models.BlockKindBlock).Scan(&blocked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check block list: %w", err)
+	}
+	return blocked, nil
+}
+
+// GetBlockList returns the users the given user blocked or muted, most recent first
+func GetBlockList(db *sql.DB, userID int) ([]models.BlockedUser, error) {
+	rows, err := db.Query(`
+		SELECT b.blocked_id, u.username, b.kind, b.created_at
+		FROM user_blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = ?
+		ORDER BY b.created_at DESC, b.id DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch block list: %w", err)
+	}
+	defer rows.Close()
+
+	blocked := make([]models.BlockedUser, 0)
+	for rows.Next() {
+		var b models.BlockedUser
+		if err := rows.Scan(&b.UserID, &b.Username, &b.Kind, &b.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan blocked user: %w", err)
+		}
+		blocked = append(blocked, b)
+	}
+	return blocked, rows.Err()
+}
+
+// HiddenAuthors returns the IDs of the users whose content the viewer blocked or muted
+func HiddenAuthors(db *sql.DB, viewerID int) (map[int]bool, error) {
+	hidden := make(map[int]bool)
+	if viewerID == 0 {
+		return hidden, nil
+	}
+
+	rows, err := db.Query(`SELECT blocked_id FROM user_blocks WHERE blocker_id = ?`, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch block list: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan blocked user: %w", err)
+		}
+		hidden[userID] = true
+	}
+	return hidden, rows.Err()
+}
+
+// FilterHiddenPosts drops the posts written by hidden authors
+func FilterHiddenPosts(posts []models.Post, hidden map[int]bool) []models.Post {
+	if len(hidden) == 0 {
+		return posts
+	}
+	kept := posts[:0]
+	for _, post := range posts {
+		if !hidden[post.UserID] {
+			kept = append(kept, post)
+		}
+	}
+	return kept
+}
+
+// HideComments replaces comments by hidden authors with "[hidden]" placeholders so that the
+// replies of others stay in the tree; hidden comments without visible replies are dropped
+func HideComments(comments []models.Comment, hidden map[int]bool) []models.Comment {
+	if len(hidden) == 0 {
+		return comments
+	}
+	kept := comments[:0]
+	for _, comment := range comments {
+		comment.Replies = HideComments(comment.Replies, hidden)
+		if comment.Quote != nil && hidden[comment.Quote.UserID] {
+			quote := *comment.Quote
+			quote.UserID = 0
+			quote.Author = models.HiddenPlaceholder
+			quote.Excerpt = models.HiddenPlaceholder
+			comment.Quote = &quote
+		}
+		if hidden[comment.UserID] {
+			if len(comment.Replies) == 0 && comment.MoreReplies == 0 {
+				continue
+			}
+			comment.IsHidden = true
+			comment.UserID = 0
+			comment.AuthorReputation = 0
+			comment.Author = models.HiddenPlaceholder
+			comment.Content = models.HiddenPlaceholder
+		}
+		kept = append(kept, comment)
+	}
+	return kept
+}
+
+// CheckCanReply returns ErrBlockedByAuthor if the author of the post, or of the parent comment
+// when parentID is not 0, has blocked the user
+func CheckCanReply(db *sql.DB, userID, postID, parentID int) error {
+	var authorID int
+	var err error
+	if parentID != 0 {
+		err = db.QueryRow(`SELECT user_id FROM comments WHERE id = ?`, parentID).Scan(&authorID)
+	} else {
+		err = db.QueryRow(`SELECT user_id FROM posts WHERE id = ?`, postID).Scan(&authorID)
+	}
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch author: %w", err)
+	}
+
+	blocked, err := IsBlocked(db, authorID, userID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlockedByAuthor
+	}
+	return nil
+}
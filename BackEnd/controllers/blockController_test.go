@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+func TestBlockAndMute(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	for i := 1; i <= 3; i++ {
+		if err := InsertTestUser(db, fmt.Sprintf("user%d@example.com", i), fmt.Sprintf("user%d", i), "password123"); err != nil {
+			t.Fatalf("Failed to insert test user: %v", err)
+		}
+	}
+
+	// user1 blocks user2 and mutes user3; muting user2 afterwards keeps the block
+	if err := BlockUser(db, 1, 2); err != nil {
+		t.Fatalf("BlockUser() error = %v", err)
+	}
+	if err := MuteUser(db, 1, 3); err != nil {
+		t.Fatalf("MuteUser() error = %v", err)
+	}
+	if err := MuteUser(db, 1, 2); err != nil {
+		t.Fatalf("MuteUser() error = %v", err)
+	}
+	if err := MuteUser(db, 1, 1); err != ErrCannotBlockSelf {
+		t.Errorf("MuteUser() self error = %v, want %v", err, ErrCannotBlockSelf)
+	}
+
+	list, err := GetBlockList(db, 1)
+	if err != nil {
+		t.Fatalf("GetBlockList() error = %v", err)
+	}
+	kinds := make(map[string]string)
+	for _, b := range list {
+		kinds[b.Username] = b.Kind
+	}
+	if len(list) != 2 || kinds["user2"] != models.BlockKindBlock || kinds["user3"] != models.BlockKindMute {
+		t.Fatalf("GetBlockList() = %+v", list)
+	}
+	if blocked, _ := IsBlocked(db, 1, 3); blocked {
+		t.Errorf("IsBlocked() = true for a muted user")
+	}
+
+	hidden, err := HiddenAuthors(db, 1)
+	if err != nil {
+		t.Fatalf("HiddenAuthors() error = %v", err)
+	}
+	if len(hidden) != 2 || !hidden[2] || !hidden[3] {
+		t.Fatalf("HiddenAuthors() = %v", hidden)
+	}
+
+	posts := FilterHiddenPosts([]models.Post{{ID: 1, UserID: 1}, {ID: 2, UserID: 2}, {ID: 3, UserID: 3}}, hidden)
+	if len(posts) != 1 || posts[0].ID != 1 {
+		t.Errorf("FilterHiddenPosts() = %+v", posts)
+	}
+
+	// user2's comment stays as a placeholder for user1's reply, user3's lone comment disappears
+	postID, err := NewPostController(db).InsertPost(models.Post{
+		Title: "title", Author: "user1", UserID: 1, Category: "music", Content: "content", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("InsertPost() error = %v", err)
+	}
+	cc := NewCommentController(db)
+	comment := func(userID, parentID int) int {
+		id, err := cc.InsertComment(models.Comment{
+			PostID: postID, UserID: userID, Author: fmt.Sprintf("user%d", userID), Content: "comment", Timestamp: time.Now(),
+			ParentID: sql.NullInt64{Int64: int64(parentID), Valid: parentID != 0},
+		})
+		if err != nil {
+			t.Fatalf("InsertComment() error = %v", err)
+		}
+		return id
+	}
+	blockedComment := comment(2, 0)
+	reply := comment(1, blockedComment)
+	comment(3, 0)
+
+	page, err := cc.GetCommentTreePage(postID, 1, models.CommentSortNew, 0)
+	if err != nil {
+		t.Fatalf("GetCommentTreePage() error = %v", err)
+	}
+	tree := HideComments(page.Comments, hidden)
+	if len(tree) != 1 || !tree[0].IsHidden || tree[0].Content != models.HiddenPlaceholder || tree[0].UserID != 0 ||
+		len(tree[0].Replies) != 1 || tree[0].Replies[0].ID != reply {
+		t.Fatalf("unexpected comment tree: %+v", tree)
+	}
+
+	// The muted user's newer comment has no replies, so it doesn't take up a place in the page
+	single, err := cc.GetCommentPage(postID, 0, 1, models.CommentSortNew, 0, 1)
+	if err != nil {
+		t.Fatalf("GetCommentPage() error = %v", err)
+	}
+	if len(single.Comments) != 1 || single.Comments[0].ID != blockedComment || single.HasMore {
+		t.Errorf("GetCommentPage() = %+v, want only the blocked comment", single)
+	}
+
+	tests := []struct {
+		name     string
+		userID   int
+		parentID int
+		wantErr  error
+	}{
+		{"blocked user comments on the post", 2, 0, ErrBlockedByAuthor},
+		{"blocked user replies to a comment", 2, reply, ErrBlockedByAuthor},
+		{"muted user can still reply", 3, reply, nil},
+		{"blocker can reply to the blocked user", 1, blockedComment, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckCanReply(db, tt.userID, postID, tt.parentID); err != tt.wantErr {
+				t.Errorf("CheckCanReply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := UnblockUser(db, 1, 2); err != nil {
+		t.Fatalf("UnblockUser() error = %v", err)
+	}
+	if err := CheckCanReply(db, 2, postID, 0); err != nil {
+		t.Errorf("CheckCanReply() after unblock error = %v", err)
+	}
+}
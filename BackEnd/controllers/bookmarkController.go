@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+var (
+	ErrBookmarkTargetNotFound = errors.New("the post or comment to bookmark does not exist")
+	ErrBookmarkNotFound       = errors.New("bookmark not found")
+)
+
+const bookmarkExcerptChars = 200
+
+type BookmarkController struct {
+	DB *sql.DB
+}
+
+func NewBookmarkController(db *sql.DB) *BookmarkController {
+	return &BookmarkController{DB: db}
+}
+
+// NormalizeFolder trims a folder name and checks its length; the empty name is the default folder
+func NormalizeFolder(folder string) (string, error) {
+	folder = strings.TrimSpace(folder)
+	if len([]rune(folder)) > models.BookmarkFolderMaxChars {
+		return "", fmt.Errorf("folder names must be at most %d characters", models.BookmarkFolderMaxChars)
+	}
+	return folder, nil
+}
+
+// ToggleBookmark saves the target for the user, or removes the bookmark if it already exists.
+// It reports whether the target is bookmarked afterwards.
+func (bc *BookmarkController) ToggleBookmark(userID int, targetType string, targetID int, folder string) (bool, error) {
+	result, err := bc.DB.Exec(`
+		DELETE FROM bookmarks WHERE user_id = ? AND target_type = ? AND target_id = ?
+	`, userID, targetType, targetID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove bookmark: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n > 0 {
+		return false, nil
+	}
+
+	var table string
+	switch targetType {
+	case models.BookmarkPost:
+		table = "posts"
+	case models.BookmarkComment:
+		table = "comments"
+	default:
+		return false, fmt.Errorf("unknown bookmark type %q", targetType)
+	}
+	var exists bool
+	err = bc.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM `+table+` WHERE id = ? AND deleted_at IS NULL)`, targetID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check bookmark target: %w", err)
+	}
+	if !exists {
+		return false, ErrBookmarkTargetNotFound
+	}
+
+	_, err = bc.DB.Exec(`
+		INSERT OR IGNORE INTO bookmarks (user_id, target_type, target_id, folder, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, targetType, targetID, folder, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to insert bookmark: %w", err)
+	}
+	return true, nil
+}
+
+// MoveBookmark files one of the user's bookmarks under another folder
+func (bc *BookmarkController) MoveBookmark(userID, bookmarkID int, folder string) error {
+	result, err := bc.DB.Exec(`UPDATE bookmarks SET folder = ? WHERE id = ? AND user_id = ?`, folder, bookmarkID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to move bookmark: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrBookmarkNotFound
+	}
+	return nil
+}
+
+// GetFolders lists the folders the user has filed bookmarks under
+func (bc *BookmarkController) GetFolders(userID int) ([]string, error) {
+	rows, err := bc.DB.Query(`
+		SELECT DISTINCT folder FROM bookmarks WHERE user_id = ? AND folder != '' ORDER BY folder
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bookmark folders: %w", err)
+	}
+	defer rows.Close()
+
+	var folders []string
+	for rows.Next() {
+		var folder string
+		if err := rows.Scan(&folder); err != nil {
+			return nil, fmt.Errorf("failed to scan bookmark folder: %w", err)
+		}
+		folders = append(folders, folder)
+	}
+	return folders, rows.Err()
+}
+
+// visibleBookmarks selects the user's bookmarks whose target has not been deleted,
+// optionally restricted to a folder
+const visibleBookmarks = `
+	FROM bookmarks b
+	LEFT JOIN posts p ON b.target_type = 'post' AND p.id = b.target_id AND p.deleted_at IS NULL
+	LEFT JOIN comments c ON b.target_type = 'comment' AND c.id = b.target_id AND c.deleted_at IS NULL
+	LEFT JOIN posts cp ON cp.id = c.post_id AND cp.deleted_at IS NULL
+	WHERE b.user_id = ? AND (? = '' OR b.folder = ?)
+	  AND (p.id IS NOT NULL OR cp.id IS NOT NULL)`
+
+// GetBookmarks returns one page of the user's bookmarks, newest first; an empty folder lists all of them
+func (bc *BookmarkController) GetBookmarks(userID int, folder string, page int) (models.BookmarkPage, error) {
+	result := models.BookmarkPage{Folder: folder, Page: page, Bookmarks: make([]models.Bookmark, 0)}
+
+	var total int
+	if err := bc.DB.QueryRow(`SELECT COUNT(*) `+visibleBookmarks, userID, folder, folder).Scan(&total); err != nil {
+		return result, fmt.Errorf("failed to count bookmarks: %w", err)
+	}
+	result.TotalPages = (total + models.BookmarkPageSize - 1) / models.BookmarkPageSize
+	if result.Page < 1 {
+		result.Page = 1
+	}
+
+	rows, err := bc.DB.Query(`
+		SELECT b.id, b.target_type, b.target_id, b.folder, b.created_at,
+		       COALESCE(p.id, cp.id), COALESCE(p.title, cp.title),
+		       COALESCE(p.author, c.author), COALESCE(p.content, c.content)
+		`+visibleBookmarks+`
+		ORDER BY b.created_at DESC, b.id DESC
+		LIMIT ? OFFSET ?
+	`, userID, folder, folder, models.BookmarkPageSize, (result.Page-1)*models.BookmarkPageSize)
+	if err != nil {
+		return result, fmt.Errorf("failed to fetch bookmarks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b models.Bookmark
+		err := rows.Scan(&b.ID, &b.Type, &b.TargetID, &b.Folder, &b.CreatedAt, &b.PostID, &b.Title, &b.Author, &b.Excerpt)
+		if err != nil {
+			return result, fmt.Errorf("failed to scan bookmark: %w", err)
+		}
+		if runes := []rune(b.Excerpt); len(runes) > bookmarkExcerptChars {
+			b.Excerpt = string(runes[:bookmarkExcerptChars]) + "..."
+		}
+		result.Bookmarks = append(result.Bookmarks, b)
+	}
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	result.Folders, err = bc.GetFolders(userID)
+	return result, err
+}
+
+// BookmarkedIDs returns the IDs of the targets of the given type the user has bookmarked
+func (bc *BookmarkController) BookmarkedIDs(userID int, targetType string) (map[int]bool, error) {
+	ids := make(map[int]bool)
+	if userID == 0 {
+		return ids, nil
+	}
+
+	rows, err := bc.DB.Query(`SELECT target_id FROM bookmarks WHERE user_id = ? AND target_type = ?`, userID, targetType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bookmarked IDs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan bookmarked ID: %w", err)
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// MarkBookmarkedComments sets IsBookmarked throughout a comment tree
+func MarkBookmarkedComments(comments []models.Comment, ids map[int]bool) {
+	for i := range comments {
+		comments[i].IsBookmarked = ids[comments[i].ID]
+		MarkBookmarkedComments(comments[i].Replies, ids)
+	}
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+func TestBookmarkController(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := InsertTestUser(db, "test@example.com", "testuser", "password123"); err != nil {
+		t.Fatalf("Failed to insert test user: %v", err)
+	}
+
+	pc := NewPostController(db)
+	cc := NewCommentController(db)
+	bc := NewBookmarkController(db)
+
+	var postIDs []int
+	for i := 0; i < models.BookmarkPageSize+1; i++ {
+		id, err := pc.InsertPost(models.Post{
+			Title: "title", Author: "testuser", UserID: 1, Category: "music", Content: "content", Timestamp: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("InsertPost() error = %v", err)
+		}
+		postIDs = append(postIDs, id)
+	}
+	commentID, err := cc.InsertComment(models.Comment{
+		PostID: postIDs[0], UserID: 1, Author: "testuser", Content: "comment", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("InsertComment() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		targetType string
+		targetID   int
+		want       bool
+		wantErr    error
+	}{
+		{"save post", models.BookmarkPost, postIDs[0], true, nil},
+		{"unsave post", models.BookmarkPost, postIDs[0], false, nil},
+		{"save comment", models.BookmarkComment, commentID, true, nil},
+		{"missing post", models.BookmarkPost, 999, false, ErrBookmarkTargetNotFound},
+		{"missing comment", models.BookmarkComment, 999, false, ErrBookmarkTargetNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bc.ToggleBookmark(1, tt.targetType, tt.targetID, "")
+			if err != tt.wantErr {
+				t.Fatalf("ToggleBookmark() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ToggleBookmark() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Save every post, the first one in a folder
+	for i, id := range postIDs {
+		folder := ""
+		if i == 0 {
+			folder = "music"
+		}
+		if _, err := bc.ToggleBookmark(1, models.BookmarkPost, id, folder); err != nil {
+			t.Fatalf("ToggleBookmark() error = %v", err)
+		}
+	}
+
+	first, err := bc.GetBookmarks(1, "", 1)
+	if err != nil {
+		t.Fatalf("GetBookmarks() error = %v", err)
+	}
+	if len(first.Bookmarks) != models.BookmarkPageSize || first.TotalPages != 2 {
+		t.Fatalf("GetBookmarks() page 1 = %d bookmarks of %d pages", len(first.Bookmarks), first.TotalPages)
+	}
+	second, err := bc.GetBookmarks(1, "", 2)
+	if err != nil {
+		t.Fatalf("GetBookmarks() error = %v", err)
+	}
+	if len(second.Bookmarks) != 2 {
+		t.Errorf("GetBookmarks() page 2 = %d bookmarks, want 2", len(second.Bookmarks))
+	}
+
+	music, err := bc.GetBookmarks(1, "music", 1)
+	if err != nil {
+		t.Fatalf("GetBookmarks() error = %v", err)
+	}
+	if len(music.Bookmarks) != 1 || music.Bookmarks[0].TargetID != postIDs[0] {
+		t.Fatalf("GetBookmarks(music) = %+v", music.Bookmarks)
+	}
+	if len(music.Folders) != 1 || music.Folders[0] != "music" {
+		t.Errorf("Folders = %v, want [music]", music.Folders)
+	}
+
+	// Moving the comment bookmark into the folder
+	var commentBookmark models.Bookmark
+	for _, b := range append(first.Bookmarks, second.Bookmarks...) {
+		if b.Type == models.BookmarkComment {
+			commentBookmark = b
+		}
+	}
+	if commentBookmark.PostID != postIDs[0] || commentBookmark.Excerpt != "comment" {
+		t.Fatalf("unexpected comment bookmark: %+v", commentBookmark)
+	}
+	if err := bc.MoveBookmark(2, commentBookmark.ID, "music"); err != ErrBookmarkNotFound {
+		t.Errorf("MoveBookmark() by another user error = %v, want %v", err, ErrBookmarkNotFound)
+	}
+	if err := bc.MoveBookmark(1, commentBookmark.ID, "music"); err != nil {
+		t.Fatalf("MoveBookmark() error = %v", err)
+	}
+	if music, _ := bc.GetBookmarks(1, "music", 1); len(music.Bookmarks) != 2 {
+		t.Errorf("GetBookmarks(music) after move = %d bookmarks, want 2", len(music.Bookmarks))
+	}
+
+	// Deleted targets drop out of the saved list
+	if err := pc.DeletePost(postIDs[0], 1); err != nil {
+		t.Fatalf("DeletePost() error = %v", err)
+	}
+	if music, _ := bc.GetBookmarks(1, "music", 1); len(music.Bookmarks) != 0 {
+		t.Errorf("GetBookmarks(music) after delete = %d bookmarks, want 0", len(music.Bookmarks))
+	}
+
+	ids, err := bc.BookmarkedIDs(1, models.BookmarkPost)
+	if err != nil {
+		t.Fatalf("BookmarkedIDs() error = %v", err)
+	}
+	if len(ids) != len(postIDs) || !ids[postIDs[1]] {
+		t.Errorf("BookmarkedIDs() = %v", ids)
+	}
+	comments := []models.Comment{{ID: commentID, Replies: []models.Comment{{ID: 999}}}}
+	MarkBookmarkedComments(comments, map[int]bool{commentID: true})
+	if !comments[0].IsBookmarked || comments[0].Replies[0].IsBookmarked {
+		t.Errorf("MarkBookmarkedComments() = %+v", comments)
+	}
+
+	if _, err := NormalizeFolder(string(make([]rune, models.BookmarkFolderMaxChars+1))); err == nil {
+		t.Errorf("NormalizeFolder() accepted an overlong folder name")
+	}
+}
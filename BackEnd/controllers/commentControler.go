@@ -2,12 +2,24 @@ package controllers
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/Raymond9734/forum.git/BackEnd/models"
 )
 
+var (
+	// ErrCommentNotFound is returned when a thread is requested for a comment that is not on the post
+	ErrCommentNotFound = errors.New("comment not found")
+	// ErrQuoteWithoutParent is returned when a top level comment carries a quote
+	ErrQuoteWithoutParent = errors.New("only replies can quote a comment")
+	// ErrQuoteMismatch is returned when the quoted text does not appear in the parent comment
+	ErrQuoteMismatch = errors.New("quoted text does not appear in the comment being replied to")
+)
+
 type CommentController struct {
 	DB *sql.DB
 }
@@ -47,16 +59,29 @@ func (cCtrl *CommentController) InsertComment(comment models.Comment) (int, erro
 			return 0, fmt.Errorf("failed to check comment depth: %w", err)
 		}
 
-		if depth >= 4 {
-			return 0, fmt.Errorf("maximum nesting depth (4) reached")
+		if depth >= models.MaxCommentDepth-1 {
+			return 0, fmt.Errorf("maximum nesting depth (%d) reached", models.MaxCommentDepth)
 		}
 	}
 
-	result, err := cCtrl.DB.Exec(`
-		INSERT INTO comments (post_id, user_id, author, content, likes, dislikes, user_vote, timestamp, parent_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+	tx, err := cCtrl.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert comment: %w", err)
+	}
+	defer tx.Rollback()
+
+	var quoteID sql.NullInt64
+	var quoteText string
+	if comment.Quote != nil {
+		quoteID = sql.NullInt64{Int64: int64(comment.Quote.ID), Valid: true}
+		quoteText = comment.Quote.Excerpt
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO comments (post_id, user_id, author, content, likes, dislikes, user_vote, timestamp, parent_id, quote_id, quote_text)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
 	`, comment.PostID, comment.UserID, comment.Author, comment.Content, comment.Likes, comment.Dislikes,
-		comment.UserVote, comment.Timestamp, comment.ParentID)
+		comment.UserVote, comment.Timestamp, comment.ParentID, quoteID, quoteText)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert comment: %w", err)
 	}
@@ -66,123 +91,315 @@ func (cCtrl *CommentController) InsertComment(comment models.Comment) (int, erro
 		return 0, fmt.Errorf("failed to get last insert ID: %w", err)
 	}
 
+	// Keep the post's comment count used for ranking in step
+	if err := adjustCommentCount(tx, int(commentID), 1); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return int(commentID), nil
 }
 
-func (cc *CommentController) GetCommentsByPostID(postID string) ([]models.Comment, error) {
-	postIDInt, err := strconv.Atoi(postID)
+// QuoteParent checks that excerpt appears in the parent comment and returns the quote to embed
+// in the reply, shortened to MaxQuoteChars. An empty excerpt quotes nothing
+func (cc *CommentController) QuoteParent(parentID int, excerpt string) (*models.QuotedComment, error) {
+	excerpt = strings.TrimSpace(excerpt)
+	if excerpt == "" {
+		return nil, nil
+	}
+	if parentID == 0 {
+		return nil, ErrQuoteWithoutParent
+	}
+
+	quote := models.QuotedComment{ID: parentID}
+	var content string
+	err := cc.DB.QueryRow(`
+		SELECT user_id, author, content FROM comments WHERE id = ? AND deleted_at IS NULL
+	`, parentID).Scan(&quote.UserID, &quote.Author, &content)
+	if err == sql.ErrNoRows {
+		return nil, ErrQuoteMismatch
+	}
 	if err != nil {
-		return nil, fmt.Errorf("invalid post ID: %w", err)
-	}
-	if postIDInt <= 0 {
-		return nil, fmt.Errorf("invalid post ID")
-	}
-
-	rows, err := cc.DB.Query(`
-        WITH RECURSIVE CommentTree AS (
-            -- Base case: get top-level comments
-            SELECT 
-                id, post_id, user_id, parent_id, author, content, 
-                likes, dislikes, user_vote, timestamp,
-                0 as depth,
-                CAST(id as CHAR(50)) as path
-            FROM comments 
-            WHERE post_id = ? AND parent_id IS NULL
-            
-            UNION ALL
-            
-            -- Recursive case: get replies with depth limit
-            SELECT 
-                c.id, c.post_id, c.user_id, c.parent_id, c.author, c.content,
-                c.likes, c.dislikes, c.user_vote, c.timestamp,
-                ct.depth + 1,
-                CONCAT(ct.path, ',', c.id)
-            FROM comments c
-            INNER JOIN CommentTree ct ON c.parent_id = ct.id
-            WHERE ct.depth < 6  -- Limit depth to 6 levels
-        )
-        SELECT * FROM CommentTree
-        ORDER BY path, depth, timestamp;
-    `, postIDInt)
+		return nil, fmt.Errorf("failed to fetch quoted comment: %w", err)
+	}
+	if !strings.Contains(content, excerpt) {
+		return nil, ErrQuoteMismatch
+	}
+
+	if utf8.RuneCountInString(excerpt) > models.MaxQuoteChars {
+		excerpt = string([]rune(excerpt)[:models.MaxQuoteChars]) + "…"
+	}
+	quote.Excerpt = excerpt
+	return &quote, nil
+}
+
+// visibleReply matches replies r that are not deleted or still have a reply, at any depth, that is
+// not, so that a deleted comment stays as a placeholder only while there is something under it to show
+const visibleReply = `(r.deleted_at IS NULL OR EXISTS (
+	WITH RECURSIVE d(id, deleted_at) AS (
+		SELECT id, deleted_at FROM comments WHERE parent_id = r.id
+		UNION ALL
+		SELECT k.id, k.deleted_at FROM comments k JOIN d ON k.parent_id = d.id
+	)
+	SELECT 1 FROM d WHERE d.deleted_at IS NULL))`
+
+// commentColumns are the columns scanned by scanComment, selected from comments c and the
+// quoted comment q; replies count the direct replies that are visible
+const commentColumns = `
+	c.id, c.post_id, c.user_id, c.parent_id, c.author, c.content,
+	c.likes, c.dislikes, c.user_vote, c.timestamp, c.deleted_at IS NOT NULL,
+	c.quote_id, c.quote_text, COALESCE(q.user_id, 0), COALESCE(q.author, ''), q.deleted_at IS NOT NULL,
+	(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id AND ` + visibleReply + `),
+	COALESCE((SELECT u.reputation FROM users u WHERE u.id = c.user_id), 0)`
+
+// visibleComment filters out deleted comments that have no visible replies left to show
+const visibleComment = `(c.deleted_at IS NULL OR EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = c.id AND ` + visibleReply + `))`
+
+// hiddenLeaf matches comments by users the viewer, bound to its parameter, blocked or muted that
+// have no visible replies
+const hiddenLeaf = `(c.user_id IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = ?)
+	AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = c.id AND ` + visibleReply + `))`
+
+// ParseCommentSort returns the requested comment order, or best if it is not one of CommentSorts
+func ParseCommentSort(sort string) string {
+	for _, s := range models.CommentSorts {
+		if s == sort {
+			return s
+		}
+	}
+	return models.CommentSortBest
+}
+
+// commentOrder returns the ORDER BY terms for the given comment order
+func commentOrder(sort string) string {
+	switch sort {
+	case models.CommentSortNew:
+		return `c.timestamp DESC, c.id DESC`
+	case models.CommentSortOld:
+		return `c.timestamp ASC, c.id ASC`
+	default:
+		return `(c.likes - c.dislikes) DESC, c.timestamp ASC, c.id ASC`
+	}
+}
+
+// scanComment reads a row of commentColumns, replacing the content of deleted comments and quotes
+func scanComment(rows *sql.Rows) (models.Comment, error) {
+	var comment models.Comment
+	var quoteID sql.NullInt64
+	var quoteText, quoteAuthor string
+	var quoteUserID int
+	var quoteDeleted bool
+	err := rows.Scan(
+		&comment.ID, &comment.PostID, &comment.UserID, &comment.ParentID,
+		&comment.Author, &comment.Content, &comment.Likes, &comment.Dislikes,
+		&comment.UserVote, &comment.Timestamp, &comment.IsDeleted,
+		&quoteID, &quoteText, &quoteUserID, &quoteAuthor, &quoteDeleted,
+		&comment.ReplyCount, &comment.AuthorReputation,
+	)
+	if err != nil {
+		return comment, fmt.Errorf("failed to scan comment: %w", err)
+	}
+	if comment.IsDeleted {
+		comment.UserID = 0
+		comment.AuthorReputation = 0
+		comment.Author = models.DeletedPlaceholder
+		comment.Content = models.DeletedPlaceholder
+	}
+	if quoteID.Valid {
+		comment.Quote = &models.QuotedComment{
+			ID: int(quoteID.Int64), UserID: quoteUserID, Author: quoteAuthor, Excerpt: quoteText,
+		}
+		// Quotes of deleted comments go with them
+		if quoteDeleted || quoteAuthor == "" {
+			comment.Quote.UserID = 0
+			comment.Quote.Author = models.DeletedPlaceholder
+			comment.Quote.Excerpt = models.DeletedPlaceholder
+		}
+	}
+	comment.Replies = make([]models.Comment, 0)
+	return comment, nil
+}
+
+// queryComments runs a query selecting commentColumns and scans every row
+func (cc *CommentController) queryComments(query string, args ...interface{}) ([]models.Comment, error) {
+	rows, err := cc.DB.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch comments: %w", err)
 	}
 	defer rows.Close()
 
-	commentMap := make(map[int]*models.Comment)
-	var topLevelComments []*models.Comment
-
+	var comments []models.Comment
 	for rows.Next() {
-		var comment models.Comment
-		var depth int
-		var path string
-		err := rows.Scan(
-			&comment.ID, &comment.PostID, &comment.UserID, &comment.ParentID,
-			&comment.Author, &comment.Content, &comment.Likes, &comment.Dislikes,
-			&comment.UserVote, &comment.Timestamp, &depth, &path,
-		)
+		comment, err := scanComment(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan comment: %w", err)
+			return nil, err
 		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
 
-		// Initialize Replies slice
-		comment.Replies = make([]models.Comment, 0)
-
-		// Store in map
-		commentMap[comment.ID] = &comment
+// buildCommentTree links comments to their parents in a single pass and returns the comments
+// whose parent is not among them, or that are listed in roots. Replies keep the order in which
+// they appear in comments
+func buildCommentTree(comments []models.Comment, roots map[int]bool) []models.Comment {
+	loaded := make(map[int]bool, len(comments))
+	for _, comment := range comments {
+		loaded[comment.ID] = true
+	}
 
-		if !comment.ParentID.Valid {
-			// This is a top-level comment
-			topLevelComments = append(topLevelComments, commentMap[comment.ID])
-		} else {
-			// This is a reply - add it to its parent's replies
+	children := make(map[int][]int, len(comments))
+	var top []int
+	for i, comment := range comments {
+		if comment.ParentID.Valid && loaded[int(comment.ParentID.Int64)] && !roots[comment.ID] {
 			parentID := int(comment.ParentID.Int64)
+			children[parentID] = append(children[parentID], i)
+		} else {
+			top = append(top, i)
+		}
+	}
 
-			if parent, exists := commentMap[parentID]; exists {
-				// Check if parent is a top-level comment
-				isTopLevelParent := false
-				for _, topComment := range topLevelComments {
-					if topComment.ID == parentID {
-						isTopLevelParent = true
-						break
-					}
-				}
-
-				if isTopLevelParent {
-					// Add reply directly to parent since it's a top-level comment
-					parent.Replies = append(parent.Replies, *commentMap[comment.ID])
-				} else {
-					// Find the top-level ancestor and add the reply there
-					for _, topComment := range topLevelComments {
-						var addReplyToTopLevel func(*models.Comment) bool
-						addReplyToTopLevel = func(c *models.Comment) bool {
-							for i := range c.Replies {
-								if c.Replies[i].ID == parentID {
-									c.Replies[i].Replies = append(c.Replies[i].Replies, *commentMap[comment.ID])
-									return true
-								}
-								if addReplyToTopLevel(&c.Replies[i]) {
-									return true
-								}
-							}
-							return false
-						}
-						if addReplyToTopLevel(topComment) {
-							break
-						}
-					}
-				}
+	var build func(i int) models.Comment
+	build = func(i int) models.Comment {
+		comment := comments[i]
+		comment.Replies = make([]models.Comment, 0, len(children[comment.ID]))
+		for _, j := range children[comment.ID] {
+			comment.Replies = append(comment.Replies, build(j))
+		}
+		return comment
+	}
+
+	result := make([]models.Comment, 0, len(top))
+	for _, i := range top {
+		result = append(result, build(i))
+	}
+	return result
+}
+
+// GetCommentPage returns up to limit direct replies to parentID, or top level comments of the post
+// when parentID is 0, skipping the first offset in the given order. Comments by users the viewer
+// blocked or muted are left out unless they have replies to show, as HideComments would drop them.
+// The comments' own replies are not loaded
+func (cc *CommentController) GetCommentPage(postID, parentID, viewerID int, sort string, offset, limit int) (models.CommentPage, error) {
+	page := models.CommentPage{Comments: []models.Comment{}}
+	parent := `c.parent_id IS NULL`
+	args := []interface{}{postID}
+	if parentID != 0 {
+		parent = `c.parent_id = ?`
+		args = append(args, parentID)
+	}
+	args = append(args, viewerID, limit+1, offset)
+
+	comments, err := cc.queryComments(`
+		SELECT `+commentColumns+`
+		FROM comments c
+		LEFT JOIN comments q ON q.id = c.quote_id
+		WHERE c.post_id = ? AND `+parent+` AND `+visibleComment+` AND NOT `+hiddenLeaf+`
+		ORDER BY `+commentOrder(sort)+`
+		LIMIT ? OFFSET ?
+	`, args...)
+	if err != nil {
+		return page, err
+	}
+	if len(comments) > limit {
+		comments = comments[:limit]
+		page.HasMore = true
+	}
+	for i := range comments {
+		comments[i].MoreReplies = comments[i].ReplyCount
+	}
+	if comments != nil {
+		page.Comments = comments
+	}
+	page.NextOffset = offset + len(comments)
+	return page, nil
+}
+
+// GetCommentTreePage returns a page of top level comments, as seen by the viewer, with a preview of
+// their replies: the first RepliesPreview replies of each comment, ThreadDisplayDepth levels deep
+func (cc *CommentController) GetCommentTreePage(postID, viewerID int, sort string, offset int) (models.CommentPage, error) {
+	page, err := cc.GetCommentPage(postID, 0, viewerID, sort, offset, models.CommentsPageSize)
+	if err != nil {
+		return page, err
+	}
+	page.Comments, err = cc.loadReplies(page.Comments, sort, nil)
+	return page, err
+}
+
+// GetCommentThread returns the subtree rooted at the given comment of a post with a preview of its
+// replies, for "continue this thread" and comment permalinks
+func (cc *CommentController) GetCommentThread(postID, commentID int, sort string) ([]models.Comment, error) {
+	root, err := cc.queryComments(`
+		SELECT `+commentColumns+`
+		FROM comments c
+		LEFT JOIN comments q ON q.id = c.quote_id
+		WHERE c.post_id = ? AND c.id = ? AND `+visibleComment+`
+	`, postID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(root) == 0 {
+		return nil, ErrCommentNotFound
+	}
+	return cc.loadReplies(root, sort, map[int]bool{commentID: true})
+}
+
+// loadReplies loads the first RepliesPreview replies of each of the top comments, level by level
+// down to ThreadDisplayDepth, and returns them as a tree. It runs one query per level
+func (cc *CommentController) loadReplies(top []models.Comment, sort string, roots map[int]bool) ([]models.Comment, error) {
+	comments := append([]models.Comment(nil), top...)
+	level := comments
+	for depth := 1; depth < models.ThreadDisplayDepth; depth++ {
+		var parents []interface{}
+		for _, comment := range level {
+			if comment.ReplyCount > 0 {
+				parents = append(parents, comment.ID)
 			}
 		}
+		if len(parents) == 0 {
+			level = nil
+			break
+		}
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(parents)), ",")
+		replies, err := cc.queryComments(`
+			SELECT `+commentColumns+`
+			FROM (
+				SELECT c.*, ROW_NUMBER() OVER (PARTITION BY c.parent_id ORDER BY `+commentOrder(sort)+`) AS position
+				FROM comments c
+				WHERE c.parent_id IN (`+placeholders+`) AND `+visibleComment+`
+			) c
+			LEFT JOIN comments q ON q.id = c.quote_id
+			WHERE c.position <= ?
+			ORDER BY c.parent_id, c.position
+		`, append(parents, models.RepliesPreview)...)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, replies...)
+		level = replies
 	}
 
-	// Convert to slice of values
-	result := make([]models.Comment, len(topLevelComments))
-	for i, comment := range topLevelComments {
-		result[i] = *comment
+	// Comments on the last level leave all their replies to "continue this thread"
+	lastLevel := make(map[int]bool, len(level))
+	for _, comment := range level {
+		lastLevel[comment.ID] = true
+	}
+	loaded := make(map[int]int, len(comments))
+	for _, comment := range comments {
+		if comment.ParentID.Valid {
+			loaded[int(comment.ParentID.Int64)]++
+		}
+	}
+	for i := range comments {
+		comments[i].MoreReplies = comments[i].ReplyCount - loaded[comments[i].ID]
+		comments[i].ContinueThread = lastLevel[comments[i].ID] && comments[i].ReplyCount > 0
 	}
 
-	return result, nil
+	return buildCommentTree(comments, roots), nil
 }
 
 func (cc *CommentController) GetCommentCountByPostID(postID int) (int, error) {
@@ -190,7 +407,7 @@ func (cc *CommentController) GetCommentCountByPostID(postID int) (int, error) {
 	err := cc.DB.QueryRow(`
         SELECT COUNT(*) 
         FROM comments 
-        WHERE post_id = ?
+        WHERE post_id = ? AND deleted_at IS NULL
     `, postID).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to fetch comment count: %w", err)
@@ -198,13 +415,19 @@ func (cc *CommentController) GetCommentCountByPostID(postID int) (int, error) {
 	return count, nil
 }
 
-// DeleteComment deletes a comment by its ID
-func (cc *CommentController) DeleteComment(commentID int) error {
-	// Execute the delete query
-	result, err := cc.DB.Exec(`
-        DELETE FROM comments 
-        WHERE id = ?
-    `, commentID)
+// DeleteComment moves a comment to the trash; its replies stay visible under a "[deleted]" placeholder
+func (cc *CommentController) DeleteComment(commentID, userID int) error {
+	tx, err := cc.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Mark the comment as deleted
+	result, err := tx.Exec(`
+        UPDATE comments SET deleted_at = ?, deleted_by = ?
+        WHERE id = ? AND deleted_at IS NULL
+    `, time.Now(), userID, commentID)
 	if err != nil {
 		return fmt.Errorf("failed to delete comment: %w", err)
 	}
@@ -219,6 +442,26 @@ func (cc *CommentController) DeleteComment(commentID int) error {
 		return fmt.Errorf("no comment found with ID: %d", commentID)
 	}
 
+	if err := adjustCommentCount(tx, commentID, -1); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// adjustCommentCount adds delta to the comment count of the post a comment belongs to
+func adjustCommentCount(ex execer, commentID, delta int) error {
+	_, err := ex.Exec(`
+        UPDATE posts SET comment_count = MAX(comment_count + ?, 0)
+        WHERE id = (SELECT post_id FROM comments WHERE id = ?)
+    `, delta, commentID)
+	if err != nil {
+		return fmt.Errorf("failed to update comment count: %w", err)
+	}
 	return nil
 }
 
@@ -237,11 +480,21 @@ func (cc *CommentController) IsCommentAuthor(commentID, userID int) (bool, error
 	return count > 0, nil
 }
 
+// GetCommentPostID returns the ID of the post the comment belongs to
+func (cc *CommentController) GetCommentPostID(commentID int) (int, error) {
+	var postID int
+	err := cc.DB.QueryRow(`SELECT post_id FROM comments WHERE id = ?`, commentID).Scan(&postID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch post of comment %d: %w", commentID, err)
+	}
+	return postID, nil
+}
+
 func (cc *CommentController) UpdateComment(commentID int, content string) error {
 	result, err := cc.DB.Exec(`
         UPDATE comments 
         SET content = ?
-        WHERE id = ?
+        WHERE id = ? AND deleted_at IS NULL
     `, content, commentID)
 	if err != nil {
 		return fmt.Errorf("failed to update comment: %w", err)
@@ -2,6 +2,8 @@ package controllers
 
 import (
 	"database/sql"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -197,7 +199,7 @@ func TestCommentController_InsertComment(t *testing.T) {
 	}
 }
 
-func TestCommentController_GetCommentsByPostID(t *testing.T) {
+func TestCommentController_GetCommentTreePage(t *testing.T) {
 	// Create a test database
 	db, err := database.Init("Test")
 	if err != nil {
@@ -237,7 +239,7 @@ func TestCommentController_GetCommentsByPostID(t *testing.T) {
 
 	tests := []struct {
 		name    string
-		postID  string
+		postID  int
 		setup   func(db *sql.DB) // Optional setup function for the test case
 		want    []models.Comment
 		wantErr bool
@@ -245,7 +247,7 @@ func TestCommentController_GetCommentsByPostID(t *testing.T) {
 	}{
 		{
 			name:   "Valid Post ID",
-			postID: "1",
+			postID: 1,
 			want: []models.Comment{
 				{
 					ID:        int(topLevelCommentID),
@@ -275,29 +277,15 @@ func TestCommentController_GetCommentsByPostID(t *testing.T) {
 			},
 			wantErr: false,
 		},
-		{
-			name:    "Invalid Post ID - Non-numeric",
-			postID:  "invalid",
-			want:    nil,
-			wantErr: true,
-			errMsg:  "invalid post ID: strconv.Atoi: parsing \"invalid\": invalid syntax",
-		},
-		{
-			name:    "Invalid Post ID - Negative",
-			postID:  "-1",
-			want:    nil,
-			wantErr: true,
-			errMsg:  "invalid post ID",
-		},
 		{
 			name:    "No Comments for Post",
-			postID:  "2", // Post ID with no comments
+			postID:  2, // Post ID with no comments
 			want:    []models.Comment{},
 			wantErr: false,
 		},
 		{
 			name:   "Database Error",
-			postID: "1",
+			postID: 1,
 			setup: func(db *sql.DB) {
 				// Close the database connection to simulate a database error
 				db.Close()
@@ -315,19 +303,20 @@ func TestCommentController_GetCommentsByPostID(t *testing.T) {
 				tt.setup(db)
 			}
 
-			comments, err := cCtrl.GetCommentsByPostID(tt.postID)
+			page, err := cCtrl.GetCommentTreePage(tt.postID, 0, models.CommentSortNew, 0)
+			comments := page.Comments
 			if (err != nil) != tt.wantErr {
-				t.Errorf("CommentController.GetCommentsByPostID() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("CommentController.GetCommentTreePage() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if tt.wantErr {
 				if err == nil || err.Error() != tt.errMsg {
-					t.Errorf("CommentController.GetCommentsByPostID() error = %v, wantErrMsg %v", err, tt.errMsg)
+					t.Errorf("CommentController.GetCommentTreePage() error = %v, wantErrMsg %v", err, tt.errMsg)
 				}
 				return
 			}
 			if len(comments) != len(tt.want) {
-				t.Errorf("CommentController.GetCommentsByPostID() returned %d comments, want %d", len(comments), len(tt.want))
+				t.Errorf("CommentController.GetCommentTreePage() returned %d comments, want %d", len(comments), len(tt.want))
 				return
 			}
 			// Compare each comment and its replies
@@ -342,11 +331,11 @@ func TestCommentController_GetCommentsByPostID(t *testing.T) {
 					comment.UserVote != tt.want[i].UserVote ||
 					!comment.Timestamp.Equal(tt.want[i].Timestamp) ||
 					comment.ParentID != tt.want[i].ParentID {
-					t.Errorf("CommentController.GetCommentsByPostID() comment mismatch: got %v, want %v", comment, tt.want[i])
+					t.Errorf("CommentController.GetCommentTreePage() comment mismatch: got %v, want %v", comment, tt.want[i])
 				}
 				// Compare replies
 				if len(comment.Replies) != len(tt.want[i].Replies) {
-					t.Errorf("CommentController.GetCommentsByPostID() reply count mismatch: got %d, want %d", len(comment.Replies), len(tt.want[i].Replies))
+					t.Errorf("CommentController.GetCommentTreePage() reply count mismatch: got %d, want %d", len(comment.Replies), len(tt.want[i].Replies))
 				}
 			}
 		})
@@ -504,7 +493,7 @@ func TestCommentController_DeleteComment(t *testing.T) {
 				tt.setup(db)
 			}
 
-			err := cCtrl.DeleteComment(tt.commentID)
+			err := cCtrl.DeleteComment(tt.commentID, 1)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CommentController.DeleteComment() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -697,3 +686,268 @@ func TestCommentController_UpdateComment(t *testing.T) {
 		})
 	}
 }
+
+func TestCommentController_GetCommentThread(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	cCtrl := NewCommentController(db)
+
+	// A single chain of replies two levels deeper than the page shows
+	var ids []int
+	for i := 0; i < models.ThreadDisplayDepth+2; i++ {
+		comment := models.Comment{PostID: 1, UserID: 1, Author: "testuser", Content: "reply", Timestamp: time.Now()}
+		if len(ids) > 0 {
+			comment.ParentID = sql.NullInt64{Int64: int64(ids[len(ids)-1]), Valid: true}
+		}
+		id, err := cCtrl.InsertComment(comment)
+		if err != nil {
+			t.Fatalf("InsertComment() at depth %d error = %v", i, err)
+		}
+		ids = append(ids, id)
+	}
+
+	// chain walks down the single reply of each comment and returns the comments it passes
+	chain := func(comments []models.Comment) []models.Comment {
+		var walked []models.Comment
+		for len(comments) == 1 {
+			walked = append(walked, comments[0])
+			comments = comments[0].Replies
+		}
+		return walked
+	}
+
+	top, err := cCtrl.GetCommentTreePage(1, 0, models.CommentSortBest, 0)
+	if err != nil {
+		t.Fatalf("GetCommentTreePage() error = %v", err)
+	}
+	walked := chain(top.Comments)
+	if len(walked) != models.ThreadDisplayDepth {
+		t.Fatalf("GetCommentTreePage() shows %d levels, want %d", len(walked), models.ThreadDisplayDepth)
+	}
+	last := walked[len(walked)-1]
+	if last.ID != ids[models.ThreadDisplayDepth-1] || last.MoreReplies != 1 {
+		t.Errorf("last shown comment = %d with %d more replies, want %d with 1", last.ID, last.MoreReplies, ids[models.ThreadDisplayDepth-1])
+	}
+
+	// Continuing from the last shown comment reaches the bottom of the chain
+	thread, err := cCtrl.GetCommentThread(1, last.ID, models.CommentSortBest)
+	if err != nil {
+		t.Fatalf("GetCommentThread() error = %v", err)
+	}
+	walked = chain(thread)
+	if len(walked) != 3 || walked[0].ID != last.ID || walked[2].ID != ids[len(ids)-1] || walked[2].MoreReplies != 0 {
+		t.Errorf("GetCommentThread() = %+v", walked)
+	}
+
+	if _, err := cCtrl.GetCommentThread(2, last.ID, models.CommentSortBest); err != ErrCommentNotFound {
+		t.Errorf("GetCommentThread() on another post error = %v, want %v", err, ErrCommentNotFound)
+	}
+	if _, err := cCtrl.GetCommentThread(1, 999, models.CommentSortBest); err != ErrCommentNotFound {
+		t.Errorf("GetCommentThread() missing comment error = %v, want %v", err, ErrCommentNotFound)
+	}
+}
+
+func TestCommentController_QuoteParent(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	cCtrl := NewCommentController(db)
+	long := strings.Repeat("é", models.MaxQuoteChars+10)
+	parentID, err := cCtrl.InsertComment(models.Comment{
+		PostID: 1, UserID: 2, Author: "parent", Content: "The quick brown fox " + long, Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("InsertComment() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		parentID int
+		excerpt  string
+		want     *models.QuotedComment
+		wantErr  error
+	}{
+		{"no quote", parentID, "  ", nil, nil},
+		{"excerpt", parentID, " quick brown ", &models.QuotedComment{ID: parentID, UserID: 2, Author: "parent", Excerpt: "quick brown"}, nil},
+		{"shortened", parentID, long, &models.QuotedComment{ID: parentID, UserID: 2, Author: "parent", Excerpt: long[:2*models.MaxQuoteChars] + "…"}, nil},
+		{"not in parent", parentID, "lazy dog", nil, ErrQuoteMismatch},
+		{"missing parent", 999, "quick", nil, ErrQuoteMismatch},
+		{"top level", 0, "quick", nil, ErrQuoteWithoutParent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cCtrl.QuoteParent(tt.parentID, tt.excerpt)
+			if err != tt.wantErr {
+				t.Fatalf("QuoteParent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("QuoteParent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// The quote is stored with the reply and shown with its author
+	quote, _ := cCtrl.QuoteParent(parentID, "brown fox")
+	if _, err := cCtrl.InsertComment(models.Comment{
+		PostID: 1, UserID: 3, Author: "replier", Content: "indeed", Timestamp: time.Now(),
+		ParentID: sql.NullInt64{Int64: int64(parentID), Valid: true}, Quote: quote,
+	}); err != nil {
+		t.Fatalf("InsertComment() error = %v", err)
+	}
+	comments, err := cCtrl.GetCommentThread(1, parentID, models.CommentSortBest)
+	if err != nil {
+		t.Fatalf("GetCommentThread() error = %v", err)
+	}
+	if len(comments) != 1 || len(comments[0].Replies) != 1 || !reflect.DeepEqual(comments[0].Replies[0].Quote, quote) {
+		t.Fatalf("GetCommentThread() reply quote = %+v, want %+v", comments, quote)
+	}
+}
+
+func TestCommentController_GetCommentPage(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	cCtrl := NewCommentController(db)
+	start := time.Now().Add(-time.Hour)
+	insert := func(parentID, likes, minute int) int {
+		id, err := InsertTestComment(db, models.Comment{
+			PostID: 1, UserID: 1, Author: "testuser", Content: "comment", Likes: likes,
+			Timestamp: start.Add(time.Duration(minute) * time.Minute),
+			ParentID:  sql.NullInt64{Int64: int64(parentID), Valid: parentID != 0},
+		})
+		if err != nil {
+			t.Fatalf("Failed to insert comment: %v", err)
+		}
+		return int(id)
+	}
+
+	// Three top level comments; the oldest has five replies, the newest is deleted without replies
+	oldest := insert(0, 1, 0)
+	best := insert(0, 5, 1)
+	deleted := insert(0, 9, 2)
+	var replies []int
+	for i := 0; i < 5; i++ {
+		replies = append(replies, insert(oldest, i, 10+i))
+	}
+	if _, err := db.Exec(`UPDATE comments SET deleted_at = ? WHERE id = ?`, time.Now(), deleted); err != nil {
+		t.Fatalf("Failed to delete comment: %v", err)
+	}
+
+	ids := func(comments []models.Comment) []int {
+		result := []int{}
+		for _, c := range comments {
+			result = append(result, c.ID)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name        string
+		parentID    int
+		sort        string
+		offset      int
+		limit       int
+		want        []int
+		wantHasMore bool
+	}{
+		{"best", 0, models.CommentSortBest, 0, 10, []int{best, oldest}, false},
+		{"new", 0, models.CommentSortNew, 0, 10, []int{best, oldest}, false},
+		{"old", 0, models.CommentSortOld, 0, 10, []int{oldest, best}, false},
+		{"first replies", oldest, models.CommentSortOld, 0, 2, []int{replies[0], replies[1]}, true},
+		{"next replies", oldest, models.CommentSortOld, 2, 2, []int{replies[2], replies[3]}, true},
+		{"last replies", oldest, models.CommentSortOld, 4, 2, []int{replies[4]}, false},
+		{"best replies", oldest, models.CommentSortBest, 0, 2, []int{replies[4], replies[3]}, true},
+		{"no replies", best, models.CommentSortBest, 0, 2, []int{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := cCtrl.GetCommentPage(1, tt.parentID, 0, tt.sort, tt.offset, tt.limit)
+			if err != nil {
+				t.Fatalf("GetCommentPage() error = %v", err)
+			}
+			if got := ids(page.Comments); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetCommentPage() = %v, want %v", got, tt.want)
+			}
+			if page.HasMore != tt.wantHasMore || page.NextOffset != tt.offset+len(tt.want) {
+				t.Errorf("GetCommentPage() hasMore = %v, nextOffset = %d", page.HasMore, page.NextOffset)
+			}
+		})
+	}
+
+	// The tree page previews the first replies of each comment and counts the rest
+	page, err := cCtrl.GetCommentTreePage(1, 0, models.CommentSortOld, 0)
+	if err != nil {
+		t.Fatalf("GetCommentTreePage() error = %v", err)
+	}
+	if got := ids(page.Comments); !reflect.DeepEqual(got, []int{oldest, best}) {
+		t.Fatalf("GetCommentTreePage() = %v", got)
+	}
+	first := page.Comments[0]
+	if got := ids(first.Replies); !reflect.DeepEqual(got, replies[:models.RepliesPreview]) {
+		t.Errorf("GetCommentTreePage() replies = %v, want %v", got, replies[:models.RepliesPreview])
+	}
+	if first.ReplyCount != 5 || first.MoreReplies != 5-models.RepliesPreview || first.ContinueThread {
+		t.Errorf("GetCommentTreePage() replyCount = %d, moreReplies = %d, continueThread = %v",
+			first.ReplyCount, first.MoreReplies, first.ContinueThread)
+	}
+
+	// A deleted comment whose replies are all deleted goes away; one with a reply left further down
+	// stays, and so does the deleted reply leading to it
+	emptyThread := insert(0, 0, 3)
+	emptyReply := insert(emptyThread, 0, 20)
+	deepThread := insert(0, 0, 4)
+	deepReply := insert(deepThread, 0, 21)
+	insert(deepReply, 0, 22)
+	for _, id := range []int{emptyThread, emptyReply, deepThread, deepReply} {
+		if _, err := db.Exec(`UPDATE comments SET deleted_at = ? WHERE id = ?`, time.Now(), id); err != nil {
+			t.Fatalf("Failed to delete comment: %v", err)
+		}
+	}
+	top, err := cCtrl.GetCommentPage(1, 0, 0, models.CommentSortNew, 0, 10)
+	if err != nil {
+		t.Fatalf("GetCommentPage() error = %v", err)
+	}
+	if got := ids(top.Comments); !reflect.DeepEqual(got, []int{deepThread, best, oldest}) || top.Comments[0].ReplyCount != 1 {
+		t.Errorf("GetCommentPage() with deleted threads = %v, want %v with one reply", got, []int{deepThread, best, oldest})
+	}
+	if page, err := cCtrl.GetCommentPage(1, emptyThread, 0, models.CommentSortNew, 0, 10); err != nil || len(page.Comments) != 0 {
+		t.Errorf("GetCommentPage() under a deleted thread = %v, %v, want none", ids(page.Comments), err)
+	}
+}
+
+func TestBuildCommentTree(t *testing.T) {
+	parent := func(id int) sql.NullInt64 { return sql.NullInt64{Int64: int64(id), Valid: id != 0} }
+
+	// Replies listed before their parents and replies to comments that were not loaded
+	comments := []models.Comment{
+		{ID: 3, ParentID: parent(1)},
+		{ID: 1},
+		{ID: 4, ParentID: parent(3)},
+		{ID: 2},
+		{ID: 5, ParentID: parent(1)},
+		{ID: 6, ParentID: parent(99)},
+	}
+	tree := buildCommentTree(comments, nil)
+	if len(tree) != 3 || tree[0].ID != 1 || tree[1].ID != 2 || tree[2].ID != 6 {
+		t.Fatalf("buildCommentTree() top = %+v", tree)
+	}
+	if r := tree[0].Replies; len(r) != 2 || r[0].ID != 3 || r[1].ID != 5 || len(r[0].Replies) != 1 || r[0].Replies[0].ID != 4 {
+		t.Errorf("buildCommentTree() replies = %+v", r)
+	}
+
+	// A root keeps its place at the top even though its parent is loaded
+	tree = buildCommentTree(comments[:3], map[int]bool{3: true})
+	if len(tree) != 2 || tree[0].ID != 3 || tree[1].ID != 1 || len(tree[1].Replies) != 0 {
+		t.Errorf("buildCommentTree() with roots = %+v", tree)
+	}
+}
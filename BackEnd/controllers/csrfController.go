@@ -5,9 +5,10 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/base64"
-	"log"
 	"net/http"
 	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
 )
 
 func GenerateCSRFToken(db *sql.DB, sessionToken string) (string, error) {
@@ -107,13 +108,13 @@ func CleanupExpiredCSRFTokens(ctx context.Context, db *sql.DB) {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Stopping CSRF token cleanup task...")
+			logger.Info("Stopping CSRF token cleanup task...")
 			return
 		case <-ticker.C:
-			log.Println("Cleaning up expired CSRF tokens...")
+			logger.Info("Cleaning up expired CSRF tokens...")
 			_, err := db.Exec("DELETE FROM csrf_tokens WHERE expires_at < ?", time.Now())
 			if err != nil {
-				log.Printf("Failed to clean up expired CSRF tokens: %v\n", err)
+				logger.Error("Failed to clean up expired CSRF tokens: %v", err)
 			}
 		}
 	}
@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/storage"
+)
+
+// orphanGracePeriod protects freshly uploaded files from garbage collection
+// until the post referencing them has had a chance to be saved
+const orphanGracePeriod = 1 * time.Hour
+
+// uploadReferenceQueries count how many rows reference a stored file path.
+// Any table storing upload paths (comment attachments, avatars, ...) must be listed here.
+var uploadReferenceQueries = []string{
+	`SELECT COUNT(*) FROM posts WHERE image_url = ?`,
+	`SELECT COUNT(*) FROM messages WHERE attachment_url = ?`,
+}
+
+// uploadLocks serialize storing and collecting the same content, so the collector never deletes an
+// object that an upload is storing or reusing. Hashes share the locks by a hash of their own
+var uploadLocks [64]sync.Mutex
+
+// lockUpload takes the lock of the content with the given hash and returns its unlock function
+func lockUpload(hash string) func() {
+	h := fnv.New32a()
+	h.Write([]byte(hash))
+	l := &uploadLocks[h.Sum32()%uint32(len(uploadLocks))]
+	l.Lock()
+	return l.Unlock
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// UploadFile stores the uploaded file under its SHA-256 hash and returns its public path.
+// Identical content is stored only once; the returned path must be retained by the row
+// that references it or the file will be collected by CollectOrphanedUploads.
+func UploadFile(db *sql.DB, r *http.Request, formName string, userID int) (string, error) {
+	return storeUpload(db, r, formName, userID, "")
+}
+
+// UploadPrivateFile stores the uploaded file like UploadFile, but under storage.PrivatePrefix so
+// that it can only be fetched through a signed URL. Private files are tracked apart from public
+// copies of the same content, so a public post never links to a private object or the reverse
+func UploadPrivateFile(db *sql.DB, r *http.Request, formName string, userID int) (string, error) {
+	return storeUpload(db, r, formName, userID, storage.PrivatePrefix)
+}
+
+// storeUpload stores the uploaded file under prefix followed by its SHA-256 hash
+func storeUpload(db *sql.DB, r *http.Request, formName string, userID int, prefix string) (string, error) {
+	file, handler, err := r.FormFile(formName)
+	if err == http.ErrMissingFile {
+		return "", nil
+	}
+	if err != nil {
+		logger.Error("Failed to retrieve file %v", err)
+		return "", err
+	}
+	defer file.Close()
+
+	// Hash the content to derive the storage key
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		logger.Error("Failed to hash file content: %v", err)
+		return "", err
+	}
+	hash := prefix + hex.EncodeToString(hasher.Sum(nil))
+	key := hash + strings.ToLower(filepath.Ext(handler.Filename))
+	unlock := lockUpload(hash)
+	defer unlock()
+
+	// Reuse the stored object if this content was uploaded before
+	var existingKey string
+	err = db.QueryRow(`SELECT storage_key FROM files WHERE hash = ?`, hash).Scan(&existingKey)
+	if err == nil {
+		_, err = db.Exec(`UPDATE files SET updated_at = ? WHERE hash = ?`, time.Now(), hash)
+		if err != nil {
+			return "", fmt.Errorf("failed to touch file %s: %w", hash, err)
+		}
+		logger.Debug("Deduplicated upload from user %d: %s", userID, existingKey)
+		return storage.PathFor(existingKey), nil
+	}
+	if err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to look up file %s: %w", hash, err)
+	}
+
+	// Only new content counts against the uploader's quota
+	if err := checkUploadQuota(db, userID, size); err != nil {
+		logger.Warning("Rejected upload from user %d: %v", userID, err)
+		return "", err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		logger.Error("Failed to rewind uploaded file: %v", err)
+		return "", err
+	}
+
+	// Save the file to the configured storage backend
+	err = storage.Default.Put(r.Context(), key, file, size, handler.Header.Get("Content-Type"))
+	if err != nil {
+		logger.Error("Failed to save file content: %v", err)
+		return "", err
+	}
+
+	// A concurrent upload of the same content may have registered the file already
+	_, err = db.Exec(`
+		INSERT INTO files (hash, storage_key, size, content_type, ref_count, uploaded_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 0, ?, ?, ?)
+		ON CONFLICT(hash) DO UPDATE SET updated_at = excluded.updated_at;
+	`, hash, key, size, handler.Header.Get("Content-Type"), userID, time.Now(), time.Now())
+	if err != nil {
+		return "", fmt.Errorf("failed to register file %s: %w", hash, err)
+	}
+
+	return storage.PathFor(key), nil
+}
+
+// retainUpload records a new reference to the file stored at path
+func retainUpload(ex execer, path string) error {
+	if path == "" {
+		return nil
+	}
+	_, err := ex.Exec(`
+		UPDATE files SET ref_count = ref_count + 1, updated_at = ?
+		WHERE storage_key = ?;
+	`, time.Now(), storage.KeyFromPath(path))
+	if err != nil {
+		return fmt.Errorf("failed to retain upload %s: %w", path, err)
+	}
+	return nil
+}
+
+// releaseUpload drops a reference to the file stored at path; unreferenced
+// files are deleted later by CollectOrphanedUploads
+func releaseUpload(ex execer, path string) error {
+	if path == "" {
+		return nil
+	}
+	_, err := ex.Exec(`
+		UPDATE files SET ref_count = MAX(ref_count - 1, 0), updated_at = ?
+		WHERE storage_key = ?;
+	`, time.Now(), storage.KeyFromPath(path))
+	if err != nil {
+		return fmt.Errorf("failed to release upload %s: %w", path, err)
+	}
+	return nil
+}
+
+// DeleteOrphanedUploads reconciles reference counts with the tables referencing
+// uploads and deletes files that have been unreferenced for longer than the grace period
+func DeleteOrphanedUploads(ctx context.Context, db *sql.DB) (int, error) {
+	rows, err := db.QueryContext(ctx, `SELECT hash, storage_key FROM files`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list files: %w", err)
+	}
+	type trackedFile struct{ hash, key string }
+	var files []trackedFile
+	for rows.Next() {
+		var f trackedFile
+		if err := rows.Scan(&f.hash, &f.key); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan file: %w", err)
+		}
+		files = append(files, f)
+	}
+	rows.Close()
+
+	// Recount references so that drift from crashes or manual edits heals itself
+	for _, f := range files {
+		refs := 0
+		for _, query := range uploadReferenceQueries {
+			var count int
+			if err := db.QueryRowContext(ctx, query, storage.PathFor(f.key)).Scan(&count); err != nil {
+				return 0, fmt.Errorf("failed to count references to %s: %w", f.key, err)
+			}
+			refs += count
+		}
+		_, err := db.ExecContext(ctx, `UPDATE files SET ref_count = ? WHERE hash = ? AND ref_count != ?`, refs, f.hash, refs)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update reference count of %s: %w", f.key, err)
+		}
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT hash, storage_key FROM files
+		WHERE ref_count = 0 AND updated_at < ?;
+	`, time.Now().Add(-orphanGracePeriod))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list orphaned files: %w", err)
+	}
+	var orphans []trackedFile
+	for rows.Next() {
+		var f trackedFile
+		if err := rows.Scan(&f.hash, &f.key); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan orphaned file: %w", err)
+		}
+		orphans = append(orphans, f)
+	}
+	rows.Close()
+
+	deleted := 0
+	for _, f := range orphans {
+		removed, err := deleteOrphan(ctx, db, f.hash, f.key)
+		if err != nil {
+			return deleted, err
+		}
+		if removed {
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// deleteOrphan removes a file that is still unreferenced and past the grace period, holding the
+// lock of its content so no upload stores or reuses it in between
+func deleteOrphan(ctx context.Context, db *sql.DB, hash, key string) (bool, error) {
+	unlock := lockUpload(hash)
+	defer unlock()
+
+	result, err := db.ExecContext(ctx, `DELETE FROM files WHERE hash = ? AND ref_count = 0 AND updated_at < ?`,
+		hash, time.Now().Add(-orphanGracePeriod))
+	if err != nil {
+		return false, fmt.Errorf("failed to delete file row %s: %w", key, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if err := storage.Default.Delete(ctx, key); err != nil {
+		return false, fmt.Errorf("failed to delete file %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// CollectOrphanedUploads periodically removes uploads no longer referenced by any row
+func CollectOrphanedUploads(ctx context.Context, db *sql.DB) {
+	ticker := time.NewTicker(1 * time.Hour) // Run collection every hour
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping upload garbage collection task...")
+			return
+		case <-ticker.C:
+			logger.Info("Collecting orphaned uploads...")
+			deleted, err := DeleteOrphanedUploads(ctx, db)
+			if err != nil {
+				logger.Error("Failed to collect orphaned uploads: %v", err)
+			}
+			if deleted > 0 {
+				logger.Info("Deleted %d orphaned uploads", deleted)
+			}
+		}
+	}
+}
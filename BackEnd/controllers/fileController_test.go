@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+	"github.com/Raymond9734/forum.git/BackEnd/storage"
+)
+
+// newUploadRequest builds a multipart request carrying a single file
+func newUploadRequest(t *testing.T, filename string, content []byte) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("post-file", filename)
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/createPost", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if err := req.ParseMultipartForm(10 << 20); err != nil {
+		t.Fatalf("Failed to parse multipart form: %v", err)
+	}
+	return req
+}
+
+func TestUploadFile_DeduplicatesAndCollectsOrphans(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	store := storage.NewLocalStorage(t.TempDir(), []byte("secret"))
+	storage.Default = store
+	defer func() { storage.Default = nil }()
+
+	if err := InsertTestUser(db, "test@example.com", "testuser", "password123"); err != nil {
+		t.Fatalf("Failed to insert test user: %v", err)
+	}
+
+	content := []byte("same image bytes")
+	first, err := UploadFile(db, newUploadRequest(t, "a.PNG", content), "post-file", 1)
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	second, err := UploadFile(db, newUploadRequest(t, "b.png", content), "post-file", 1)
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if first != second {
+		t.Errorf("identical uploads stored at %q and %q", first, second)
+	}
+	other, err := UploadFile(db, newUploadRequest(t, "c.png", []byte("other bytes")), "post-file", 1)
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+
+	pc := NewPostController(db)
+	postID, err := pc.InsertPost(models.Post{
+		Title: "Title", Author: "testuser", UserID: 1, Category: "Art", Content: "Content",
+		Timestamp: time.Now(), ImageUrl: sql.NullString{String: first, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("InsertPost() error = %v", err)
+	}
+
+	// Age every file past the grace period
+	if _, err := db.Exec(`UPDATE files SET updated_at = ?`, time.Now().Add(-2*orphanGracePeriod)); err != nil {
+		t.Fatalf("Failed to age files: %v", err)
+	}
+
+	deleted, err := DeleteOrphanedUploads(context.Background(), db)
+	if err != nil {
+		t.Fatalf("DeleteOrphanedUploads() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("DeleteOrphanedUploads() deleted %d files, want 1", deleted)
+	}
+	if _, err := store.Get(context.Background(), storage.KeyFromPath(other)); err != storage.ErrNotExist {
+		t.Errorf("orphaned upload still stored, Get() error = %v", err)
+	}
+	if object, err := store.Get(context.Background(), storage.KeyFromPath(first)); err != nil {
+		t.Errorf("referenced upload was deleted: %v", err)
+	} else {
+		object.Close()
+	}
+
+	// A post in the trash still references its image
+	if err := pc.DeletePost(postID, 1); err != nil {
+		t.Fatalf("DeletePost() error = %v", err)
+	}
+	if deleted, err = DeleteOrphanedUploads(context.Background(), db); err != nil || deleted != 0 {
+		t.Errorf("DeleteOrphanedUploads() = %d, %v; want 0, nil", deleted, err)
+	}
+
+	// Purging the post releases the last reference
+	if _, err := PurgeExpiredTrash(context.Background(), db, 0); err != nil {
+		t.Fatalf("PurgeExpiredTrash() error = %v", err)
+	}
+	if _, err := db.Exec(`UPDATE files SET updated_at = ?`, time.Now().Add(-2*orphanGracePeriod)); err != nil {
+		t.Fatalf("Failed to age files: %v", err)
+	}
+	if deleted, err = DeleteOrphanedUploads(context.Background(), db); err != nil || deleted != 1 {
+		t.Errorf("DeleteOrphanedUploads() = %d, %v; want 1, nil", deleted, err)
+	}
+}
+
+func TestDeleteOrphanedUploads_WaitsForUploads(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	store := storage.NewLocalStorage(t.TempDir(), []byte("secret"))
+	storage.Default = store
+	defer func() { storage.Default = nil }()
+
+	if err := InsertTestUser(db, "test@example.com", "testuser", "password123"); err != nil {
+		t.Fatalf("Failed to insert test user: %v", err)
+	}
+	content := []byte("reused image bytes")
+	path, err := UploadFile(db, newUploadRequest(t, "a.png", content), "post-file", 1)
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if _, err := db.Exec(`UPDATE files SET updated_at = ?`, time.Now().Add(-2*orphanGracePeriod)); err != nil {
+		t.Fatalf("Failed to age files: %v", err)
+	}
+
+	// An upload of the same content holds its lock while it reuses the stored object
+	sum := sha256.Sum256(content)
+	unlock := lockUpload(hex.EncodeToString(sum[:]))
+	type result struct {
+		deleted int
+		err     error
+	}
+	done := make(chan result)
+	go func() {
+		deleted, err := DeleteOrphanedUploads(context.Background(), db)
+		done <- result{deleted, err}
+	}()
+	select {
+	case <-done:
+		t.Fatal("DeleteOrphanedUploads() did not wait for the upload")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if _, err := db.Exec(`UPDATE files SET updated_at = ?`, time.Now()); err != nil {
+		t.Fatalf("Failed to touch file: %v", err)
+	}
+	unlock()
+
+	if got := <-done; got.err != nil || got.deleted != 0 {
+		t.Errorf("DeleteOrphanedUploads() = %d, %v; want 0, nil", got.deleted, got.err)
+	}
+	if object, err := store.Get(context.Background(), storage.KeyFromPath(path)); err != nil {
+		t.Errorf("reused upload was deleted: %v", err)
+	} else {
+		object.Close()
+	}
+}
+
+func TestUploadFile_EnforcesQuota(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	storage.Default = storage.NewLocalStorage(t.TempDir(), []byte("secret"))
+	defer func() { storage.Default = nil }()
+
+	if err := InsertTestUser(db, "test@example.com", "testuser", "password123"); err != nil {
+		t.Fatalf("Failed to insert test user: %v", err)
+	}
+	t.Setenv("FORUM_QUOTA_USER", "20B")
+
+	if _, err := UploadFile(db, newUploadRequest(t, "a.png", []byte("0123456789")), "post-file", 1); err != nil {
+		t.Fatalf("UploadFile() within quota error = %v", err)
+	}
+	// Re-uploading stored content costs nothing
+	if _, err := UploadFile(db, newUploadRequest(t, "b.png", []byte("0123456789")), "post-file", 1); err != nil {
+		t.Fatalf("UploadFile() of duplicate content error = %v", err)
+	}
+
+	_, err = UploadFile(db, newUploadRequest(t, "c.png", []byte("this upload is too large")), "post-file", 1)
+	var quotaErr *QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("UploadFile() over quota error = %v, want QuotaExceededError", err)
+	}
+	if quotaErr.Used != 10 || quotaErr.Quota != 20 {
+		t.Errorf("QuotaExceededError = %+v, want Used 10 and Quota 20", quotaErr)
+	}
+
+	usage, err := GetTopStorageConsumers(db, 10)
+	if err != nil {
+		t.Fatalf("GetTopStorageConsumers() error = %v", err)
+	}
+	if len(usage) != 1 || usage[0].Username != "testuser" || usage[0].BytesUsed != 10 {
+		t.Errorf("GetTopStorageConsumers() = %+v", usage)
+	}
+}
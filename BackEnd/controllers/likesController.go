@@ -16,68 +16,6 @@ func NewLikesController(db *sql.DB) *LikesController {
 	return &LikesController{DB: db}
 }
 
-func (Lc *LikesController) InsertLikes(like models.Likes) error {
-	// Check if a row with the same post_id and user_id exists
-	existingRow := Lc.DB.QueryRow(`
-        SELECT id FROM likes WHERE post_id = ? AND user_id = ?;
-    `, like.PostId, like.UserId)
-
-	var existingID int
-	err := existingRow.Scan(&existingID)
-	if err != nil && err != sql.ErrNoRows {
-		return fmt.Errorf("failed to check existing like: %w", err)
-	}
-
-	// If a matching row exists, delete it
-	if err == nil {
-		_, err := Lc.DB.Exec(`DELETE FROM likes WHERE id = ?;`, existingID)
-		if err != nil {
-			return fmt.Errorf("failed to delete existing like: %w", err)
-		}
-	}
-
-	// Insert the new like
-	_, err = Lc.DB.Exec(`
-        INSERT INTO likes (post_id, user_id, user_vote)
-        VALUES (?, ?, ?);
-    `, like.PostId, like.UserId, like.UserVote)
-	if err != nil {
-		return fmt.Errorf("failed to insert like: %w", err)
-	}
-
-	return nil
-}
-
-func (lc *LikesController) UpdatePostVotes(postID int) error {
-	// Query to calculate the likes and dislikes from the likes table
-	query := `
-        SELECT 
-            COALESCE(SUM(CASE WHEN user_vote = 'like' THEN 1 ELSE 0 END), 0) AS likes_count,
-            COALESCE(SUM(CASE WHEN user_vote = 'dislike' THEN 1 ELSE 0 END), 0) AS dislikes_count
-        FROM likes
-        WHERE post_id = ?;
-    `
-
-	var likesCount, dislikesCount int
-	err := lc.DB.QueryRow(query, postID).Scan(&likesCount, &dislikesCount)
-	if err != nil {
-		return fmt.Errorf("failed to retrieve vote counts for post %d: %w", postID, err)
-	}
-
-	// Update the likes and dislikes in the posts table
-	updateQuery := `
-        UPDATE posts
-        SET likes = ?, dislikes = ?
-        WHERE id = ?;
-    `
-	_, err = lc.DB.Exec(updateQuery, likesCount, dislikesCount, postID)
-	if err != nil {
-		return fmt.Errorf("failed to update post votes for post %d: %w", postID, err)
-	}
-
-	return nil
-}
-
 func (lc *LikesController) GetPostVotes(postID int) (int, int, error) {
 	// Query to calculate the likes and dislikes from the posts table
 	query := `
@@ -109,65 +47,9 @@ func (lc *LikesController) CheckUserVote(postID, userID int) (string, error) {
 	return userVote, nil
 }
 
-// RemoveUserVote removes the user's vote from the database
-func (lc *LikesController) RemoveUserVote(postID, userID int) error {
-	query := `DELETE FROM likes WHERE post_id = ? AND user_id = ?`
-	_, err := lc.DB.Exec(query, postID, userID)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-// AddUserVote adds the user's vote to the database
-func (lc *LikesController) AddUserVote(postID, userID int, vote string) error {
-	query := `INSERT INTO likes (post_id, user_id, user_vote) VALUES (?, ?, ?)`
-	_, err := lc.DB.Exec(query, postID, userID, vote)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-// HandleVote handles the user's vote (like or dislike)
-func (lc *LikesController) HandleVote(postID, userID int, vote string) error {
-	// Check if the user has already voted
-	existingVote, err := lc.CheckUserVote(postID, userID)
-	if err != nil {
-		return fmt.Errorf("failed to check user vote: %v", err)
-	}
-
-	// If the user has already voted in the same way, remove their vote
-	if existingVote == vote {
-		err = lc.RemoveUserVote(postID, userID)
-		if err != nil {
-			return fmt.Errorf("failed to remove user vote: %v", err)
-		}
-	} else if existingVote != "" {
-		// If the user has voted in the opposite way, remove their previous vote and add the new one
-		err = lc.RemoveUserVote(postID, userID)
-		if err != nil {
-			return fmt.Errorf("failed to remove user vote: %v", err)
-		}
-		err = lc.AddUserVote(postID, userID, vote)
-		if err != nil {
-			return fmt.Errorf("failed to add user vote: %v", err)
-		}
-	} else {
-		// If the user has not voted, add their vote
-		err = lc.AddUserVote(postID, userID, vote)
-		if err != nil {
-			return fmt.Errorf("failed to add user vote: %v", err)
-		}
-	}
-
-	// Update the post's likes and dislikes count
-	err = lc.UpdatePostVotes(postID)
-	if err != nil {
-		return fmt.Errorf("failed to update post votes: %v", err)
-	}
-
-	return nil
+// HandleVote toggles the user's like or dislike on a post and returns the post's new counts
+func (lc *LikesController) HandleVote(postID, userID int, vote string) (models.VoteTally, error) {
+	return NewVoteController(lc.DB).Vote(models.VoteTargetPost, postID, userID, vote)
 }
 
 func (lc *LikesController) GetUserVotes(userID int) (map[string]string, error) {
@@ -203,7 +85,7 @@ func (lc *LikesController) GetUserLikesPosts(userID int) ([]models.Post, error)
         SELECT p.id, p.title, p.author, p.user_id, p.category, p.likes, p.dislikes, p.user_vote, p.content, p.image_url, p.timestamp
         FROM posts p
         INNER JOIN likes l ON p.id = l.post_id
-        WHERE l.user_id = ? AND l.user_vote = 'like';
+        WHERE l.user_id = ? AND l.user_vote = 'like' AND p.deleted_at IS NULL;
     `
 	rows, err := lc.DB.Query(query, userID)
 	if err != nil {
@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/events"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+// Username length limits, matching registration
+const (
+	minUsernameLen = 3
+	maxUsernameLen = 20
+)
+
+type MentionController struct {
+	DB *sql.DB
+}
+
+func NewMentionController(db *sql.DB) *MentionController {
+	return &MentionController{DB: db}
+}
+
+func isUsernameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// FindMentions returns the [start, end) byte offsets of every @username in content, @ included.
+// The @ must not follow a username character, so email addresses are not mentions
+func FindMentions(content string) [][2]int {
+	var spans [][2]int
+	for i := 0; i < len(content); i++ {
+		if content[i] != '@' {
+			continue
+		}
+		if i > 0 && (isUsernameChar(content[i-1]) || content[i-1] == '@') {
+			continue
+		}
+		end := i + 1
+		for end < len(content) && isUsernameChar(content[end]) {
+			end++
+		}
+		if n := end - i - 1; n >= minUsernameLen && n <= maxUsernameLen {
+			spans = append(spans, [2]int{i, end})
+		}
+		i = end - 1
+	}
+	return spans
+}
+
+// ParseMentions returns the distinct usernames @mentioned in content, in order of appearance
+func ParseMentions(content string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, span := range FindMentions(content) {
+		name := content[span[0]+1 : span[1]]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// SaveMentions replaces the mentions recorded for a post or comment with those in content and
+// alerts the users who were not mentioned in it before, so editing does not alert twice.
+// Unknown usernames, the author and users who blocked or muted the author are skipped.
+// It returns the IDs of the users alerted
+func (mc *MentionController) SaveMentions(sourceType string, sourceID, postID, authorID int, content string) ([]int, error) {
+	mentioned, err := mc.resolveUsernames(ParseMentions(content))
+	if err != nil {
+		return nil, err
+	}
+	delete(mentioned, authorID)
+
+	tx, err := mc.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT user_id FROM mentions WHERE source_type = ? AND source_id = ?`, sourceType, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch mentions: %w", err)
+	}
+	previous := make(map[int]bool)
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan mention: %w", err)
+		}
+		previous[userID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch mentions: %w", err)
+	}
+
+	for userID := range previous {
+		if mentioned[userID] {
+			continue
+		}
+		_, err := tx.Exec(`DELETE FROM mentions WHERE source_type = ? AND source_id = ? AND user_id = ?`, sourceType, sourceID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove mention: %w", err)
+		}
+	}
+
+	var added []int
+	now := time.Now()
+	for userID := range mentioned {
+		if previous[userID] {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO mentions (source_type, source_id, post_id, user_id, author_id, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, sourceType, sourceID, postID, userID, authorID, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record mention: %w", err)
+		}
+		added = append(added, userID)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	sort.Ints(added)
+
+	var alerted []int
+	var recipients []events.Recipient
+	for _, userID := range added {
+		hidden, err := HiddenAuthors(mc.DB, userID)
+		if err != nil {
+			return nil, err
+		}
+		if hidden[authorID] {
+			continue
+		}
+		alerted = append(alerted, userID)
+		recipients = append(recipients, events.Recipient{UserID: userID, Reason: events.ReasonMention})
+	}
+	if len(recipients) == 0 {
+		return nil, nil
+	}
+
+	ev := events.Event{
+		Type:      events.Mentioned,
+		ActorID:   authorID,
+		PostID:    postID,
+		CreatedAt: now,
+	}
+	if sourceType == models.MentionSourceComment {
+		ev.CommentID = sourceID
+	}
+	if err := NewSubscriptionController(mc.DB).Deliver(ev, recipients); err != nil {
+		return nil, err
+	}
+	return alerted, nil
+}
+
+// resolveUsernames maps the given usernames to user IDs, ignoring unknown ones
+func (mc *MentionController) resolveUsernames(usernames []string) (map[int]bool, error) {
+	ids := make(map[int]bool)
+	if len(usernames) == 0 {
+		return ids, nil
+	}
+
+	args := make([]interface{}, len(usernames))
+	for i, name := range usernames {
+		args[i] = name
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(usernames)), ",")
+	rows, err := mc.DB.Query(`SELECT id FROM users WHERE username IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve mentioned users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// GetMentions returns the mentions of the given user, most recent first
+func (mc *MentionController) GetMentions(userID int) ([]models.Mention, error) {
+	rows, err := mc.DB.Query(`
+		SELECT source_type, source_id, post_id, user_id, author_id, created_at
+		FROM mentions WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch mentions: %w", err)
+	}
+	defer rows.Close()
+
+	var result []models.Mention
+	for rows.Next() {
+		var m models.Mention
+		if err := rows.Scan(&m.SourceType, &m.SourceID, &m.PostID, &m.UserID, &m.AuthorID, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan mention: %w", err)
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// SuggestUsernames returns up to limit usernames starting with prefix, shortest first,
+// for the @mention autocomplete
+func SuggestUsernames(db *sql.DB, prefix string, limit int) ([]string, error) {
+	if prefix == "" || len(prefix) > maxUsernameLen {
+		return []string{}, nil
+	}
+	for i := 0; i < len(prefix); i++ {
+		if !isUsernameChar(prefix[i]) {
+			return []string{}, nil
+		}
+	}
+
+	// Usernames only hold letters, digits and underscores, so only _ needs escaping
+	pattern := strings.ReplaceAll(prefix, "_", `\_`) + "%"
+	rows, err := db.Query(`
+		SELECT username FROM users
+		WHERE username LIKE ? ESCAPE '\'
+		ORDER BY LENGTH(username), username
+		LIMIT ?
+	`, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest usernames: %w", err)
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan username: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/events"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"none", "hello there", nil},
+		{"single", "thanks @alice!", []string{"alice"}},
+		{"start of text", "@bob_2 see this", []string{"bob_2"}},
+		{"duplicates", "@alice and @alice again", []string{"alice"}},
+		{"several", "(@alice, @bob_2)", []string{"alice", "bob_2"}},
+		{"email address", "mail me at me@example.com", nil},
+		{"double at", "@@alice", nil},
+		{"too short", "hi @al", nil},
+		{"too long", "@abcdefghijklmnopqrstu", nil},
+		{"bare at", "meet @ noon", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseMentions(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMentions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveMentions(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	for i := 1; i <= 4; i++ {
+		if err := InsertTestUser(db, fmt.Sprintf("user%d@example.com", i), fmt.Sprintf("user%d", i), "password123"); err != nil {
+			t.Fatalf("Failed to insert test user: %v", err)
+		}
+	}
+	// user4 blocked user1, so they are recorded but not alerted
+	if err := BlockUser(db, 4, 1); err != nil {
+		t.Fatalf("BlockUser() error = %v", err)
+	}
+
+	var published []events.Event
+	defer events.Register(func(ev events.Event, recipients []events.Recipient) {
+		if ev.Type == events.Mentioned {
+			published = append(published, ev)
+		}
+	})()
+
+	postID, err := NewPostController(db).InsertPost(models.Post{
+		Title: "title", Author: "user1", UserID: 1, Category: "music", Content: "content", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("InsertPost() error = %v", err)
+	}
+	mc := NewMentionController(db)
+
+	steps := []struct {
+		name    string
+		content string
+		want    []int
+	}{
+		{"new mentions", "@user2 @user1 @user4 @nobody", []int{2}},
+		{"edit keeps user2", "@user2 and @user3", []int{3}},
+		{"edit drops user3", "@user2", nil},
+		{"user3 mentioned again", "@user2 @user3", []int{3}},
+	}
+	for _, tt := range steps {
+		got, err := mc.SaveMentions(models.MentionSourceComment, 7, postID, 1, tt.content)
+		if err != nil {
+			t.Fatalf("%s: SaveMentions() error = %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: SaveMentions() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if len(published) != 3 || published[0].CommentID != 7 || published[0].PostID != postID || published[0].ActorID != 1 {
+		t.Errorf("published = %+v", published)
+	}
+
+	mentions, err := mc.GetMentions(4)
+	if err != nil {
+		t.Fatalf("GetMentions() error = %v", err)
+	}
+	if len(mentions) != 0 {
+		t.Errorf("GetMentions() for user4 = %+v, want none after the edit", mentions)
+	}
+	mentions, _ = mc.GetMentions(2)
+	if len(mentions) != 1 || mentions[0].SourceType != models.MentionSourceComment || mentions[0].AuthorID != 1 {
+		t.Errorf("GetMentions() for user2 = %+v", mentions)
+	}
+
+	inbox, err := NewSubscriptionController(db).GetEvents(3, time.Time{})
+	if err != nil {
+		t.Fatalf("GetEvents() error = %v", err)
+	}
+	if len(inbox) != 2 || inbox[0].Reason != events.ReasonMention {
+		t.Errorf("GetEvents() = %+v", inbox)
+	}
+}
+
+func TestSuggestUsernames(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	for _, name := range []string{"alice", "alicia", "al_x", "alxander", "bob"} {
+		if err := InsertTestUser(db, name+"@example.com", name, "password123"); err != nil {
+			t.Fatalf("Failed to insert test user: %v", err)
+		}
+	}
+
+	tests := []struct {
+		prefix string
+		limit  int
+		want   []string
+	}{
+		{"ali", 10, []string{"alice", "alicia"}},
+		{"al", 2, []string{"al_x", "alice"}},
+		{"al_", 10, []string{"al_x"}},
+		{"AL", 10, []string{"al_x", "alice", "alicia", "alxander"}},
+		{"", 10, []string{}},
+		{"a%", 10, []string{}},
+		{"zed", 10, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			got, err := SuggestUsernames(db, tt.prefix, tt.limit)
+			if err != nil {
+				t.Fatalf("SuggestUsernames() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SuggestUsernames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
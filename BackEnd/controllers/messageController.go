@@ -0,0 +1,408 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+	"github.com/Raymond9734/forum.git/BackEnd/storage"
+)
+
+var (
+	ErrConversationNotFound = errors.New("conversation not found")
+	ErrNoRecipients         = errors.New("add at least one recipient")
+	ErrTooManyRecipients    = fmt.Errorf("conversations can have at most %d members", models.MaxConversationMembers)
+	ErrRecipientBlocked     = errors.New("you cannot message this user")
+	ErrEmptyMessage         = errors.New("message cannot be empty")
+	ErrMessageTooLong       = fmt.Errorf("messages must be at most %d characters", models.MaxMessageChars)
+	ErrSubjectTooLong       = fmt.Errorf("subjects must be at most %d characters", models.MaxSubjectChars)
+)
+
+// lastMessageChars is the length of the last message preview in the conversation list
+const lastMessageChars = 100
+
+// attachmentURLTTL is how long the signed URL of a message attachment stays valid
+const attachmentURLTTL = time.Hour
+
+type MessageController struct {
+	DB *sql.DB
+}
+
+func NewMessageController(db *sql.DB) *MessageController {
+	return &MessageController{DB: db}
+}
+
+// validateMessage trims the message and checks its length; a message with an attachment may have
+// no text
+func validateMessage(content, attachment string) (string, error) {
+	content = strings.TrimSpace(content)
+	if content == "" && attachment == "" {
+		return "", ErrEmptyMessage
+	}
+	if len([]rune(content)) > models.MaxMessageChars {
+		return "", ErrMessageTooLong
+	}
+	return content, nil
+}
+
+// StartConversation sends the first message to the named users. A one-to-one message without a
+// subject continues the existing conversation between the two users if there is one.
+func (mc *MessageController) StartConversation(senderID int, usernames []string, subject, content string) (int, error) {
+	content, err := validateMessage(content, "")
+	if err != nil {
+		return 0, err
+	}
+	subject = strings.TrimSpace(subject)
+	if len([]rune(subject)) > models.MaxSubjectChars {
+		return 0, ErrSubjectTooLong
+	}
+
+	seen := map[int]bool{senderID: true}
+	var recipients []int
+	for _, username := range usernames {
+		if username = strings.TrimPrefix(strings.TrimSpace(username), "@"); username == "" {
+			continue
+		}
+		userID, err := GetUserIDByUsername(mc.DB, username)
+		if errors.Is(err, ErrUserNotFound) {
+			return 0, fmt.Errorf("%w: %s", ErrUserNotFound, username)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if !seen[userID] {
+			seen[userID] = true
+			recipients = append(recipients, userID)
+		}
+	}
+	if len(recipients) == 0 {
+		return 0, ErrNoRecipients
+	}
+	if len(recipients)+1 > models.MaxConversationMembers {
+		return 0, ErrTooManyRecipients
+	}
+	if err := mc.checkBlocked(senderID, recipients); err != nil {
+		return 0, err
+	}
+
+	conversationID := 0
+	if len(recipients) == 1 && subject == "" {
+		err := mc.DB.QueryRow(`
+			SELECT c.id FROM conversations c
+			JOIN conversation_members a ON a.conversation_id = c.id AND a.user_id = ?
+			JOIN conversation_members b ON b.conversation_id = c.id AND b.user_id = ?
+			WHERE c.subject = '' AND (SELECT COUNT(*) FROM conversation_members m WHERE m.conversation_id = c.id) = 2
+			LIMIT 1
+		`, senderID, recipients[0]).Scan(&conversationID)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, fmt.Errorf("failed to look up conversation: %w", err)
+		}
+	}
+
+	tx, err := mc.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if conversationID == 0 {
+		result, err := tx.Exec(`
+			INSERT INTO conversations (subject, created_by, created_at, updated_at) VALUES (?, ?, ?, ?)
+		`, subject, senderID, now, now)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create conversation: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get conversation ID: %w", err)
+		}
+		conversationID = int(id)
+
+		for _, userID := range append([]int{senderID}, recipients...) {
+			_, err := tx.Exec(`
+				INSERT INTO conversation_members (conversation_id, user_id, joined_at) VALUES (?, ?, ?)
+			`, conversationID, userID, now)
+			if err != nil {
+				return 0, fmt.Errorf("failed to add conversation member: %w", err)
+			}
+		}
+	}
+
+	if _, err := addMessage(tx, conversationID, senderID, content, "", now); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return conversationID, nil
+}
+
+// SendMessage adds a message to a conversation the sender is a member of. The optional attachment
+// is the path of a file stored by UploadPrivateFile
+func (mc *MessageController) SendMessage(conversationID, senderID int, content, attachment string) (int, error) {
+	content, err := validateMessage(content, attachment)
+	if err != nil {
+		return 0, err
+	}
+
+	members, err := mc.memberIDs(conversationID)
+	if err != nil {
+		return 0, err
+	}
+	var others []int
+	isMember := false
+	for _, userID := range members {
+		if userID == senderID {
+			isMember = true
+		} else {
+			others = append(others, userID)
+		}
+	}
+	if !isMember {
+		return 0, ErrConversationNotFound
+	}
+	if err := mc.checkBlocked(senderID, others); err != nil {
+		return 0, err
+	}
+
+	tx, err := mc.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	messageID, err := addMessage(tx, conversationID, senderID, content, attachment, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return messageID, nil
+}
+
+// addMessage stores a message and retains its attachment, bumps the conversation and marks it read
+// for the sender
+func addMessage(tx *sql.Tx, conversationID, senderID int, content, attachment string, now time.Time) (int, error) {
+	result, err := tx.Exec(`
+		INSERT INTO messages (conversation_id, sender_id, content, attachment_url, created_at) VALUES (?, ?, ?, ?, ?)
+	`, conversationID, senderID, content, attachment, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert message: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get message ID: %w", err)
+	}
+	if err := retainUpload(tx, attachment); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`UPDATE conversations SET updated_at = ? WHERE id = ?`, now, conversationID); err != nil {
+		return 0, fmt.Errorf("failed to update conversation: %w", err)
+	}
+	_, err = tx.Exec(`
+		UPDATE conversation_members SET last_read_message_id = ? WHERE conversation_id = ? AND user_id = ?
+	`, id, conversationID, senderID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update read state: %w", err)
+	}
+	return int(id), nil
+}
+
+// memberIDs lists the members of a conversation
+func (mc *MessageController) memberIDs(conversationID int) ([]int, error) {
+	rows, err := mc.DB.Query(`SELECT user_id FROM conversation_members WHERE conversation_id = ?`, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch conversation members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan conversation member: %w", err)
+		}
+		members = append(members, userID)
+	}
+	return members, rows.Err()
+}
+
+// checkBlocked returns ErrRecipientBlocked if any of the recipients has blocked the sender
+func (mc *MessageController) checkBlocked(senderID int, recipients []int) error {
+	for _, userID := range recipients {
+		blocked, err := IsBlocked(mc.DB, userID, senderID)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return ErrRecipientBlocked
+		}
+	}
+	return nil
+}
+
+// GetConversations lists the user's conversations, most recently active first
+func (mc *MessageController) GetConversations(userID int) ([]models.Conversation, error) {
+	return mc.listConversations(userID, 0)
+}
+
+// GetConversation returns one of the user's conversations, or ErrConversationNotFound
+// if the user is not a member of it
+func (mc *MessageController) GetConversation(conversationID, userID int) (models.Conversation, error) {
+	conversations, err := mc.listConversations(userID, conversationID)
+	if err != nil {
+		return models.Conversation{}, err
+	}
+	if len(conversations) == 0 {
+		return models.Conversation{}, ErrConversationNotFound
+	}
+	return conversations[0], nil
+}
+
+// listConversations fetches the user's conversations, or only the given one when conversationID is not 0
+func (mc *MessageController) listConversations(userID, conversationID int) ([]models.Conversation, error) {
+	rows, err := mc.DB.Query(`
+		SELECT c.id, c.subject, c.updated_at,
+		       COALESCE((SELECT CASE WHEN content = '' THEN 'Attachment' ELSE content END
+		                 FROM messages WHERE conversation_id = c.id ORDER BY id DESC LIMIT 1), ''),
+		       (SELECT COUNT(*) FROM messages msg
+		        WHERE msg.conversation_id = c.id AND msg.id > m.last_read_message_id AND msg.sender_id != m.user_id)
+		FROM conversations c
+		JOIN conversation_members m ON m.conversation_id = c.id AND m.user_id = ?
+		WHERE ? = 0 OR c.id = ?
+		ORDER BY c.updated_at DESC, c.id DESC
+	`, userID, conversationID, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch conversations: %w", err)
+	}
+	defer rows.Close()
+
+	conversations := make([]models.Conversation, 0)
+	index := make(map[int]int)
+	for rows.Next() {
+		var c models.Conversation
+		if err := rows.Scan(&c.ID, &c.Subject, &c.UpdatedAt, &c.LastMessage, &c.Unread); err != nil {
+			return nil, fmt.Errorf("failed to scan conversation: %w", err)
+		}
+		if runes := []rune(c.LastMessage); len(runes) > lastMessageChars {
+			c.LastMessage = string(runes[:lastMessageChars]) + "..."
+		}
+		index[c.ID] = len(conversations)
+		conversations = append(conversations, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	members, err := mc.DB.Query(`
+		SELECT cm.conversation_id, u.id, u.username
+		FROM conversation_members cm
+		JOIN users u ON u.id = cm.user_id
+		WHERE cm.conversation_id IN (SELECT conversation_id FROM conversation_members WHERE user_id = ?)
+		ORDER BY cm.joined_at, u.id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch conversation members: %w", err)
+	}
+	defer members.Close()
+
+	for members.Next() {
+		var id int
+		var member models.ConversationMember
+		if err := members.Scan(&id, &member.UserID, &member.Username); err != nil {
+			return nil, fmt.Errorf("failed to scan conversation member: %w", err)
+		}
+		if i, ok := index[id]; ok {
+			conversations[i].Members = append(conversations[i].Members, member)
+		}
+	}
+	return conversations, members.Err()
+}
+
+// GetMessages returns up to models.MessagesPageSize messages of the conversation in the order they
+// were sent, starting with the most recent or, when before is not 0, those older than that message.
+// Attachments are linked through signed URLs valid for attachmentURLTTL
+func (mc *MessageController) GetMessages(conversationID, userID, before int) ([]models.Message, error) {
+	var isMember bool
+	err := mc.DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM conversation_members WHERE conversation_id = ? AND user_id = ?)
+	`, conversationID, userID).Scan(&isMember)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check conversation membership: %w", err)
+	}
+	if !isMember {
+		return nil, ErrConversationNotFound
+	}
+
+	rows, err := mc.DB.Query(`
+		SELECT m.id, m.conversation_id, m.sender_id, COALESCE(u.username, ''), m.content, m.attachment_url, m.created_at
+		FROM messages m
+		LEFT JOIN users u ON u.id = m.sender_id
+		WHERE m.conversation_id = ? AND (? = 0 OR m.id < ?)
+		ORDER BY m.id DESC
+		LIMIT ?
+	`, conversationID, before, before, models.MessagesPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]models.Message, 0)
+	for rows.Next() {
+		var m models.Message
+		var attachment string
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.Sender, &m.Content, &attachment, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		if attachment != "" {
+			m.AttachmentURL, err = storage.Default.SignedURL(storage.KeyFromPath(attachment), attachmentURLTTL)
+			if err != nil {
+				return nil, fmt.Errorf("failed to sign attachment URL of message %d: %w", m.ID, err)
+			}
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Oldest first
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+// MarkRead marks every message of the conversation as read by the user
+func (mc *MessageController) MarkRead(conversationID, userID int) error {
+	_, err := mc.DB.Exec(`
+		UPDATE conversation_members
+		SET last_read_message_id = (SELECT COALESCE(MAX(id), 0) FROM messages WHERE conversation_id = ?)
+		WHERE conversation_id = ? AND user_id = ?
+	`, conversationID, conversationID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark conversation read: %w", err)
+	}
+	return nil
+}
+
+// UnreadCount returns the number of unread messages across all of the user's conversations
+func (mc *MessageController) UnreadCount(userID int) (int, error) {
+	var unread int
+	err := mc.DB.QueryRow(`
+		SELECT COUNT(*) FROM messages msg
+		JOIN conversation_members m ON m.conversation_id = msg.conversation_id AND m.user_id = ?
+		WHERE msg.id > m.last_read_message_id AND msg.sender_id != m.user_id
+	`, userID).Scan(&unread)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread messages: %w", err)
+	}
+	return unread, nil
+}
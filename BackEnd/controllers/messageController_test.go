@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+func TestMessageController(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	var everyone []string
+	for i := 1; i <= models.MaxConversationMembers+1; i++ {
+		username := fmt.Sprintf("user%d", i)
+		if err := InsertTestUser(db, username+"@example.com", username, "password123"); err != nil {
+			t.Fatalf("Failed to insert test user: %v", err)
+		}
+		everyone = append(everyone, username)
+	}
+	mc := NewMessageController(db)
+
+	direct, err := mc.StartConversation(1, []string{"user2"}, "", "hello")
+	if err != nil {
+		t.Fatalf("StartConversation() error = %v", err)
+	}
+	again, err := mc.StartConversation(1, []string{" @user2 "}, "", "are you there?")
+	if err != nil {
+		t.Fatalf("StartConversation() error = %v", err)
+	}
+	if again != direct {
+		t.Errorf("one-to-one conversation was not reused: got %d, want %d", again, direct)
+	}
+	group, err := mc.StartConversation(1, []string{"user2", "user3", "user2"}, "Plans", "hi all")
+	if err != nil {
+		t.Fatalf("StartConversation() error = %v", err)
+	}
+	if group == direct {
+		t.Errorf("group conversation reused the one-to-one conversation")
+	}
+
+	inbox, err := mc.GetConversations(2)
+	if err != nil {
+		t.Fatalf("GetConversations() error = %v", err)
+	}
+	if len(inbox) != 2 || inbox[0].ID != group || len(inbox[0].Members) != 3 || inbox[1].Unread != 2 {
+		t.Fatalf("unexpected inbox: %+v", inbox)
+	}
+	if unread, _ := mc.UnreadCount(2); unread != 3 {
+		t.Errorf("UnreadCount() = %d, want 3", unread)
+	}
+	if unread, _ := mc.UnreadCount(1); unread != 0 {
+		t.Errorf("UnreadCount() for the sender = %d, want 0", unread)
+	}
+	if err := mc.MarkRead(direct, 2); err != nil {
+		t.Fatalf("MarkRead() error = %v", err)
+	}
+	if unread, _ := mc.UnreadCount(2); unread != 1 {
+		t.Errorf("UnreadCount() after MarkRead = %d, want 1", unread)
+	}
+
+	tests := []struct {
+		name      string
+		usernames []string
+		content   string
+		wantErr   error
+	}{
+		{"only the sender", []string{" ", "user1"}, "hi", ErrNoRecipients},
+		{"unknown user", []string{"nobody"}, "hi", ErrUserNotFound},
+		{"empty message", []string{"user2"}, "   ", ErrEmptyMessage},
+		{"message too long", []string{"user2"}, strings.Repeat("a", models.MaxMessageChars+1), ErrMessageTooLong},
+		{"too many members", everyone, "hi", ErrTooManyRecipients},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := mc.StartConversation(1, tt.usernames, "", tt.content); !errors.Is(err, tt.wantErr) {
+				t.Errorf("StartConversation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Only members can read or write
+	if _, err := mc.SendMessage(direct, 3, "let me in", ""); err != ErrConversationNotFound {
+		t.Errorf("SendMessage() by a non-member error = %v, want %v", err, ErrConversationNotFound)
+	}
+	if _, err := mc.GetMessages(direct, 3, 0); err != ErrConversationNotFound {
+		t.Errorf("GetMessages() by a non-member error = %v, want %v", err, ErrConversationNotFound)
+	}
+	if _, err := mc.GetConversation(direct, 3); err != ErrConversationNotFound {
+		t.Errorf("GetConversation() by a non-member error = %v, want %v", err, ErrConversationNotFound)
+	}
+
+	// Blocked senders cannot reach the blocker, who can still write to them
+	if err := BlockUser(db, 1, 1); err != ErrCannotBlockSelf {
+		t.Errorf("BlockUser() self error = %v, want %v", err, ErrCannotBlockSelf)
+	}
+	if err := BlockUser(db, 2, 1); err != nil {
+		t.Fatalf("BlockUser() error = %v", err)
+	}
+	if _, err := mc.SendMessage(direct, 1, "hello?", ""); err != ErrRecipientBlocked {
+		t.Errorf("SendMessage() to a blocker error = %v, want %v", err, ErrRecipientBlocked)
+	}
+	if _, err := mc.StartConversation(1, []string{"user3", "user2"}, "", "hello?"); err != ErrRecipientBlocked {
+		t.Errorf("StartConversation() with a blocker error = %v, want %v", err, ErrRecipientBlocked)
+	}
+	if _, err := mc.SendMessage(direct, 2, "go away", ""); err != nil {
+		t.Errorf("SendMessage() by the blocker error = %v", err)
+	}
+	if err := UnblockUser(db, 2, 1); err != nil {
+		t.Fatalf("UnblockUser() error = %v", err)
+	}
+
+	// Messages are paged from the most recent backwards
+	for i := 0; i < models.MessagesPageSize; i++ {
+		if _, err := mc.SendMessage(direct, 1, fmt.Sprintf("message %d", i), ""); err != nil {
+			t.Fatalf("SendMessage() error = %v", err)
+		}
+	}
+	latest, err := mc.GetMessages(direct, 2, 0)
+	if err != nil {
+		t.Fatalf("GetMessages() error = %v", err)
+	}
+	if len(latest) != models.MessagesPageSize || latest[len(latest)-1].Content != fmt.Sprintf("message %d", models.MessagesPageSize-1) {
+		t.Fatalf("GetMessages() returned %d messages ending with %q", len(latest), latest[len(latest)-1].Content)
+	}
+	older, err := mc.GetMessages(direct, 2, latest[0].ID)
+	if err != nil {
+		t.Fatalf("GetMessages() error = %v", err)
+	}
+	if len(older) != 3 || older[0].Content != "hello" || older[0].Sender != "user1" {
+		t.Errorf("GetMessages() older = %+v", older)
+	}
+}
@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+var (
+	ErrPollNotFound      = errors.New("poll not found")
+	ErrPollClosed        = errors.New("poll is closed")
+	ErrAlreadyVoted      = errors.New("you have already voted in this poll")
+	ErrInvalidPollChoice = errors.New("invalid poll choice")
+)
+
+type PollController struct {
+	DB *sql.DB
+}
+
+func NewPollController(db *sql.DB) *PollController {
+	return &PollController{DB: db}
+}
+
+// ValidatePoll normalizes the poll options and checks them against the poll limits
+func ValidatePoll(poll *models.Poll) error {
+	seen := make(map[string]bool)
+	options := make([]models.PollOption, 0, len(poll.Options))
+	for _, option := range poll.Options {
+		option.Text = strings.TrimSpace(option.Text)
+		if option.Text == "" {
+			continue
+		}
+		if len(option.Text) > models.PollMaxOptionChars {
+			return fmt.Errorf("poll options must be at most %d characters", models.PollMaxOptionChars)
+		}
+		if seen[strings.ToLower(option.Text)] {
+			return errors.New("poll options must be unique")
+		}
+		seen[strings.ToLower(option.Text)] = true
+		options = append(options, option)
+	}
+	if len(options) < models.PollMinOptions || len(options) > models.PollMaxOptions {
+		return fmt.Errorf("a poll needs between %d and %d options", models.PollMinOptions, models.PollMaxOptions)
+	}
+	poll.Options = options
+
+	poll.Question = strings.TrimSpace(poll.Question)
+	if len(poll.Question) > 300 {
+		return errors.New("poll question must be at most 300 characters")
+	}
+	if poll.ClosesAt != nil && !poll.ClosesAt.After(time.Now()) {
+		return errors.New("poll closing time must be in the future")
+	}
+	return nil
+}
+
+// insertPoll stores a validated poll for a post inside the post's transaction
+func insertPoll(tx *sql.Tx, postID int, poll *models.Poll) error {
+	var closesAt sql.NullTime
+	if poll.ClosesAt != nil {
+		closesAt = sql.NullTime{Time: *poll.ClosesAt, Valid: true}
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO polls (post_id, question, multiple_choice, closes_at, created_at)
+		VALUES (?, ?, ?, ?, ?);
+	`, postID, poll.Question, poll.MultipleChoice, closesAt, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to insert poll: %w", err)
+	}
+	pollID, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+
+	for i, option := range poll.Options {
+		_, err := tx.Exec(`INSERT INTO poll_options (poll_id, position, text) VALUES (?, ?, ?)`, pollID, i, option.Text)
+		if err != nil {
+			return fmt.Errorf("failed to insert poll option: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetPollByPostID returns the poll of a post as seen by viewerID (0 for anonymous viewers),
+// or nil if the post has no poll
+func (pc *PollController) GetPollByPostID(postID, viewerID int) (*models.Poll, error) {
+	poll := &models.Poll{PostID: postID}
+	var closesAt sql.NullTime
+	err := pc.DB.QueryRow(`
+		SELECT id, question, multiple_choice, closes_at FROM polls WHERE post_id = ?
+	`, postID).Scan(&poll.ID, &poll.Question, &poll.MultipleChoice, &closesAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch poll: %w", err)
+	}
+	if closesAt.Valid {
+		poll.ClosesAt = &closesAt.Time
+		poll.IsClosed = !time.Now().Before(closesAt.Time)
+	}
+	return poll, pc.loadTallies(poll, viewerID)
+}
+
+// loadTallies fills in the options, vote counts and the viewer's own choices
+func (pc *PollController) loadTallies(poll *models.Poll, viewerID int) error {
+	rows, err := pc.DB.Query(`
+		SELECT o.id, o.text, COUNT(bo.ballot_id),
+		       COALESCE(MAX(b.user_id = ?), 0)
+		FROM poll_options o
+		LEFT JOIN poll_ballot_options bo ON bo.option_id = o.id
+		LEFT JOIN poll_ballots b ON b.id = bo.ballot_id
+		WHERE o.poll_id = ?
+		GROUP BY o.id
+		ORDER BY o.position;
+	`, viewerID, poll.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch poll options: %w", err)
+	}
+	defer rows.Close()
+
+	poll.Options = nil
+	for rows.Next() {
+		var option models.PollOption
+		if err := rows.Scan(&option.ID, &option.Text, &option.Votes, &option.Chosen); err != nil {
+			return fmt.Errorf("failed to scan poll option: %w", err)
+		}
+		poll.HasVoted = poll.HasVoted || option.Chosen
+		poll.Options = append(poll.Options, option)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	err = pc.DB.QueryRow(`SELECT COUNT(*) FROM poll_ballots WHERE poll_id = ?`, poll.ID).Scan(&poll.TotalVoters)
+	if err != nil {
+		return fmt.Errorf("failed to count poll voters: %w", err)
+	}
+
+	poll.ResultsVisible = poll.HasVoted || poll.IsClosed
+	if !poll.ResultsVisible {
+		// Hide the tallies so early results do not sway voters
+		poll.TotalVoters = 0
+		for i := range poll.Options {
+			poll.Options[i].Votes = 0
+		}
+		return nil
+	}
+	for i := range poll.Options {
+		if poll.TotalVoters > 0 {
+			poll.Options[i].Percent = poll.Options[i].Votes * 100 / poll.TotalVoters
+		}
+	}
+	return nil
+}
+
+// Vote records the user's ballot; each user may vote once per poll
+func (pc *PollController) Vote(postID, userID int, optionIDs []int) (*models.Poll, error) {
+	poll, err := pc.GetPollByPostID(postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if poll == nil {
+		return nil, ErrPollNotFound
+	}
+	if poll.IsClosed {
+		return nil, ErrPollClosed
+	}
+	if poll.HasVoted {
+		return nil, ErrAlreadyVoted
+	}
+
+	// Every choice must be a distinct option of this poll
+	valid := make(map[int]bool, len(poll.Options))
+	for _, option := range poll.Options {
+		valid[option.ID] = true
+	}
+	chosen := make(map[int]bool, len(optionIDs))
+	for _, id := range optionIDs {
+		if !valid[id] || chosen[id] {
+			return nil, ErrInvalidPollChoice
+		}
+		chosen[id] = true
+	}
+	if len(chosen) == 0 || (!poll.MultipleChoice && len(chosen) > 1) {
+		return nil, ErrInvalidPollChoice
+	}
+
+	tx, err := pc.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The UNIQUE(poll_id, user_id) constraint settles concurrent double votes
+	result, err := tx.Exec(`
+		INSERT OR IGNORE INTO poll_ballots (poll_id, user_id, created_at) VALUES (?, ?, ?)
+	`, poll.ID, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert ballot: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return nil, ErrAlreadyVoted
+	}
+	ballotID, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+
+	for id := range chosen {
+		_, err := tx.Exec(`INSERT INTO poll_ballot_options (ballot_id, option_id) VALUES (?, ?)`, ballotID, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert ballot choice: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return pc.GetPollByPostID(postID, userID)
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+func TestPollController_Vote(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	for _, u := range []string{"alice", "bob"} {
+		if err := InsertTestUser(db, u+"@example.com", u, "password123"); err != nil {
+			t.Fatalf("Failed to insert test user: %v", err)
+		}
+	}
+
+	poll := &models.Poll{
+		Question: "Tabs or spaces?",
+		Options:  []models.PollOption{{Text: "Tabs"}, {Text: " Spaces "}, {Text: ""}},
+	}
+	if err := ValidatePoll(poll); err != nil {
+		t.Fatalf("ValidatePoll() error = %v", err)
+	}
+	postID, err := NewPostController(db).InsertPost(models.Post{
+		Title: "Poll", Author: "alice", UserID: 1, Category: "programming", Timestamp: time.Now(), Poll: poll,
+	})
+	if err != nil {
+		t.Fatalf("InsertPost() error = %v", err)
+	}
+
+	pc := NewPollController(db)
+	before, err := pc.GetPollByPostID(postID, 2)
+	if err != nil || before == nil {
+		t.Fatalf("GetPollByPostID() = %v, %v", before, err)
+	}
+	if len(before.Options) != 2 || before.ResultsVisible {
+		t.Fatalf("expected 2 options with hidden results, got %+v", before)
+	}
+	tabs, spaces := before.Options[0].ID, before.Options[1].ID
+
+	tests := []struct {
+		name    string
+		userID  int
+		choices []int
+		wantErr error
+	}{
+		{"several choices in single-choice poll", 1, []int{tabs, spaces}, ErrInvalidPollChoice},
+		{"unknown option", 1, []int{-1}, ErrInvalidPollChoice},
+		{"valid vote", 1, []int{tabs}, nil},
+		{"second vote", 1, []int{spaces}, ErrAlreadyVoted},
+		{"other user", 2, []int{tabs}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pc.Vote(postID, tt.userID, tt.choices)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Vote() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	after, err := pc.GetPollByPostID(postID, 2)
+	if err != nil {
+		t.Fatalf("GetPollByPostID() error = %v", err)
+	}
+	if !after.ResultsVisible || after.TotalVoters != 2 || after.Options[0].Votes != 2 || after.Options[0].Percent != 100 {
+		t.Errorf("unexpected tallies: %+v", after)
+	}
+}
@@ -4,9 +4,13 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/Raymond9734/forum.git/BackEnd/logger"
 	"github.com/Raymond9734/forum.git/BackEnd/models"
+	"github.com/Raymond9734/forum.git/BackEnd/ranking"
 )
 
 type PostController struct {
@@ -18,11 +22,20 @@ func NewPostController(db *sql.DB) *PostController {
 }
 
 func (pc *PostController) InsertPost(post models.Post) (int, error) {
+	// Insert the post and reference its image atomically
+	tx, err := pc.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	// Insert the post with the UserID
-	result, err := pc.DB.Exec(`
-		INSERT INTO posts (title, user_id, author, category, likes, dislikes, user_vote, content, timestamp, image_url)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
-	`, post.Title, post.UserID, post.Author, post.Category, post.Likes, post.Dislikes, post.UserVote, post.Content, post.Timestamp, post.ImageUrl)
+	result, err := tx.Exec(`
+		INSERT INTO posts (title, user_id, author, category, likes, dislikes, user_vote, content, timestamp, image_url,
+			hot_score, controversy_score)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+	`, post.Title, post.UserID, post.Author, post.Category, post.Likes, post.Dislikes, post.UserVote, post.Content, post.Timestamp, post.ImageUrl,
+		ranking.Hot(post.Likes, post.Dislikes, post.Timestamp), ranking.Controversy(post.Likes, post.Dislikes))
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert post: %w", err)
 	}
@@ -33,19 +46,109 @@ func (pc *PostController) InsertPost(post models.Post) (int, error) {
 		return 0, fmt.Errorf("failed to get last insert ID: %w", err)
 	}
 
+	if err := retainUpload(tx, post.ImageUrl.String); err != nil {
+		return 0, err
+	}
+
+	// Attach the optional poll
+	if post.Poll != nil {
+		if err := insertPoll(tx, int(postID), post.Poll); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return int(postID), nil
 }
 
 func (pc *PostController) GetAllPosts() ([]models.Post, error) {
+	return pc.GetRankedPosts(ranking.DefaultSort, ranking.PeriodAll, "")
+}
+
+// inCategory matches posts whose comma separated categories include the lowercase category bound to it
+const inCategory = `INSTR(',' || LOWER(REPLACE(REPLACE(TRIM(category), ', ', ','), ' ,', ',')) || ',', ',' || ? || ',') > 0`
+
+// GetRankedPosts lists posts in the given ranking.Sort order; period limits SortTop to recent posts.
+// A category, which is empty for every post, keeps only the posts in it. Globally pinned posts
+// always come first, followed by the posts pinned to the category.
+func (pc *PostController) GetRankedPosts(sort, period, category string) ([]models.Post, error) {
+	posts, _, err := pc.ListPosts(PostQuery{Sort: sort, Period: period, Category: category})
+	return posts, err
+}
+
+// PostQuery selects and orders the posts ListPosts returns
+type PostQuery struct {
+	Sort   string
+	Period string
+	// Category keeps only the posts filed under it and Author those by that username
+	Category string
+	Author   string
+	// ViewerID leaves out posts by users the viewer blocked or muted
+	ViewerID int
+	// Unanswered keeps only Q&A posts without an accepted answer
+	Unanswered bool
+	// Limit of 0 returns every post after Offset
+	Offset int
+	Limit  int
+}
+
+// ListPosts returns the posts matching q in the order of GetRankedPosts, and whether more follow
+// the page. Filtering and paging run in the database
+func (pc *PostController) ListPosts(q PostQuery) ([]models.Post, bool, error) {
+	sort := ranking.ParseSort(q.Sort)
+	since := time.Time{}
+	if sort == ranking.SortTop {
+		since = ranking.Since(ranking.ParsePeriod(q.Period), time.Now())
+	}
+	category := strings.ToLower(strings.TrimSpace(q.Category))
+
+	where := []string{`deleted_at IS NULL`, `(? OR timestamp >= ?)`, `(? = '' OR ` + inCategory + `)`}
+	args := []interface{}{since.IsZero(), since, category, category}
+	if q.Author != "" {
+		where = append(where, `author = ?`)
+		args = append(args, q.Author)
+	}
+	if q.ViewerID != 0 {
+		where = append(where, `user_id NOT IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = ?)`)
+		args = append(args, q.ViewerID)
+	}
+	if q.Unanswered {
+		var questions []string
+		for _, qa := range QACategories() {
+			questions = append(questions, inCategory)
+			args = append(args, qa)
+		}
+		if len(questions) == 0 {
+			return []models.Post{}, false, nil
+		}
+		where = append(where, `(`+strings.Join(questions, ` OR `)+`) AND `+acceptedAnswer+` = 0`)
+	}
+	args = append(args, category, category)
+	limit := -1
+	if q.Limit > 0 {
+		limit = q.Limit + 1
+	}
+	args = append(args, limit, q.Offset)
+
 	rows, err := pc.DB.Query(`
 		SELECT id, title, user_id, author, category, likes, dislikes, 
-			   user_vote, content, timestamp, image_url 
+			   user_vote, content, timestamp, image_url, comment_count,
+			   pinned, pinned_category, locked, archived, `+acceptedAnswer+`, `+authorReputation+`
 		FROM posts 
-		ORDER BY timestamp DESC
-	`)
+		WHERE `+strings.Join(where, ` AND `)+`
+		ORDER BY (pinned = 1 AND pinned_category = '') DESC,
+			   CASE WHEN pinned = 1 AND pinned_category = '' THEN pinned_at END DESC,
+			   (pinned = 1 AND pinned_category = ?) DESC,
+			   CASE WHEN pinned = 1 AND pinned_category = ? THEN pinned_at END DESC,
+			   `+ranking.OrderBy(sort)+`
+		LIMIT ? OFFSET ?
+	`, args...)
 	if err != nil {
-		logger.Error("Database query failed in GetAllPosts: %v", err)
-		return nil, fmt.Errorf("failed to fetch posts: %w", err)
+		logger.Error("Database query failed in ListPosts: %v", err)
+		return nil, false, fmt.Errorf("failed to fetch posts: %w", err)
 	}
 	defer rows.Close()
 
@@ -55,114 +158,112 @@ func (pc *PostController) GetAllPosts() ([]models.Post, error) {
 		err := rows.Scan(
 			&post.ID, &post.Title, &post.UserID, &post.Author,
 			&post.Category, &post.Likes, &post.Dislikes,
-			&post.UserVote, &post.Content, &post.Timestamp, &post.ImageUrl,
+			&post.UserVote, &post.Content, &post.Timestamp, &post.ImageUrl, &post.CommentCount,
+			&post.IsPinned, &post.PinnedCategory, &post.IsLocked, &post.IsArchived,
+			&post.AcceptedCommentID, &post.AuthorReputation,
 		)
 		if err != nil {
-			logger.Error("Row scan failed in GetAllPosts: %v", err)
-			return nil, fmt.Errorf("failed to scan post: %w", err)
+			logger.Error("Row scan failed in ListPosts: %v", err)
+			return nil, false, fmt.Errorf("failed to scan post: %w", err)
 		}
+		markQuestion(&post)
 		posts = append(posts, post)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to fetch posts: %w", err)
+	}
 
-	return posts, nil
+	hasMore := q.Limit > 0 && len(posts) > q.Limit
+	if hasMore {
+		posts = posts[:q.Limit]
+	}
+	return posts, hasMore, nil
 }
 
 func (pc *PostController) GetPostByID(postID string) (models.Post, error) {
 	var post models.Post
 	err := pc.DB.QueryRow(`
         SELECT id, title, user_id, author, category, likes, dislikes, 
-               user_vote, content, timestamp, image_url 
+               user_vote, content, timestamp, image_url, comment_count,
+               pinned, pinned_category, locked, archived, `+acceptedAnswer+`, `+authorReputation+`
         FROM posts 
-        WHERE id = ?
+        WHERE id = ? AND deleted_at IS NULL
     `, postID).Scan(
 		&post.ID, &post.Title, &post.UserID, &post.Author,
 		&post.Category, &post.Likes, &post.Dislikes,
-		&post.UserVote, &post.Content, &post.Timestamp, &post.ImageUrl,
+		&post.UserVote, &post.Content, &post.Timestamp, &post.ImageUrl, &post.CommentCount,
+		&post.IsPinned, &post.PinnedCategory, &post.IsLocked, &post.IsArchived,
+		&post.AcceptedCommentID, &post.AuthorReputation,
 	)
 	if err != nil {
 		return post, fmt.Errorf("failed to fetch post: %w", err)
 	}
+	markQuestion(&post)
 	return post, nil
 }
 
 func (pc *PostController) UpdatePost(post models.Post) error {
+	tx, err := pc.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Fetch the current image so a replaced one can be released
+	var oldImage sql.NullString
+	err = tx.QueryRow(`SELECT image_url FROM posts WHERE id = ? AND user_id = ? AND deleted_at IS NULL`, post.ID, post.UserID).Scan(&oldImage)
+	if err == sql.ErrNoRows {
+		return errors.New("no post found with the given ID or user ID")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch post: %w", err)
+	}
+
 	// Prepare the SQL statement for updating the post
 	query := `
 	UPDATE posts
-	SET Title = ?, Author = ?, UserID = ?, Category = ?, Likes = ?, Dislikes = ?, UserVote = ?, Content = ?, ImageUrl = ?, Timestamp = ?
-	WHERE ID = ?;
+	SET title = ?, author = ?, category = ?, content = ?, image_url = ?
+	WHERE id = ? AND user_id = ?;
 	`
 
 	// Execute the SQL statement with the post data
-	_, err := pc.DB.Exec(query,
+	_, err = tx.Exec(query,
 		post.Title,
 		post.Author,
-		post.UserID,
 		post.Category,
-		post.Likes,
-		post.Dislikes,
-		post.UserVote,
 		post.Content,
 		post.ImageUrl,
-		post.Timestamp,
 		post.ID,
+		post.UserID,
 	)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if oldImage.String != post.ImageUrl.String {
+		if err := releaseUpload(tx, oldImage.String); err != nil {
+			return err
+		}
+		if err := retainUpload(tx, post.ImageUrl.String); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
-// DeletePost deletes a post from the database by its ID, along with its comments and associated images
+// DeletePost moves a post to its author's trash. The post, its comments and its image
+// are kept until the trash retention period ends and PurgeExpiredTrash removes them.
 func (pc *PostController) DeletePost(postID, userID int) error {
 	// Ensure the database connection is not nil
 	if pc.DB == nil {
 		return errors.New("database connection is nil")
 	}
 
-	// Begin a transaction to ensure atomicity
-	tx, err := pc.DB.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback() // Rollback in case of error
-
-	// Step 1: Delete all comments associated with the post
-	_, err = tx.Exec(`
-		DELETE FROM comments 
-		WHERE post_id = ?;
-	`, postID)
-	if err != nil {
-		return fmt.Errorf("failed to delete comments: %w", err)
-	}
-
-	// Step 2: Fetch image paths associated with the post before deleting the post
-	var imagePaths []string
-	rows, err := tx.Query(`
-		SELECT image_url FROM posts 
-		WHERE id = ? AND user_id = ?;
-	`, postID, userID)
-	if err != nil {
-		return fmt.Errorf("failed to fetch image paths: %w", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var imagePath sql.NullString // Use sql.NullString to handle NULL values
-		if err := rows.Scan(&imagePath); err != nil {
-			return fmt.Errorf("failed to scan image path: %w", err)
-		}
-		if imagePath.Valid && imagePath.String != "" { // Only append non-empty paths
-			imagePaths = append(imagePaths, imagePath.String)
-		}
-	}
-
-	// Step 3: Delete the post
-	result, err := tx.Exec(`
-		DELETE FROM posts 
-		WHERE id = ? AND user_id = ?;
-	`, postID, userID)
+	result, err := pc.DB.Exec(`
+		UPDATE posts SET deleted_at = ?, deleted_by = ?
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL;
+	`, time.Now(), userID, postID, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete post: %w", err)
 	}
@@ -176,17 +277,6 @@ func (pc *PostController) DeletePost(postID, userID int) error {
 		return errors.New("no post found with the given ID or user ID")
 	}
 
-	// Step 4: Delete the image files from the upload folder
-	err = removeImages(imagePaths)
-	if err != nil {
-		return fmt.Errorf("failed to delete image files: %w", err)
-	}
-
-	// Commit the transaction
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	return nil
 }
 
@@ -209,3 +299,39 @@ func (pc *PostController) IsPostAuthor(postID, userID int) (bool, error) {
 	// Compare the post's author ID with the provided userID
 	return authorID == userID, nil
 }
+
+// GetCategories lists the categories posts are filed under with their number of posts, the most
+// used first
+func (pc *PostController) GetCategories() ([]models.Category, error) {
+	rows, err := pc.DB.Query(`SELECT category FROM posts WHERE deleted_at IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch categories: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var category string
+		if err := rows.Scan(&category); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		for _, c := range PostCategories(category) {
+			counts[c]++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch categories: %w", err)
+	}
+
+	categories := make([]models.Category, 0, len(counts))
+	for name, count := range counts {
+		categories = append(categories, models.Category{Name: name, PostCount: count})
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].PostCount != categories[j].PostCount {
+			return categories[i].PostCount > categories[j].PostCount
+		}
+		return categories[i].Name < categories[j].Name
+	})
+	return categories, nil
+}
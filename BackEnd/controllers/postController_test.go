@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+	"github.com/Raymond9734/forum.git/BackEnd/ranking"
+)
+
+func TestPostController_GetRankedPosts(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	for _, u := range []string{"alice", "bob", "carol"} {
+		if err := InsertTestUser(db, u+"@example.com", u, "password123"); err != nil {
+			t.Fatalf("Failed to insert test user: %v", err)
+		}
+	}
+
+	pc := NewPostController(db)
+	insert := func(title string, age time.Duration) int {
+		id, err := pc.InsertPost(models.Post{
+			Title: title, Author: "alice", UserID: 1, Category: "music", Content: title, Timestamp: time.Now().Add(-age),
+		})
+		if err != nil {
+			t.Fatalf("InsertPost() error = %v", err)
+		}
+		return id
+	}
+	oldPopular := insert("old popular", 10*24*time.Hour)
+	divisive := insert("divisive", 2*time.Hour)
+	fresh := insert("fresh", time.Minute)
+
+	// Scores are maintained as votes and comments come in
+	lc := NewLikesController(db)
+	votes := []struct {
+		postID, userID int
+		vote           string
+	}{
+		{oldPopular, 1, "like"}, {oldPopular, 2, "like"}, {oldPopular, 3, "like"},
+		{divisive, 1, "like"}, {divisive, 2, "dislike"},
+	}
+	for _, v := range votes {
+		if _, err := lc.HandleVote(v.postID, v.userID, v.vote); err != nil {
+			t.Fatalf("HandleVote() error = %v", err)
+		}
+	}
+	if _, err := NewCommentController(db).InsertComment(models.Comment{
+		PostID: divisive, UserID: 3, Author: "carol", Content: "hmm", Timestamp: time.Now(),
+	}); err != nil {
+		t.Fatalf("InsertComment() error = %v", err)
+	}
+
+	tests := []struct {
+		sort   string
+		period string
+		want   []int
+	}{
+		{ranking.SortNew, "", []int{fresh, divisive, oldPopular}},
+		{ranking.SortHot, "", []int{fresh, divisive, oldPopular}},
+		{ranking.SortTop, ranking.PeriodAll, []int{oldPopular, divisive, fresh}},
+		{ranking.SortTop, ranking.PeriodWeek, []int{divisive, fresh}},
+		{ranking.SortControversial, "", []int{divisive, fresh, oldPopular}},
+		{ranking.SortComments, "", []int{divisive, fresh, oldPopular}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort+"/"+tt.period, func(t *testing.T) {
+			posts, err := pc.GetRankedPosts(tt.sort, tt.period, "")
+			if err != nil {
+				t.Fatalf("GetRankedPosts() error = %v", err)
+			}
+			var got []int
+			for _, p := range posts {
+				got = append(got, p.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("GetRankedPosts() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("GetRankedPosts() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+	// Filters and pages are applied in the query, with the cached comment count
+	if _, err := pc.InsertPost(models.Post{
+		Title: "by bob", Author: "bob", UserID: 2, Category: "music", Content: "bob", Timestamp: time.Now(),
+	}); err != nil {
+		t.Fatalf("InsertPost() error = %v", err)
+	}
+	if err := MuteUser(db, 3, 2); err != nil {
+		t.Fatalf("MuteUser() error = %v", err)
+	}
+	page, hasMore, err := pc.ListPosts(PostQuery{Sort: ranking.SortNew, Author: "alice", ViewerID: 3, Offset: 1, Limit: 1})
+	if err != nil || len(page) != 1 || page[0].ID != divisive || page[0].CommentCount != 1 || !hasMore {
+		t.Errorf("ListPosts() = %+v, %v, %v, want divisive with more to follow", page, hasMore, err)
+	}
+	page, hasMore, err = pc.ListPosts(PostQuery{Sort: ranking.SortNew, ViewerID: 3, Offset: 2, Limit: 2})
+	if err != nil || len(page) != 1 || page[0].ID != oldPopular || hasMore {
+		t.Errorf("ListPosts() last page = %+v, %v, %v, want only oldPopular", page, hasMore, err)
+	}
+}
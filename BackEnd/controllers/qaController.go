@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Raymond9734/forum.git/BackEnd/config"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+var (
+	ErrNotQAPost    = errors.New("answers can only be accepted on posts in a Q&A category")
+	ErrNotAnswer    = errors.New("the comment is not an answer on this post")
+	ErrCannotAccept = errors.New("only the post author or a moderator can accept an answer")
+)
+
+// FilterUnanswered lists only Q&A posts without an accepted answer on the homepage
+const FilterUnanswered = "unanswered"
+
+// DefaultQACategories are the categories run as Q&A when FORUM_QA_CATEGORIES is not set
+var DefaultQACategories = []string{"programming", "technology"}
+
+// acceptedAnswer selects the accepted comment of posts, or 0 when there is none or it was deleted
+const acceptedAnswer = `COALESCE((SELECT a.id FROM comments a
+	WHERE a.id = posts.accepted_comment_id AND a.deleted_at IS NULL), 0)`
+
+// QACategories returns the categories configured through FORUM_QA_CATEGORIES
+func QACategories() []string {
+	categories := config.List("FORUM_QA_CATEGORIES")
+	if len(categories) == 0 {
+		return DefaultQACategories
+	}
+	for i := range categories {
+		categories[i] = strings.ToLower(categories[i])
+	}
+	return categories
+}
+
+// IsQACategory reports whether any of a post's comma separated categories is a Q&A category
+func IsQACategory(category string) bool {
+	qa := QACategories()
+	for _, c := range PostCategories(category) {
+		for _, q := range qa {
+			if c == q {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// markQuestion flags posts in a Q&A category; an answer accepted while the post was in one is
+// ignored after it moves out of them
+func markQuestion(post *models.Post) {
+	post.IsQuestion = IsQACategory(post.Category)
+	if !post.IsQuestion {
+		post.AcceptedCommentID = 0
+	}
+}
+
+// AcceptAnswer marks a comment on a Q&A post as its accepted answer, replacing any previous one;
+// a commentID of 0 clears it. Only the post author or a moderator may do so
+func (pc *PostController) AcceptAnswer(postID, commentID, userID int) error {
+	var authorID int
+	var category string
+	err := pc.DB.QueryRow(`SELECT user_id, category FROM posts WHERE id = ? AND deleted_at IS NULL`, postID).Scan(&authorID, &category)
+	if err == sql.ErrNoRows {
+		return ErrPostNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch post: %w", err)
+	}
+	if !IsQACategory(category) {
+		return ErrNotQAPost
+	}
+	if userID != authorID && !IsModerator(pc.DB, userID) {
+		return ErrCannotAccept
+	}
+
+	var accepted sql.NullInt64
+	if commentID != 0 {
+		var exists bool
+		err = pc.DB.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM comments WHERE id = ? AND post_id = ? AND deleted_at IS NULL)
+		`, commentID, postID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to fetch comment: %w", err)
+		}
+		if !exists {
+			return ErrNotAnswer
+		}
+		accepted = sql.NullInt64{Int64: int64(commentID), Valid: true}
+	}
+
+	_, err = pc.DB.Exec(`UPDATE posts SET accepted_comment_id = ? WHERE id = ?`, accepted, postID)
+	if err != nil {
+		return fmt.Errorf("failed to accept answer: %w", err)
+	}
+	return nil
+}
+
+// AcceptedAnswerID returns the accepted answer of a Q&A post, or 0 when it has none
+func AcceptedAnswerID(db *sql.DB, postID int) (int, error) {
+	var post models.Post
+	err := db.QueryRow(`SELECT category, `+acceptedAnswer+` FROM posts WHERE id = ?`, postID).Scan(&post.Category, &post.AcceptedCommentID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch accepted answer: %w", err)
+	}
+	markQuestion(&post)
+	return post.AcceptedCommentID, nil
+}
+
+// GetAcceptedAnswer returns the accepted answer of a post without its replies, for pinning above
+// the other comments
+func (cc *CommentController) GetAcceptedAnswer(postID, commentID int) (*models.Comment, error) {
+	comments, err := cc.queryComments(`
+		SELECT `+commentColumns+`
+		FROM comments c
+		LEFT JOIN comments q ON q.id = c.quote_id
+		WHERE c.post_id = ? AND c.id = ? AND c.deleted_at IS NULL
+	`, postID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(comments) == 0 {
+		return nil, ErrCommentNotFound
+	}
+	comments[0].IsAccepted = true
+	return &comments[0], nil
+}
+
+// MarkAcceptedAnswer sets IsAccepted on the accepted answer wherever it appears in the tree
+func MarkAcceptedAnswer(comments []models.Comment, commentID int) {
+	if commentID == 0 {
+		return
+	}
+	for i := range comments {
+		comments[i].IsAccepted = comments[i].ID == commentID
+		MarkAcceptedAnswer(comments[i].Replies, commentID)
+	}
+}
+
+// UnansweredPosts keeps the Q&A posts that have no accepted answer
+func UnansweredPosts(posts []models.Post) []models.Post {
+	kept := posts[:0]
+	for _, post := range posts {
+		if post.IsQuestion && post.AcceptedCommentID == 0 {
+			kept = append(kept, post)
+		}
+	}
+	return kept
+}
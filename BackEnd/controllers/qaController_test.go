@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+func TestAcceptAnswer(t *testing.T) {
+	t.Setenv("FORUM_QA_CATEGORIES", "Programming")
+
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	for _, name := range []string{"asker", "helper", "moderator"} {
+		if err := InsertTestUser(db, name+"@example.com", name, "password123"); err != nil {
+			t.Fatalf("Failed to insert test user: %v", err)
+		}
+	}
+	if _, err := db.Exec(`UPDATE users SET role = ? WHERE id = 3`, models.RoleModerator); err != nil {
+		t.Fatalf("Failed to promote moderator: %v", err)
+	}
+
+	pc := NewPostController(db)
+	insertPost := func(category string) int {
+		id, err := pc.InsertPost(models.Post{
+			Title: "How do I " + category, Author: "asker", UserID: 1, Category: category, Content: "question",
+			Timestamp: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("InsertPost() error = %v", err)
+		}
+		return id
+	}
+	question := insertPost("music, programming")
+	discussion := insertPost("music")
+
+	insertComment := func(postID int) int {
+		id, err := InsertTestComment(db, models.Comment{
+			PostID: postID, UserID: 2, Author: "helper", Content: "answer", Timestamp: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("InsertTestComment() error = %v", err)
+		}
+		return int(id)
+	}
+	answer := insertComment(question)
+	other := insertComment(question)
+	offTopic := insertComment(discussion)
+
+	tests := []struct {
+		name      string
+		postID    int
+		commentID int
+		userID    int
+		want      error
+	}{
+		{"author accepts", question, answer, 1, nil},
+		{"commenter cannot accept", question, other, 2, ErrCannotAccept},
+		{"moderator replaces", question, other, 3, nil},
+		{"comment of another post", question, offTopic, 1, ErrNotAnswer},
+		{"post outside Q&A categories", discussion, offTopic, 1, ErrNotQAPost},
+		{"missing post", 999, answer, 1, ErrPostNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := pc.AcceptAnswer(tt.postID, tt.commentID, tt.userID); !errors.Is(err, tt.want) {
+				t.Errorf("AcceptAnswer() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	post, err := pc.GetPostByID(strconv.Itoa(question))
+	if err != nil {
+		t.Fatalf("GetPostByID() error = %v", err)
+	}
+	if !post.IsQuestion || post.AcceptedCommentID != other {
+		t.Errorf("GetPostByID() IsQuestion = %v, AcceptedCommentID = %d, want true, %d", post.IsQuestion, post.AcceptedCommentID, other)
+	}
+
+	posts, err := pc.GetAllPosts()
+	if err != nil {
+		t.Fatalf("GetAllPosts() error = %v", err)
+	}
+	if unanswered := UnansweredPosts(posts); len(unanswered) != 0 {
+		t.Errorf("UnansweredPosts() = %+v, want none", unanswered)
+	}
+
+	// Deleting the accepted answer leaves the question unsolved again
+	if _, err := db.Exec(`UPDATE comments SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?`, other); err != nil {
+		t.Fatalf("Failed to delete comment: %v", err)
+	}
+	posts, err = pc.GetAllPosts()
+	if err != nil {
+		t.Fatalf("GetAllPosts() error = %v", err)
+	}
+	if unanswered := UnansweredPosts(posts); len(unanswered) != 1 || unanswered[0].ID != question {
+		t.Errorf("UnansweredPosts() = %+v, want post %d", unanswered, question)
+	}
+
+	if err := pc.AcceptAnswer(question, 0, 1); err != nil {
+		t.Fatalf("AcceptAnswer() clearing error = %v", err)
+	}
+	if accepted, err := AcceptedAnswerID(db, question); err != nil || accepted != 0 {
+		t.Errorf("AcceptedAnswerID() = %d, %v, want 0", accepted, err)
+	}
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Raymond9734/forum.git/BackEnd/config"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+// MaxUploadRequestSize bounds the body of a single upload request
+const MaxUploadRequestSize = 10 << 20 // 10 MB
+
+// QuotaExceededError is returned when an upload would push a user past their quota
+type QuotaExceededError struct {
+	Used  int64
+	Quota int64
+	Size  int64
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("upload quota exceeded: %s used of %s, upload is %s",
+		formatBytes(e.Used), formatBytes(e.Quota), formatBytes(e.Size))
+}
+
+// UploadQuotaForRole returns the storage quota in bytes for a role; 0 means unlimited
+func UploadQuotaForRole(role string) int64 {
+	switch role {
+	case models.RoleAdmin:
+		return config.Bytes("FORUM_QUOTA_ADMIN", 0)
+	case models.RoleModerator:
+		return config.Bytes("FORUM_QUOTA_MODERATOR", 1<<30)
+	default:
+		return config.Bytes("FORUM_QUOTA_USER", 100<<20)
+	}
+}
+
+// GetStorageUsed returns the bytes of stored files first uploaded by the user
+func GetStorageUsed(db *sql.DB, userID int) (int64, error) {
+	var used int64
+	err := db.QueryRow(`SELECT COALESCE(SUM(size), 0) FROM files WHERE uploaded_by = ?`, userID).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute storage used by user %d: %w", userID, err)
+	}
+	return used, nil
+}
+
+// checkUploadQuota returns a *QuotaExceededError if storing size more bytes exceeds the user's quota
+func checkUploadQuota(db *sql.DB, userID int, size int64) error {
+	role, err := GetUserRole(db, userID)
+	if err != nil {
+		return err
+	}
+	quota := UploadQuotaForRole(role)
+	if quota == 0 {
+		return nil
+	}
+
+	used, err := GetStorageUsed(db, userID)
+	if err != nil {
+		return err
+	}
+	if used+size > quota {
+		return &QuotaExceededError{Used: used, Quota: quota, Size: size}
+	}
+	return nil
+}
+
+// GetTopStorageConsumers returns the users charged with the most stored bytes
+func GetTopStorageConsumers(db *sql.DB, limit int) ([]models.StorageUsage, error) {
+	rows, err := db.Query(`
+		SELECT u.id, u.username, u.role, COUNT(f.hash), COALESCE(SUM(f.size), 0) AS bytes_used
+		FROM users u
+		INNER JOIN files f ON f.uploaded_by = u.id
+		GROUP BY u.id
+		ORDER BY bytes_used DESC
+		LIMIT ?;
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query storage usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make([]models.StorageUsage, 0)
+	for rows.Next() {
+		var u models.StorageUsage
+		if err := rows.Scan(&u.UserID, &u.Username, &u.Role, &u.FileCount, &u.BytesUsed); err != nil {
+			return nil, fmt.Errorf("failed to scan storage usage: %w", err)
+		}
+		u.Quota = UploadQuotaForRole(u.Role)
+		usage = append(usage, u)
+	}
+	return usage, nil
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
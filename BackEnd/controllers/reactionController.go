@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/config"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+var (
+	ErrInvalidReaction        = errors.New("reaction is not one of the configured reactions")
+	ErrReactionTargetNotFound = errors.New("the post or comment reacted to does not exist")
+)
+
+// reactionTables maps each reaction target type to the table holding the targets
+var reactionTables = map[string]string{
+	models.ReactionPost:    "posts",
+	models.ReactionComment: "comments",
+}
+
+type ReactionController struct {
+	DB *sql.DB
+}
+
+func NewReactionController(db *sql.DB) *ReactionController {
+	return &ReactionController{DB: db}
+}
+
+// Reactions returns the reactions configured through FORUM_REACTIONS, in the order they are offered
+func Reactions() []string {
+	reactions := config.List("FORUM_REACTIONS")
+	if len(reactions) == 0 {
+		return models.DefaultReactions
+	}
+	return reactions
+}
+
+// IsReaction reports whether reaction is one of the configured reactions
+func IsReaction(reaction string) bool {
+	for _, r := range Reactions() {
+		if r == reaction {
+			return true
+		}
+	}
+	return false
+}
+
+// React toggles a user's reaction on a post or comment: reacting the same way again removes the
+// reaction and reacting another way replaces it, since a user has one reaction per target.
+// It returns the target's reaction counts afterwards
+func (rc *ReactionController) React(userID int, targetType string, targetID int, reaction string) ([]models.ReactionCount, error) {
+	table, ok := reactionTables[targetType]
+	if !ok {
+		return nil, fmt.Errorf("unknown reaction target %q", targetType)
+	}
+	if !IsReaction(reaction) {
+		return nil, ErrInvalidReaction
+	}
+
+	result, err := rc.DB.Exec(`
+		DELETE FROM reactions WHERE user_id = ? AND target_type = ? AND target_id = ? AND reaction = ?
+	`, userID, targetType, targetID, reaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove reaction: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		var exists bool
+		err = rc.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM `+table+` WHERE id = ? AND deleted_at IS NULL)`, targetID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check reaction target: %w", err)
+		}
+		if !exists {
+			return nil, ErrReactionTargetNotFound
+		}
+
+		_, err = rc.DB.Exec(`
+			INSERT INTO reactions (user_id, target_type, target_id, reaction, created_at)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (user_id, target_type, target_id)
+			DO UPDATE SET reaction = excluded.reaction, created_at = excluded.created_at
+		`, userID, targetType, targetID, reaction, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert reaction: %w", err)
+		}
+	}
+
+	counts, err := rc.ReactionCounts(targetType, []int{targetID}, userID)
+	if err != nil {
+		return nil, err
+	}
+	return counts[targetID], nil
+}
+
+// ReactionCounts returns the counts of every configured reaction on each of the targets, marking
+// the ones the viewer chose. Reactions that are no longer configured are not counted
+func (rc *ReactionController) ReactionCounts(targetType string, targetIDs []int, viewerID int) (map[int][]models.ReactionCount, error) {
+	reactions := Reactions()
+	counts := make(map[int][]models.ReactionCount, len(targetIDs))
+	if len(targetIDs) == 0 {
+		return counts, nil
+	}
+
+	args := make([]interface{}, 0, len(targetIDs)+2)
+	args = append(args, viewerID, targetType)
+	for _, id := range targetIDs {
+		args = append(args, id)
+		list := make([]models.ReactionCount, len(reactions))
+		for i, reaction := range reactions {
+			list[i].Reaction = reaction
+		}
+		counts[id] = list
+	}
+
+	rows, err := rc.DB.Query(`
+		SELECT target_id, reaction, COUNT(*), MAX(user_id = ?)
+		FROM reactions
+		WHERE target_type = ? AND target_id IN (?`+strings.Repeat(", ?", len(targetIDs)-1)+`)
+		GROUP BY target_id, reaction
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reaction counts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, count int
+		var reaction string
+		var reacted bool
+		if err := rows.Scan(&id, &reaction, &count, &reacted); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction count: %w", err)
+		}
+		for i := range counts[id] {
+			if counts[id][i].Reaction == reaction {
+				counts[id][i].Count = count
+				counts[id][i].Reacted = reacted
+			}
+		}
+	}
+	return counts, rows.Err()
+}
+
+// AddCommentReactions sets the reaction counts throughout a comment tree; deleted and hidden
+// placeholders get none
+func (rc *ReactionController) AddCommentReactions(comments []models.Comment, viewerID int) error {
+	var ids []int
+	var collect func([]models.Comment)
+	collect = func(comments []models.Comment) {
+		for _, comment := range comments {
+			if !comment.IsDeleted && !comment.IsHidden {
+				ids = append(ids, comment.ID)
+			}
+			collect(comment.Replies)
+		}
+	}
+	collect(comments)
+
+	counts, err := rc.ReactionCounts(models.ReactionComment, ids, viewerID)
+	if err != nil {
+		return err
+	}
+	var mark func([]models.Comment)
+	mark = func(comments []models.Comment) {
+		for i := range comments {
+			comments[i].Reactions = counts[comments[i].ID]
+			mark(comments[i].Replies)
+		}
+	}
+	mark(comments)
+	return nil
+}
+
+// GetReactors lists the users who reacted to a post or comment, newest first, optionally only
+// those who chose the given reaction. A deleted post or comment has no reactors
+func (rc *ReactionController) GetReactors(targetType string, targetID int, reaction string) ([]models.Reactor, error) {
+	table, ok := reactionTables[targetType]
+	if !ok {
+		return nil, fmt.Errorf("unknown reaction target %q", targetType)
+	}
+	rows, err := rc.DB.Query(`
+		SELECT r.user_id, u.username, r.reaction, r.created_at
+		FROM reactions r
+		JOIN users u ON u.id = r.user_id
+		JOIN `+table+` t ON t.id = r.target_id AND t.deleted_at IS NULL
+		WHERE r.target_type = ? AND r.target_id = ? AND (? = '' OR r.reaction = ?)
+		ORDER BY r.created_at DESC, r.id DESC
+		LIMIT ?
+	`, targetType, targetID, reaction, reaction, models.ReactorsPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reactors: %w", err)
+	}
+	defer rows.Close()
+
+	reactors := []models.Reactor{}
+	for rows.Next() {
+		var reactor models.Reactor
+		if err := rows.Scan(&reactor.UserID, &reactor.Username, &reactor.Reaction, &reactor.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reactor: %w", err)
+		}
+		reactors = append(reactors, reactor)
+	}
+	return reactors, rows.Err()
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+func TestReact(t *testing.T) {
+	t.Setenv("FORUM_REACTIONS", "👍, 🎉, 😮")
+
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	for _, name := range []string{"author", "fan"} {
+		if err := InsertTestUser(db, name+"@example.com", name, "password123"); err != nil {
+			t.Fatalf("Failed to insert test user: %v", err)
+		}
+	}
+	postID, err := NewPostController(db).InsertPost(models.Post{
+		Title: "post", Author: "author", UserID: 1, Category: "music", Content: "post", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("InsertPost() error = %v", err)
+	}
+	commentID, err := InsertTestComment(db, models.Comment{
+		PostID: postID, UserID: 1, Author: "author", Content: "comment", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("InsertTestComment() error = %v", err)
+	}
+
+	rc := NewReactionController(db)
+	tests := []struct {
+		name       string
+		userID     int
+		targetType string
+		targetID   int
+		reaction   string
+		wantErr    error
+		// want is the count of each configured reaction afterwards and reacted the user's reaction
+		want    [3]int
+		reacted string
+	}{
+		{"react", 1, models.ReactionPost, postID, "🎉", nil, [3]int{0, 1, 0}, "🎉"},
+		{"another user", 2, models.ReactionPost, postID, "🎉", nil, [3]int{0, 2, 0}, "🎉"},
+		{"another reaction replaces it", 2, models.ReactionPost, postID, "😮", nil, [3]int{0, 1, 1}, "😮"},
+		{"same reaction removes it", 2, models.ReactionPost, postID, "😮", nil, [3]int{0, 1, 0}, ""},
+		{"unconfigured reaction", 2, models.ReactionPost, postID, "❤️", ErrInvalidReaction, [3]int{}, ""},
+		{"missing post", 2, models.ReactionPost, 999, "👍", ErrReactionTargetNotFound, [3]int{}, ""},
+		{"react to a comment", 2, models.ReactionComment, int(commentID), "👍", nil, [3]int{1, 0, 0}, "👍"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts, err := rc.React(tt.userID, tt.targetType, tt.targetID, tt.reaction)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("React() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(counts) != len(tt.want) {
+				t.Fatalf("React() = %+v, want %d reactions", counts, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if counts[i].Count != want {
+					t.Errorf("React() %s count = %d, want %d", counts[i].Reaction, counts[i].Count, want)
+				}
+				if reacted := counts[i].Reaction == tt.reacted; counts[i].Reacted != reacted {
+					t.Errorf("React() %s reacted = %v, want %v", counts[i].Reaction, counts[i].Reacted, reacted)
+				}
+			}
+		})
+	}
+
+	comments := []models.Comment{{ID: int(commentID)}, {ID: 999, IsDeleted: true}}
+	if err := rc.AddCommentReactions(comments, 2); err != nil {
+		t.Fatalf("AddCommentReactions() error = %v", err)
+	}
+	if got := comments[0].Reactions; len(got) != 3 || got[0].Count != 1 || !got[0].Reacted {
+		t.Errorf("AddCommentReactions() = %+v, want one 👍 by the viewer", got)
+	}
+	if comments[1].Reactions != nil {
+		t.Errorf("AddCommentReactions() on a deleted comment = %+v, want none", comments[1].Reactions)
+	}
+
+	reactors, err := rc.GetReactors(models.ReactionPost, postID, "")
+	if err != nil {
+		t.Fatalf("GetReactors() error = %v", err)
+	}
+	if len(reactors) != 1 || reactors[0].Username != "author" || reactors[0].Reaction != "🎉" {
+		t.Errorf("GetReactors() = %+v, want author with 🎉", reactors)
+	}
+	if reactors, err := rc.GetReactors(models.ReactionPost, postID, "👍"); err != nil || len(reactors) != 0 {
+		t.Errorf("GetReactors() filtered = %+v, %v, want none", reactors, err)
+	}
+}
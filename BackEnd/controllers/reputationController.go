@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Raymond9734/forum.git/BackEnd/config"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+// ReputationRequiredError is returned when a user's reputation is below the threshold of a privilege
+type ReputationRequiredError struct {
+	Privilege  models.Privilege
+	Reputation int
+	Required   int
+}
+
+func (e *ReputationRequiredError) Error() string {
+	return fmt.Sprintf("you need %d reputation to %s, you have %d", e.Required, e.Privilege.Description, e.Reputation)
+}
+
+// authorReputation selects the reputation of the author of posts
+const authorReputation = `COALESCE((SELECT u.reputation FROM users u WHERE u.id = posts.user_id), 0)`
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// ReputationWeightOf returns the configured reputation a vote of the given kind gives the author
+func ReputationWeightOf(targetType, vote string) int {
+	for _, weight := range models.ReputationWeights {
+		if weight.TargetType == targetType && weight.Vote == vote {
+			return config.Int(weight.Env, weight.Default)
+		}
+	}
+	return 0
+}
+
+// PrivilegeThreshold returns the configured reputation needed for a privilege
+func PrivilegeThreshold(privilege models.Privilege) int {
+	return config.Int(privilege.Env, privilege.Default)
+}
+
+// GetReputation returns a user's reputation
+func GetReputation(db *sql.DB, userID int) (int, error) {
+	var reputation int
+	err := db.QueryRow(`SELECT reputation FROM users WHERE id = ?`, userID).Scan(&reputation)
+	if err == sql.ErrNoRows {
+		return 0, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch reputation: %w", err)
+	}
+	return reputation, nil
+}
+
+// CheckPrivilege returns a *ReputationRequiredError when the user may not use the privilege yet
+func CheckPrivilege(db *sql.DB, userID int, privilege models.Privilege) error {
+	return checkPrivilege(db, userID, privilege)
+}
+
+func checkPrivilege(q rowQuerier, userID int, privilege models.Privilege) error {
+	required := PrivilegeThreshold(privilege)
+	if required <= 0 {
+		return nil
+	}
+
+	var role string
+	var reputation int
+	err := q.QueryRow(`SELECT role, reputation FROM users WHERE id = ?`, userID).Scan(&role, &reputation)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch reputation: %w", err)
+	}
+	if role == models.RoleModerator || role == models.RoleAdmin {
+		return nil
+	}
+	if reputation < required {
+		return &ReputationRequiredError{Privilege: privilege, Reputation: reputation, Required: required}
+	}
+	return nil
+}
+
+// UserPrivileges lists every privilege with its threshold and whether the reputation unlocks it
+func UserPrivileges(reputation int) []models.UnlockedPrivilege {
+	privileges := make([]models.UnlockedPrivilege, 0, len(models.Privileges))
+	for _, privilege := range models.Privileges {
+		required := PrivilegeThreshold(privilege)
+		privileges = append(privileges, models.UnlockedPrivilege{
+			Description: privilege.Description,
+			Required:    required,
+			Unlocked:    reputation >= required,
+		})
+	}
+	return privileges
+}
+
+// recordReputation moves the reputation a voter gave the author of a post or comment to match
+// their current vote, which is empty once removed. The previous vote is reversed by an unvote
+// entry netting out everything the voter gave for the target, so the ledger stays exact even if
+// the weights changed in between. It runs inside the vote transaction
+func recordReputation(tx *sql.Tx, targetType string, targetID, postID, authorID, voterID int, previous, current string) error {
+	if authorID == 0 || authorID == voterID {
+		return nil
+	}
+
+	var net int
+	err := tx.QueryRow(`
+		SELECT COALESCE(SUM(delta), 0) FROM reputation_ledger
+		WHERE voter_id = ? AND target_type = ? AND target_id = ?
+	`, voterID, targetType, targetID).Scan(&net)
+	if err != nil {
+		return fmt.Errorf("failed to fetch reputation given: %w", err)
+	}
+
+	record := func(vote, reason string, delta int) error {
+		_, err := tx.Exec(`
+			INSERT INTO reputation_ledger (user_id, voter_id, target_type, target_id, post_id, vote, reason, delta)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, authorID, voterID, targetType, targetID, postID, vote, reason, delta)
+		if err != nil {
+			return fmt.Errorf("failed to record reputation: %w", err)
+		}
+		return nil
+	}
+
+	change := 0
+	if net != 0 && previous != "" {
+		if err := record(previous, models.ReputationUnvote, -net); err != nil {
+			return err
+		}
+		change -= net
+	}
+	if current != "" {
+		if delta := ReputationWeightOf(targetType, current); delta != 0 {
+			if err := record(current, models.ReputationVote, delta); err != nil {
+				return err
+			}
+			change += delta
+		}
+	}
+	if change == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(`UPDATE users SET reputation = reputation + ? WHERE id = ?`, change, authorID)
+	if err != nil {
+		return fmt.Errorf("failed to update reputation: %w", err)
+	}
+	return nil
+}
+
+// reverseReputation records an unvote entry netting out the reputation each voter gave for every
+// target of the ledger entries matching where, and takes it back from the authors. It runs inside
+// the purge transaction, before the targets and their votes are deleted
+func reverseReputation(tx *sql.Tx, where string, args ...interface{}) error {
+	_, err := tx.Exec(`
+		UPDATE users SET reputation = reputation -
+			(SELECT COALESCE(SUM(delta), 0) FROM reputation_ledger WHERE user_id = users.id AND `+where+`)
+		WHERE id IN (SELECT user_id FROM reputation_ledger WHERE `+where+`)
+	`, append(append([]interface{}{}, args...), args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to reverse reputation: %w", err)
+	}
+
+	// The latest entry of a vote that still counts is the vote itself
+	_, err = tx.Exec(`
+		INSERT INTO reputation_ledger (user_id, voter_id, target_type, target_id, post_id, vote, reason, delta)
+		SELECT l.user_id, l.voter_id, l.target_type, l.target_id, l.post_id,
+			(SELECT r.vote FROM reputation_ledger r
+				WHERE r.voter_id = l.voter_id AND r.target_type = l.target_type AND r.target_id = l.target_id
+				ORDER BY r.id DESC LIMIT 1),
+			?, -SUM(l.delta)
+		FROM reputation_ledger l
+		WHERE `+where+`
+		GROUP BY l.user_id, l.voter_id, l.target_type, l.target_id, l.post_id
+		HAVING SUM(l.delta) != 0
+	`, append([]interface{}{models.ReputationUnvote}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to record reputation reversal: %w", err)
+	}
+	return nil
+}
+
+// GetReputationLedger returns the latest reputation changes of a user, newest first
+func GetReputationLedger(db *sql.DB, userID, limit int) ([]models.ReputationEntry, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, voter_id, target_type, target_id, post_id, vote, reason, delta, created_at
+		FROM reputation_ledger
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reputation ledger: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.ReputationEntry
+	for rows.Next() {
+		var e models.ReputationEntry
+		err := rows.Scan(&e.ID, &e.UserID, &e.VoterID, &e.TargetType, &e.TargetID, &e.PostID,
+			&e.Vote, &e.Reason, &e.Delta, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reputation entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+func TestReputation(t *testing.T) {
+	t.Setenv("FORUM_REP_DOWNVOTE", "15")
+	t.Setenv("FORUM_REP_POST_IMAGES", "10")
+
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	for _, name := range []string{"author", "voter", "moderator"} {
+		if err := InsertTestUser(db, name+"@example.com", name, "password123"); err != nil {
+			t.Fatalf("Failed to insert test user: %v", err)
+		}
+	}
+	if _, err := db.Exec(`UPDATE users SET role = ? WHERE id = 3`, models.RoleModerator); err != nil {
+		t.Fatalf("Failed to promote moderator: %v", err)
+	}
+
+	pc := NewPostController(db)
+	insertPost := func(userID int, author string) int {
+		id, err := pc.InsertPost(models.Post{
+			Title: "post", Author: author, UserID: userID, Category: "music", Content: "post", Timestamp: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("InsertPost() error = %v", err)
+		}
+		return id
+	}
+	authorPost := insertPost(1, "author")
+	voterPost := insertPost(2, "voter")
+	commentID, err := InsertTestComment(db, models.Comment{
+		PostID: authorPost, UserID: 1, Author: "author", Content: "comment", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("InsertTestComment() error = %v", err)
+	}
+
+	vc := NewVoteController(db)
+	steps := []struct {
+		name    string
+		setEnv  [2]string
+		target  string
+		id      int
+		voterID int
+		vote    string
+		wantErr bool
+		// want is the reputation of author, voter and moderator after the step
+		want [3]int
+	}{
+		{"downvote needs reputation", [2]string{}, models.VoteTargetPost, authorPost, 2, models.VoteDislike, true, [3]int{0, 0, 0}},
+		{"like a post", [2]string{}, models.VoteTargetPost, authorPost, 2, models.VoteLike, false, [3]int{10, 0, 0}},
+		{"self votes count for nothing", [2]string{}, models.VoteTargetPost, authorPost, 1, models.VoteLike, false, [3]int{10, 0, 0}},
+		{"removal reverses the recorded weight", [2]string{"FORUM_REP_POST_LIKE", "20"}, models.VoteTargetPost, authorPost, 2, models.VoteLike, false, [3]int{0, 0, 0}},
+		{"like again with the new weight", [2]string{}, models.VoteTargetPost, authorPost, 2, models.VoteLike, false, [3]int{20, 0, 0}},
+		{"like a comment", [2]string{}, models.VoteTargetComment, int(commentID), 2, models.VoteLike, false, [3]int{25, 0, 0}},
+		{"enough reputation to downvote", [2]string{}, models.VoteTargetPost, voterPost, 1, models.VoteDislike, false, [3]int{25, -2, 0}},
+		{"moderators always may downvote", [2]string{}, models.VoteTargetPost, voterPost, 3, models.VoteDislike, false, [3]int{25, -4, 0}},
+		{"switching reverses the dislike", [2]string{}, models.VoteTargetPost, voterPost, 3, models.VoteLike, false, [3]int{25, 18, 0}},
+	}
+	for _, step := range steps {
+		if step.setEnv[0] != "" {
+			t.Setenv(step.setEnv[0], step.setEnv[1])
+		}
+		_, err := vc.Vote(step.target, step.id, step.voterID, step.vote)
+		var repErr *ReputationRequiredError
+		if step.wantErr != errors.As(err, &repErr) || (!step.wantErr && err != nil) {
+			t.Fatalf("%s: Vote() error = %v, want reputation error %v", step.name, err, step.wantErr)
+		}
+		for i, want := range step.want {
+			got, err := GetReputation(db, i+1)
+			if err != nil {
+				t.Fatalf("GetReputation() error = %v", err)
+			}
+			if got != want {
+				t.Errorf("%s: reputation of user %d = %d, want %d", step.name, i+1, got, want)
+			}
+		}
+	}
+
+	// The cached reputation always matches the ledger
+	var mismatched int
+	err = db.QueryRow(`
+		SELECT COUNT(*) FROM users
+		WHERE reputation != (SELECT COALESCE(SUM(delta), 0) FROM reputation_ledger r WHERE r.user_id = users.id)
+	`).Scan(&mismatched)
+	if err != nil {
+		t.Fatalf("Failed to compare ledger: %v", err)
+	}
+	if mismatched != 0 {
+		t.Errorf("%d users have a reputation that differs from their ledger", mismatched)
+	}
+
+	ledger, err := GetReputationLedger(db, 1, 10)
+	if err != nil {
+		t.Fatalf("GetReputationLedger() error = %v", err)
+	}
+	if len(ledger) != 4 || ledger[2].Reason != models.ReputationUnvote || ledger[2].Delta != -10 {
+		t.Errorf("GetReputationLedger() = %+v, want 4 entries, newest first, with the reversal of the first like third", ledger)
+	}
+
+	if err := CheckPrivilege(db, 2, models.PrivilegePostImages); err != nil {
+		t.Errorf("CheckPrivilege() with 18 reputation error = %v", err)
+	}
+	if err := CheckPrivilege(db, 3, models.PrivilegePostImages); err != nil {
+		t.Errorf("CheckPrivilege() for a moderator error = %v", err)
+	}
+}
@@ -3,9 +3,10 @@ package controllers
 import (
 	"context"
 	"database/sql"
-	"log"
 	"net/http"
 	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
 )
 
 // Helper function to check if a session is valid
@@ -75,13 +76,13 @@ func CleanupExpiredSessions(ctx context.Context, db *sql.DB) {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Stopping session cleanup task...")
+			logger.Info("Stopping session cleanup task...")
 			return
 		case <-ticker.C:
-			log.Println("Cleaning up expired sessions...")
+			logger.Info("Cleaning up expired sessions...")
 			_, err := db.Exec("DELETE FROM sessions WHERE expires_at < ?", time.Now())
 			if err != nil {
-				log.Printf("Failed to clean up expired sessions: %v\n", err)
+				logger.Error("Failed to clean up expired sessions: %v", err)
 			}
 		}
 	}
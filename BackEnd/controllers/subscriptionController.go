@@ -0,0 +1,336 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/events"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+var (
+	ErrInvalidWatchLevel       = errors.New("watch level must be one of all, replies or muted")
+	ErrInvalidSubscription     = errors.New("subscribe to either a post or a category")
+	ErrSubscriptionNotFound    = errors.New("subscription not found")
+	ErrSubscriptionPostMissing = errors.New("the post to subscribe to does not exist")
+)
+
+type SubscriptionController struct {
+	DB *sql.DB
+}
+
+func NewSubscriptionController(db *sql.DB) *SubscriptionController {
+	return &SubscriptionController{DB: db}
+}
+
+// ValidWatchLevel reports whether level is one of models.WatchLevels
+func ValidWatchLevel(level string) bool {
+	for _, l := range models.WatchLevels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// PostCategories splits the comma separated category list stored on a post
+func PostCategories(category string) []string {
+	var categories []string
+	for _, c := range strings.Split(category, ",") {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			categories = append(categories, c)
+		}
+	}
+	return categories
+}
+
+// Subscribe follows a post, or a category when postID is 0, at the given watch level,
+// replacing the level of an existing subscription
+func (sc *SubscriptionController) Subscribe(userID, postID int, category, level string) error {
+	category = strings.ToLower(strings.TrimSpace(category))
+	if (postID == 0) == (category == "") {
+		return ErrInvalidSubscription
+	}
+	if !ValidWatchLevel(level) {
+		return ErrInvalidWatchLevel
+	}
+	if postID != 0 {
+		var exists bool
+		err := sc.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM posts WHERE id = ? AND deleted_at IS NULL)`, postID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check post: %w", err)
+		}
+		if !exists {
+			return ErrSubscriptionPostMissing
+		}
+	}
+
+	_, err := sc.DB.Exec(`
+		INSERT INTO subscriptions (user_id, post_id, category, level, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, post_id, category) DO UPDATE SET level = excluded.level
+	`, userID, postID, category, level, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save subscription: %w", err)
+	}
+	return nil
+}
+
+// Unsubscribe stops following a post, or a category when postID is 0
+func (sc *SubscriptionController) Unsubscribe(userID, postID int, category string) error {
+	category = strings.ToLower(strings.TrimSpace(category))
+	if (postID == 0) == (category == "") {
+		return ErrInvalidSubscription
+	}
+	result, err := sc.DB.Exec(`
+		DELETE FROM subscriptions WHERE user_id = ? AND post_id = ? AND category = ?
+	`, userID, postID, category)
+	if err != nil {
+		return fmt.Errorf("failed to delete subscription: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// AutoSubscribe follows a post at the given level unless the user already chose a level for it
+func (sc *SubscriptionController) AutoSubscribe(userID, postID int, level string) error {
+	_, err := sc.DB.Exec(`
+		INSERT OR IGNORE INTO subscriptions (user_id, post_id, category, level, created_at)
+		VALUES (?, ?, '', ?, ?)
+	`, userID, postID, level, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to subscribe user %d to post %d: %w", userID, postID, err)
+	}
+	return nil
+}
+
+// GetWatchLevel returns the user's watch level for a post, or "" when they do not follow it
+func (sc *SubscriptionController) GetWatchLevel(userID, postID int) (string, error) {
+	var level string
+	err := sc.DB.QueryRow(`
+		SELECT level FROM subscriptions WHERE user_id = ? AND post_id = ? AND category = ''
+	`, userID, postID).Scan(&level)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch watch level: %w", err)
+	}
+	return level, nil
+}
+
+// GetSubscriptions lists the user's category subscriptions followed by their thread subscriptions
+func (sc *SubscriptionController) GetSubscriptions(userID int) ([]models.Subscription, error) {
+	rows, err := sc.DB.Query(`
+		SELECT s.id, s.post_id, COALESCE(p.title, ''), s.category, s.level, s.created_at
+		FROM subscriptions s
+		LEFT JOIN posts p ON p.id = s.post_id
+		WHERE s.user_id = ? AND (s.post_id = 0 OR p.deleted_at IS NULL)
+		ORDER BY s.post_id != 0, s.category, s.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := make([]models.Subscription, 0)
+	for rows.Next() {
+		var s models.Subscription
+		if err := rows.Scan(&s.ID, &s.PostID, &s.PostTitle, &s.Category, &s.Level, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions, rows.Err()
+}
+
+// GetEvents returns the events fanned out to the user since the given time, oldest first
+func (sc *SubscriptionController) GetEvents(userID int, since time.Time) ([]models.SubscriptionEvent, error) {
+	rows, err := sc.DB.Query(`
+		SELECT id, event_type, actor_id, post_id, comment_id, reason, created_at
+		FROM subscription_events
+		WHERE user_id = ? AND created_at > ?
+		ORDER BY created_at, id
+	`, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscription events: %w", err)
+	}
+	defer rows.Close()
+
+	var result []models.SubscriptionEvent
+	for rows.Next() {
+		var e models.SubscriptionEvent
+		if err := rows.Scan(&e.ID, &e.Type, &e.ActorID, &e.PostID, &e.CommentID, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan subscription event: %w", err)
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// PostCreated subscribes the author to their new post and fans the event out to
+// everyone watching all activity in one of its categories
+func (sc *SubscriptionController) PostCreated(post models.Post) ([]events.Recipient, error) {
+	if err := sc.AutoSubscribe(post.UserID, post.ID, models.WatchAll); err != nil {
+		return nil, err
+	}
+
+	ev := events.Event{
+		Type:       events.PostCreated,
+		ActorID:    post.UserID,
+		PostID:     post.ID,
+		Categories: PostCategories(post.Category),
+		CreatedAt:  time.Now(),
+	}
+	return sc.fanOut(ev, 0)
+}
+
+// CommentCreated subscribes the commenter to the thread at the replies level and fans the
+// event out to the thread's and categories' subscribers
+func (sc *SubscriptionController) CommentCreated(comment models.Comment) ([]events.Recipient, error) {
+	if err := sc.AutoSubscribe(comment.UserID, comment.PostID, models.WatchReplies); err != nil {
+		return nil, err
+	}
+
+	// A top level comment replies to the post author, a nested one to the parent comment's author
+	var category string
+	var repliedTo int
+	err := sc.DB.QueryRow(`SELECT category, user_id FROM posts WHERE id = ?`, comment.PostID).Scan(&category, &repliedTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch post %d: %w", comment.PostID, err)
+	}
+	if comment.ParentID.Valid {
+		err := sc.DB.QueryRow(`SELECT user_id FROM comments WHERE id = ?`, comment.ParentID.Int64).Scan(&repliedTo)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to fetch parent comment: %w", err)
+		}
+	}
+
+	ev := events.Event{
+		Type:       events.CommentCreated,
+		ActorID:    comment.UserID,
+		PostID:     comment.PostID,
+		CommentID:  comment.ID,
+		Categories: PostCategories(category),
+		CreatedAt:  time.Now(),
+	}
+	return sc.fanOut(ev, repliedTo)
+}
+
+// fanOut works out who receives the event and delivers it to them
+func (sc *SubscriptionController) fanOut(ev events.Event, repliedTo int) ([]events.Recipient, error) {
+	recipients, err := sc.recipients(ev, repliedTo)
+	if err != nil {
+		return nil, err
+	}
+	if err := sc.Deliver(ev, recipients); err != nil {
+		return nil, err
+	}
+	return recipients, nil
+}
+
+// Deliver records one row per recipient for the event and publishes it to the registered listeners
+func (sc *SubscriptionController) Deliver(ev events.Event, recipients []events.Recipient) error {
+	if len(recipients) > 0 {
+		tx, err := sc.DB.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		for _, r := range recipients {
+			_, err := tx.Exec(`
+				INSERT INTO subscription_events (user_id, event_type, actor_id, post_id, comment_id, reason, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?)
+			`, r.UserID, ev.Type, ev.ActorID, ev.PostID, ev.CommentID, r.Reason, ev.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("failed to record subscription event: %w", err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+	}
+
+	events.Publish(ev, recipients)
+	return nil
+}
+
+// recipients applies the watch levels: a thread subscription overrides category subscriptions,
+// muting a category mutes it for all of its posts, and the actor never receives their own event
+func (sc *SubscriptionController) recipients(ev events.Event, repliedTo int) ([]events.Recipient, error) {
+	threadLevels := make(map[int]string)
+	categoryLevels := make(map[int]string)
+
+	args := []interface{}{ev.PostID}
+	query := `SELECT user_id, post_id, level FROM subscriptions WHERE post_id = ?`
+	if len(ev.Categories) > 0 {
+		query += ` OR (post_id = 0 AND category IN (?` + strings.Repeat(`, ?`, len(ev.Categories)-1) + `))`
+		for _, c := range ev.Categories {
+			args = append(args, c)
+		}
+	}
+	rows, err := sc.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscribers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID, postID int
+		var level string
+		if err := rows.Scan(&userID, &postID, &level); err != nil {
+			return nil, fmt.Errorf("failed to scan subscriber: %w", err)
+		}
+		if postID != 0 {
+			threadLevels[userID] = level
+			continue
+		}
+		switch current := categoryLevels[userID]; {
+		case current == models.WatchMuted:
+		case level == models.WatchMuted, level == models.WatchAll, current == "":
+			categoryLevels[userID] = level
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	users := make([]int, 0, len(threadLevels)+len(categoryLevels))
+	for userID := range threadLevels {
+		users = append(users, userID)
+	}
+	for userID := range categoryLevels {
+		if _, ok := threadLevels[userID]; !ok {
+			users = append(users, userID)
+		}
+	}
+	sort.Ints(users)
+
+	var recipients []events.Recipient
+	for _, userID := range users {
+		if userID == ev.ActorID {
+			continue
+		}
+		level, onThread := threadLevels[userID]
+		if !onThread {
+			level = categoryLevels[userID]
+		}
+		switch {
+		case level == models.WatchMuted:
+		case userID == repliedTo:
+			recipients = append(recipients, events.Recipient{UserID: userID, Reason: events.ReasonReply})
+		case level == models.WatchAll && onThread:
+			recipients = append(recipients, events.Recipient{UserID: userID, Reason: events.ReasonThread})
+		case level == models.WatchAll:
+			recipients = append(recipients, events.Recipient{UserID: userID, Reason: events.ReasonCategory})
+		}
+	}
+	return recipients, nil
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/events"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+func TestSubscriptionFanOut(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	for i := 1; i <= 5; i++ {
+		if err := InsertTestUser(db, fmt.Sprintf("user%d@example.com", i), fmt.Sprintf("user%d", i), "password123"); err != nil {
+			t.Fatalf("Failed to insert test user: %v", err)
+		}
+	}
+
+	var published []string
+	defer events.Register(func(ev events.Event, _ []events.Recipient) {
+		published = append(published, ev.Type)
+	})()
+
+	sc := NewSubscriptionController(db)
+	pc := NewPostController(db)
+	cc := NewCommentController(db)
+
+	// user2 watches music, user3 watches music but muted gaming
+	for _, s := range []struct {
+		userID          int
+		category, level string
+	}{
+		{2, "music", models.WatchAll},
+		{3, "Music ", models.WatchAll},
+		{3, "gaming", models.WatchMuted},
+	} {
+		if err := sc.Subscribe(s.userID, 0, s.category, s.level); err != nil {
+			t.Fatalf("Subscribe() error = %v", err)
+		}
+	}
+
+	post := models.Post{Title: "title", Author: "user1", UserID: 1, Category: "music,gaming", Content: "content", Timestamp: time.Now()}
+	post.ID, err = pc.InsertPost(post)
+	if err != nil {
+		t.Fatalf("InsertPost() error = %v", err)
+	}
+	comment := func(userID, parentID int) models.Comment {
+		c := models.Comment{
+			PostID: post.ID, UserID: userID, Author: "user", Content: "comment", Timestamp: time.Now(),
+			ParentID: sql.NullInt64{Int64: int64(parentID), Valid: parentID != 0},
+		}
+		if c.ID, err = cc.InsertComment(c); err != nil {
+			t.Fatalf("InsertComment() error = %v", err)
+		}
+		return c
+	}
+
+	got, err := sc.PostCreated(post)
+	if err != nil {
+		t.Fatalf("PostCreated() error = %v", err)
+	}
+	if want := []events.Recipient{{UserID: 2, Reason: events.ReasonCategory}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PostCreated() recipients = %v, want %v", got, want)
+	}
+	if level, _ := sc.GetWatchLevel(1, post.ID); level != models.WatchAll {
+		t.Errorf("author watch level = %q, want %q", level, models.WatchAll)
+	}
+
+	steps := []struct {
+		name   string
+		setup  func()
+		userID int
+		parent func() int
+		want   []events.Recipient
+	}{
+		{
+			name:   "top level comment reaches the author and category watchers",
+			userID: 4,
+			want:   []events.Recipient{{UserID: 1, Reason: events.ReasonReply}, {UserID: 2, Reason: events.ReasonCategory}},
+		},
+		{
+			name: "muting the thread overrides the category",
+			setup: func() {
+				if err := sc.Subscribe(2, post.ID, "", models.WatchMuted); err != nil {
+					t.Fatalf("Subscribe() error = %v", err)
+				}
+			},
+			userID: 1,
+			parent: func() int { return 1 },
+			want:   []events.Recipient{{UserID: 4, Reason: events.ReasonReply}},
+		},
+		{
+			name:   "replies level ignores comments that are not replies",
+			userID: 5,
+			want:   []events.Recipient{{UserID: 1, Reason: events.ReasonReply}},
+		},
+	}
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup()
+			}
+			parentID := 0
+			if tt.parent != nil {
+				parentID = tt.parent()
+			}
+			got, err := sc.CommentCreated(comment(tt.userID, parentID))
+			if err != nil {
+				t.Fatalf("CommentCreated() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CommentCreated() recipients = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if level, _ := sc.GetWatchLevel(4, post.ID); level != models.WatchReplies {
+		t.Errorf("commenter watch level = %q, want %q", level, models.WatchReplies)
+	}
+	if len(published) != 4 {
+		t.Errorf("published %d events, want 4", len(published))
+	}
+	inbox, err := sc.GetEvents(1, time.Time{})
+	if err != nil {
+		t.Fatalf("GetEvents() error = %v", err)
+	}
+	if len(inbox) != 2 || inbox[0].Type != events.CommentCreated || inbox[0].Reason != events.ReasonReply {
+		t.Errorf("GetEvents() = %+v", inbox)
+	}
+
+	subs, err := sc.GetSubscriptions(3)
+	if err != nil {
+		t.Fatalf("GetSubscriptions() error = %v", err)
+	}
+	if len(subs) != 2 || subs[0].Category != "gaming" || subs[1].Category != "music" {
+		t.Errorf("GetSubscriptions() = %+v", subs)
+	}
+}
+
+func TestSubscriptionController_Subscribe(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := InsertTestUser(db, "test@example.com", "testuser", "password123"); err != nil {
+		t.Fatalf("Failed to insert test user: %v", err)
+	}
+	postID, err := NewPostController(db).InsertPost(models.Post{
+		Title: "title", Author: "testuser", UserID: 1, Category: "music", Content: "content", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("InsertPost() error = %v", err)
+	}
+	sc := NewSubscriptionController(db)
+
+	tests := []struct {
+		name     string
+		postID   int
+		category string
+		level    string
+		wantErr  error
+	}{
+		{"post", postID, "", models.WatchReplies, nil},
+		{"change level", postID, "", models.WatchMuted, nil},
+		{"category", 0, "music", models.WatchAll, nil},
+		{"invalid level", postID, "", "loud", ErrInvalidWatchLevel},
+		{"neither target", 0, " ", models.WatchAll, ErrInvalidSubscription},
+		{"both targets", postID, "music", models.WatchAll, ErrInvalidSubscription},
+		{"missing post", 999, "", models.WatchAll, ErrSubscriptionPostMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := sc.Subscribe(1, tt.postID, tt.category, tt.level); err != tt.wantErr {
+				t.Errorf("Subscribe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if level, _ := sc.GetWatchLevel(1, postID); level != models.WatchMuted {
+		t.Errorf("GetWatchLevel() = %q, want %q", level, models.WatchMuted)
+	}
+	if err := sc.Unsubscribe(1, postID, ""); err != nil {
+		t.Fatalf("Unsubscribe() error = %v", err)
+	}
+	if err := sc.Unsubscribe(1, postID, ""); err != ErrSubscriptionNotFound {
+		t.Errorf("Unsubscribe() twice error = %v, want %v", err, ErrSubscriptionNotFound)
+	}
+	if level, _ := sc.GetWatchLevel(1, postID); level != "" {
+		t.Errorf("GetWatchLevel() after unsubscribe = %q, want none", level)
+	}
+}
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/config"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+)
+
+var (
+	ErrPostNotFound   = errors.New("post not found")
+	ErrThreadLocked   = errors.New("this thread is locked and no longer accepts comments")
+	ErrThreadArchived = errors.New("this thread is archived and no longer accepts comments")
+)
+
+// DefaultArchiveAfter is how long a thread may go without activity before it is archived
+const DefaultArchiveAfter = 180 * 24 * time.Hour
+
+// ArchiveAfter returns the inactivity period configured through FORUM_ARCHIVE_AFTER; zero disables archiving
+func ArchiveAfter() time.Duration {
+	return config.Duration("FORUM_ARCHIVE_AFTER", DefaultArchiveAfter)
+}
+
+// CheckThreadOpen returns ErrThreadLocked or ErrThreadArchived when the post cannot receive comments
+func CheckThreadOpen(db *sql.DB, postID int) error {
+	var locked, archived bool
+	err := db.QueryRow(`SELECT locked, archived FROM posts WHERE id = ? AND deleted_at IS NULL`, postID).Scan(&locked, &archived)
+	if err == sql.ErrNoRows {
+		return ErrPostNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch thread state: %w", err)
+	}
+	if archived {
+		return ErrThreadArchived
+	}
+	if locked {
+		return ErrThreadLocked
+	}
+	return nil
+}
+
+// SetPinned pins a post globally (empty category) or within one of its categories, or unpins it
+func (pc *PostController) SetPinned(postID int, pinned bool, category string) error {
+	category = strings.ToLower(strings.TrimSpace(category))
+	if pinned && category != "" {
+		var categories string
+		err := pc.DB.QueryRow(`SELECT category FROM posts WHERE id = ?`, postID).Scan(&categories)
+		if err == sql.ErrNoRows {
+			return ErrPostNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to fetch post categories: %w", err)
+		}
+		found := false
+		for _, c := range strings.Split(categories, ",") {
+			found = found || strings.ToLower(strings.TrimSpace(c)) == category
+		}
+		if !found {
+			return fmt.Errorf("post is not in category %q", category)
+		}
+	}
+	if !pinned {
+		category = ""
+	}
+
+	var pinnedAt sql.NullTime
+	if pinned {
+		pinnedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	return pc.setThreadState(postID, `pinned = ?, pinned_category = ?, pinned_at = ?`, pinned, category, pinnedAt)
+}
+
+// SetLocked locks or unlocks a thread for new comments
+func (pc *PostController) SetLocked(postID int, locked bool) error {
+	return pc.setThreadState(postID, `locked = ?`, locked)
+}
+
+// SetArchived archives or restores a thread
+func (pc *PostController) SetArchived(postID int, archived bool) error {
+	return pc.setThreadState(postID, `archived = ?`, archived)
+}
+
+func (pc *PostController) setThreadState(postID int, assignments string, args ...interface{}) error {
+	result, err := pc.DB.Exec(`UPDATE posts SET `+assignments+` WHERE id = ?`, append(args, postID)...)
+	if err != nil {
+		return fmt.Errorf("failed to update thread state: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrPostNotFound
+	}
+	return nil
+}
+
+// ArchiveInactivePosts archives unpinned threads whose last post or comment is older than inactiveFor
+func ArchiveInactivePosts(db *sql.DB, inactiveFor time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-inactiveFor)
+	result, err := db.Exec(`
+		UPDATE posts SET archived = 1
+		WHERE archived = 0 AND pinned = 0
+		  AND timestamp < ?
+		  AND NOT EXISTS (SELECT 1 FROM comments c WHERE c.post_id = posts.id AND c.timestamp >= ?)
+	`, cutoff, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive inactive posts: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func ArchiveInactiveThreads(ctx context.Context, db *sql.DB) {
+	inactiveFor := ArchiveAfter()
+	if inactiveFor == 0 {
+		logger.Info("Thread archiving is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(1 * time.Hour) // Run archiving every hour
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping thread archiving task...")
+			return
+		case <-ticker.C:
+			logger.Info("Archiving inactive threads...")
+			archived, err := ArchiveInactivePosts(db, inactiveFor)
+			if err != nil {
+				logger.Error("Failed to archive inactive threads: %v", err)
+			}
+			if archived > 0 {
+				logger.Info("Archived %d inactive threads", archived)
+			}
+		}
+	}
+}
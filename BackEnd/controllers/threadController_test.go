@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+	"github.com/Raymond9734/forum.git/BackEnd/ranking"
+)
+
+func TestThreadStates(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := InsertTestUser(db, "test@example.com", "testuser", "password123"); err != nil {
+		t.Fatalf("Failed to insert test user: %v", err)
+	}
+
+	pc := NewPostController(db)
+	insert := func(title string, age time.Duration) int {
+		id, err := pc.InsertPost(models.Post{
+			Title: title, Author: "testuser", UserID: 1, Category: "music,science", Content: title,
+			Timestamp: time.Now().Add(-age),
+		})
+		if err != nil {
+			t.Fatalf("InsertPost() error = %v", err)
+		}
+		return id
+	}
+	old := insert("old", 400*24*time.Hour)
+	oldPinned := insert("old pinned", 400*24*time.Hour)
+	recent := insert("recent", time.Hour)
+
+	if err := pc.SetPinned(oldPinned, true, ""); err != nil {
+		t.Fatalf("SetPinned() error = %v", err)
+	}
+	if err := pc.SetPinned(old, true, "gaming"); err == nil {
+		t.Errorf("SetPinned() to a category the post is not in should fail")
+	}
+	if err := pc.SetPinned(999, true, ""); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("SetPinned() on missing post error = %v, want %v", err, ErrPostNotFound)
+	}
+
+	posts, err := pc.GetAllPosts()
+	if err != nil {
+		t.Fatalf("GetAllPosts() error = %v", err)
+	}
+	if len(posts) != 3 || posts[0].ID != oldPinned || posts[1].ID != recent {
+		t.Errorf("expected the pinned post first and the rest newest first, got %+v", posts)
+	}
+
+	// Within a category its own pins follow the global ones, and posts of other categories are left out
+	scienceOnly, err := pc.InsertPost(models.Post{
+		Title: "science", Author: "testuser", UserID: 1, Category: "Science", Content: "science", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("InsertPost() error = %v", err)
+	}
+	gaming, err := pc.InsertPost(models.Post{
+		Title: "gaming", Author: "testuser", UserID: 1, Category: "gaming", Content: "gaming", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("InsertPost() error = %v", err)
+	}
+	if err := pc.SetPinned(old, true, "science"); err != nil {
+		t.Fatalf("SetPinned() error = %v", err)
+	}
+	posts, err = pc.GetRankedPosts(ranking.SortNew, "", "Science")
+	if err != nil {
+		t.Fatalf("GetRankedPosts() error = %v", err)
+	}
+	var got []int
+	for _, post := range posts {
+		got = append(got, post.ID)
+	}
+	if want := []int{oldPinned, old, scienceOnly, recent}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRankedPosts() in science = %v, want %v", got, want)
+	}
+	posts, err = pc.GetRankedPosts(ranking.SortNew, "", "")
+	if err != nil || len(posts) != 5 || posts[1].ID != gaming {
+		t.Errorf("GetRankedPosts() of every category = %+v, %v, want the category pin in place", posts, err)
+	}
+	if err := pc.SetPinned(old, false, ""); err != nil {
+		t.Fatalf("SetPinned() error = %v", err)
+	}
+
+	if err := pc.SetLocked(recent, true); err != nil {
+		t.Fatalf("SetLocked() error = %v", err)
+	}
+
+	archived, err := ArchiveInactivePosts(db, 180*24*time.Hour)
+	if err != nil {
+		t.Fatalf("ArchiveInactivePosts() error = %v", err)
+	}
+	if archived != 1 {
+		t.Errorf("ArchiveInactivePosts() = %d, want 1", archived)
+	}
+
+	tests := []struct {
+		postID int
+		want   error
+	}{
+		{old, ErrThreadArchived},
+		{oldPinned, nil},
+		{recent, ErrThreadLocked},
+		{999, ErrPostNotFound},
+	}
+	for _, tt := range tests {
+		if err := CheckThreadOpen(db, tt.postID); !errors.Is(err, tt.want) {
+			t.Errorf("CheckThreadOpen(%d) = %v, want %v", tt.postID, err, tt.want)
+		}
+	}
+}
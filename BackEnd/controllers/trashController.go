@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/config"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+// ErrNotInTrash is returned when restoring something that is not in the user's trash
+var ErrNotInTrash = errors.New("item not found in trash or retention period expired")
+
+// DefaultTrashRetention is how long deleted posts and comments can be restored
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+const trashExcerptChars = 200
+
+// TrashRetention returns the retention period configured through FORUM_TRASH_RETENTION
+func TrashRetention() time.Duration {
+	return config.Duration("FORUM_TRASH_RETENTION", DefaultTrashRetention)
+}
+
+// GetTrash lists the posts and comments the user deleted within the retention period, newest first
+func GetTrash(db *sql.DB, userID int) ([]models.TrashItem, error) {
+	retention := TrashRetention()
+	cutoff := time.Now().Add(-retention)
+
+	rows, err := db.Query(`
+		SELECT 'post', id, id, title, content, deleted_at
+		FROM posts
+		WHERE user_id = ? AND deleted_by = ? AND deleted_at >= ?
+		UNION ALL
+		SELECT 'comment', c.id, c.post_id, COALESCE(p.title, ''), c.content, c.deleted_at
+		FROM comments c
+		LEFT JOIN posts p ON p.id = c.post_id
+		WHERE c.user_id = ? AND c.deleted_by = ? AND c.deleted_at >= ?
+	`, userID, userID, cutoff, userID, userID, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch trash: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]models.TrashItem, 0)
+	for rows.Next() {
+		var item models.TrashItem
+		if err := rows.Scan(&item.Type, &item.ID, &item.PostID, &item.Title, &item.Excerpt, &item.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan trash item: %w", err)
+		}
+		if runes := []rune(item.Excerpt); len(runes) > trashExcerptChars {
+			item.Excerpt = string(runes[:trashExcerptChars]) + "..."
+		}
+		item.PurgeAt = item.DeletedAt.Add(retention)
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].DeletedAt.After(items[j].DeletedAt) })
+	return items, nil
+}
+
+// RestoreFromTrash restores a post or comment the user deleted, if the retention period has not expired
+func RestoreFromTrash(db *sql.DB, itemType string, id, userID int) error {
+	var table string
+	switch itemType {
+	case models.TrashPost:
+		table = "posts"
+	case models.TrashComment:
+		table = "comments"
+	default:
+		return fmt.Errorf("unknown trash item type %q", itemType)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE `+table+` SET deleted_at = NULL, deleted_by = NULL
+		WHERE id = ? AND user_id = ? AND deleted_by = ? AND deleted_at >= ?
+	`, id, userID, userID, time.Now().Add(-TrashRetention()))
+	if err != nil {
+		return fmt.Errorf("failed to restore %s: %w", itemType, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotInTrash
+	}
+
+	if itemType == models.TrashComment {
+		if err := adjustCommentCount(tx, id, 1); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpiredTrash permanently deletes posts and comments deleted longer than retention ago.
+// Deleted comments that still have replies are kept as placeholders until their replies are gone.
+func PurgeExpiredTrash(ctx context.Context, db *sql.DB, retention time.Duration) (int, error) {
+	cutoff := time.Now().Add(-retention)
+
+	rows, err := db.QueryContext(ctx, `SELECT id FROM posts WHERE deleted_at < ?`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired posts: %w", err)
+	}
+	var postIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan expired post: %w", err)
+		}
+		postIDs = append(postIDs, id)
+	}
+	rows.Close()
+
+	purged := 0
+	for _, postID := range postIDs {
+		if err := purgePost(ctx, db, postID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	// Remove deleted leaf comments until only placeholders with live replies remain
+	for {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return purged, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		const expiredLeaves = `
+			SELECT id FROM comments
+			WHERE deleted_at < ? AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = comments.id)`
+		if err := reverseReputation(tx, `target_type = 'comment' AND target_id IN (`+expiredLeaves+`)`, cutoff); err != nil {
+			tx.Rollback()
+			return purged, err
+		}
+		if _, err := tx.Exec(`DELETE FROM comment_votes WHERE comment_id IN (`+expiredLeaves+`)`, cutoff); err != nil {
+			tx.Rollback()
+			return purged, fmt.Errorf("failed to delete comment votes: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM bookmarks WHERE target_type = 'comment' AND target_id IN (`+expiredLeaves+`)`, cutoff); err != nil {
+			tx.Rollback()
+			return purged, fmt.Errorf("failed to delete comment bookmarks: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM mentions WHERE source_type = 'comment' AND source_id IN (`+expiredLeaves+`)`, cutoff); err != nil {
+			tx.Rollback()
+			return purged, fmt.Errorf("failed to delete comment mentions: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM reactions WHERE target_type = 'comment' AND target_id IN (`+expiredLeaves+`)`, cutoff); err != nil {
+			tx.Rollback()
+			return purged, fmt.Errorf("failed to delete comment reactions: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM subscription_events WHERE comment_id IN (`+expiredLeaves+`)`, cutoff); err != nil {
+			tx.Rollback()
+			return purged, fmt.Errorf("failed to delete comment subscription events: %w", err)
+		}
+		result, err := tx.Exec(`DELETE FROM comments WHERE id IN (`+expiredLeaves+`)`, cutoff)
+		if err != nil {
+			tx.Rollback()
+			return purged, fmt.Errorf("failed to delete comments: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return purged, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		if n == 0 {
+			break
+		}
+		purged += int(n)
+	}
+
+	return purged, nil
+}
+
+// purgePost removes a post with everything attached to it and releases its image
+func purgePost(ctx context.Context, db *sql.DB, postID int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var imageURL sql.NullString
+	if err := tx.QueryRow(`SELECT image_url FROM posts WHERE id = ?`, postID).Scan(&imageURL); err != nil {
+		return fmt.Errorf("failed to fetch post %d: %w", postID, err)
+	}
+
+	// The votes go with the post and its comments, and so does the reputation they gave
+	if err := reverseReputation(tx, `post_id = ?`, postID); err != nil {
+		return err
+	}
+
+	for _, query := range []string{
+		`DELETE FROM comment_votes WHERE comment_id IN (SELECT id FROM comments WHERE post_id = ?)`,
+		`DELETE FROM bookmarks WHERE target_type = 'comment' AND target_id IN (SELECT id FROM comments WHERE post_id = ?)`,
+		`DELETE FROM bookmarks WHERE target_type = 'post' AND target_id = ?`,
+		`DELETE FROM reactions WHERE target_type = 'comment' AND target_id IN (SELECT id FROM comments WHERE post_id = ?)`,
+		`DELETE FROM reactions WHERE target_type = 'post' AND target_id = ?`,
+		`DELETE FROM subscriptions WHERE post_id = ?`,
+		`DELETE FROM subscription_events WHERE post_id = ?`,
+		`DELETE FROM mentions WHERE post_id = ?`,
+		`DELETE FROM comments WHERE post_id = ?`,
+		`DELETE FROM likes WHERE post_id = ?`,
+		`DELETE FROM poll_ballot_options WHERE ballot_id IN
+			(SELECT b.id FROM poll_ballots b JOIN polls p ON p.id = b.poll_id WHERE p.post_id = ?)`,
+		`DELETE FROM poll_ballots WHERE poll_id IN (SELECT id FROM polls WHERE post_id = ?)`,
+		`DELETE FROM poll_options WHERE poll_id IN (SELECT id FROM polls WHERE post_id = ?)`,
+		`DELETE FROM polls WHERE post_id = ?`,
+		`DELETE FROM posts WHERE id = ?`,
+	} {
+		if _, err := tx.Exec(query, postID); err != nil {
+			return fmt.Errorf("failed to purge post %d: %w", postID, err)
+		}
+	}
+
+	// Unreferenced files are removed by the upload collector
+	if imageURL.Valid {
+		if err := releaseUpload(tx, imageURL.String); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func PurgeDeletedContent(ctx context.Context, db *sql.DB) {
+	ticker := time.NewTicker(1 * time.Hour) // Run purge every hour
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping trash purge task...")
+			return
+		case <-ticker.C:
+			logger.Info("Purging expired trash...")
+			purged, err := PurgeExpiredTrash(ctx, db, TrashRetention())
+			if err != nil {
+				logger.Error("Failed to purge expired trash: %v", err)
+			}
+			if purged > 0 {
+				logger.Info("Purged %d deleted posts and comments", purged)
+			}
+		}
+	}
+}
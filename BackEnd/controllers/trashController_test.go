@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+func TestSoftDeleteRestoreAndPurge(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := InsertTestUser(db, "test@example.com", "testuser", "password123"); err != nil {
+		t.Fatalf("Failed to insert test user: %v", err)
+	}
+
+	pc := NewPostController(db)
+	cc := NewCommentController(db)
+	postID, err := pc.InsertPost(models.Post{
+		Title: "title", Author: "testuser", UserID: 1, Category: "music", Content: "content", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("InsertPost() error = %v", err)
+	}
+	comment := func(parentID int) int {
+		id, err := cc.InsertComment(models.Comment{
+			PostID: postID, UserID: 1, Author: "testuser", Content: "comment", Timestamp: time.Now(),
+			ParentID: sql.NullInt64{Int64: int64(parentID), Valid: parentID != 0},
+		})
+		if err != nil {
+			t.Fatalf("InsertComment() error = %v", err)
+		}
+		return id
+	}
+	parent := comment(0)
+	reply := comment(parent)
+	lonely := comment(0)
+
+	rc := NewReactionController(db)
+	if _, err := rc.React(1, models.ReactionPost, postID, "👍"); err != nil {
+		t.Fatalf("React() error = %v", err)
+	}
+	if _, err := rc.React(1, models.ReactionComment, parent, "👍"); err != nil {
+		t.Fatalf("React() error = %v", err)
+	}
+
+	if err := InsertTestUser(db, "voter@example.com", "voter", "password123"); err != nil {
+		t.Fatalf("Failed to insert test user: %v", err)
+	}
+	vc := NewVoteController(db)
+	if _, err := vc.Vote(models.VoteTargetPost, postID, 2, models.VoteLike); err != nil {
+		t.Fatalf("Vote() error = %v", err)
+	}
+	if _, err := vc.Vote(models.VoteTargetComment, reply, 2, models.VoteLike); err != nil {
+		t.Fatalf("Vote() error = %v", err)
+	}
+
+	for _, id := range []int{parent, lonely} {
+		if err := cc.DeleteComment(id, 1); err != nil {
+			t.Fatalf("DeleteComment() error = %v", err)
+		}
+	}
+
+	// The deleted parent stays as a placeholder for its reply, the lonely comment disappears
+	page, err := cc.GetCommentTreePage(postID, 0, models.CommentSortNew, 0)
+	if err != nil {
+		t.Fatalf("GetCommentTreePage() error = %v", err)
+	}
+	tree := page.Comments
+	if len(tree) != 1 || !tree[0].IsDeleted || tree[0].Content != models.DeletedPlaceholder ||
+		len(tree[0].Replies) != 1 || tree[0].Replies[0].ID != reply {
+		t.Fatalf("unexpected comment tree: %+v", tree)
+	}
+	if count, _ := cc.GetCommentCountByPostID(postID); count != 1 {
+		t.Errorf("GetCommentCountByPostID() = %d, want 1", count)
+	}
+
+	if err := pc.DeletePost(postID, 1); err != nil {
+		t.Fatalf("DeletePost() error = %v", err)
+	}
+	if posts, _ := pc.GetAllPosts(); len(posts) != 0 {
+		t.Errorf("deleted post is still listed")
+	}
+
+	for targetType, id := range map[string]int{models.ReactionPost: postID, models.ReactionComment: parent} {
+		if reactors, err := rc.GetReactors(targetType, id, ""); err != nil || len(reactors) != 0 {
+			t.Errorf("GetReactors() of a deleted %s = %+v, %v, want none", targetType, reactors, err)
+		}
+	}
+
+	trash, err := GetTrash(db, 1)
+	if err != nil {
+		t.Fatalf("GetTrash() error = %v", err)
+	}
+	if len(trash) != 3 || trash[0].Type != models.TrashPost {
+		t.Fatalf("unexpected trash: %+v", trash)
+	}
+
+	if err := RestoreFromTrash(db, models.TrashPost, postID, 2); err != ErrNotInTrash {
+		t.Errorf("RestoreFromTrash() by another user error = %v, want %v", err, ErrNotInTrash)
+	}
+	if err := RestoreFromTrash(db, models.TrashComment, lonely, 1); err != nil {
+		t.Errorf("RestoreFromTrash() error = %v", err)
+	}
+
+	// Votes on purged posts and comments take back the reputation they gave, both when a post goes
+	// with its comments and when a deleted comment goes on its own
+	otherPost, err := pc.InsertPost(models.Post{
+		Title: "other", Author: "testuser", UserID: 1, Category: "music", Content: "content", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("InsertPost() error = %v", err)
+	}
+	leaf, err := cc.InsertComment(models.Comment{PostID: otherPost, UserID: 1, Author: "testuser", Content: "leaf", Timestamp: time.Now()})
+	if err != nil {
+		t.Fatalf("InsertComment() error = %v", err)
+	}
+	if _, err := vc.Vote(models.VoteTargetComment, leaf, 2, models.VoteLike); err != nil {
+		t.Fatalf("Vote() error = %v", err)
+	}
+	if err := cc.DeleteComment(leaf, 1); err != nil {
+		t.Fatalf("DeleteComment() error = %v", err)
+	}
+	// Events about the leaf go with it, those about its post stay
+	for _, commentID := range []int{leaf, 0} {
+		if _, err := db.Exec(`INSERT INTO subscription_events (user_id, event_type, actor_id, post_id, comment_id, reason)
+			VALUES (2, 'comment.created', 1, ?, ?, 'thread')`, otherPost, commentID); err != nil {
+			t.Fatalf("failed to insert subscription event: %v", err)
+		}
+	}
+	if reputation, _ := GetReputation(db, 1); reputation == 0 {
+		t.Fatal("votes gave no reputation")
+	}
+
+	// Everything deleted is expired with a zero retention; the restored comment survives
+	purged, err := PurgeExpiredTrash(context.Background(), db, 0)
+	if err != nil {
+		t.Fatalf("PurgeExpiredTrash() error = %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("PurgeExpiredTrash() = %d, want 2", purged)
+	}
+	var given int
+	if err := db.QueryRow(`SELECT COALESCE(SUM(delta), 0) FROM reputation_ledger`).Scan(&given); err != nil || given != 0 {
+		t.Errorf("reputation left in the ledger = %d, %v, want 0", given, err)
+	}
+	if reputation, err := GetReputation(db, 1); err != nil || reputation != 0 {
+		t.Errorf("reputation after purging = %d, %v, want 0", reputation, err)
+	}
+	var remaining, reactions int
+	db.QueryRow(`SELECT COUNT(*) FROM comments`).Scan(&remaining)
+	if remaining != 0 {
+		t.Errorf("%d comments left after purging their post", remaining)
+	}
+	db.QueryRow(`SELECT COUNT(*) FROM reactions`).Scan(&reactions)
+	if reactions != 0 {
+		t.Errorf("%d reactions left after purging their post and comments", reactions)
+	}
+	var subscriptionEvents int
+	db.QueryRow(`SELECT COUNT(*) FROM subscription_events`).Scan(&subscriptionEvents)
+	if subscriptionEvents != 1 {
+		t.Errorf("%d subscription events left after purging, want the one about the remaining post", subscriptionEvents)
+	}
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+// ErrUserNotFound is returned when no user has the requested username
+var ErrUserNotFound = errors.New("user not found")
+
+// GetUserRole returns the role of the given user
+func GetUserRole(db *sql.DB, userID int) (string, error) {
+	var role string
+	err := db.QueryRow(`SELECT role FROM users WHERE id = ?`, userID).Scan(&role)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch role of user %d: %w", userID, err)
+	}
+	return role, nil
+}
+
+// HasRole reports whether the user holds one of the given roles
+func HasRole(db *sql.DB, userID int, roles ...string) bool {
+	role, err := GetUserRole(db, userID)
+	if err != nil {
+		return false
+	}
+	for _, r := range roles {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// IsModerator reports whether the user may moderate content
+func IsModerator(db *sql.DB, userID int) bool {
+	return HasRole(db, userID, models.RoleModerator, models.RoleAdmin)
+}
+
+// PromoteAdmins grants the admin role to the given usernames
+func PromoteAdmins(db *sql.DB, usernames []string) error {
+	for _, username := range usernames {
+		_, err := db.Exec(`UPDATE users SET role = ? WHERE username = ?`, models.RoleAdmin, username)
+		if err != nil {
+			return fmt.Errorf("failed to promote %s to admin: %w", username, err)
+		}
+	}
+	return nil
+}
+
+// GetUserIDByUsername looks up the ID of the user with the given username
+func GetUserIDByUsername(db *sql.DB, username string) (int, error) {
+	var userID int
+	err := db.QueryRow(`SELECT id FROM users WHERE username = ?`, username).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up user %s: %w", username, err)
+	}
+	return userID, nil
+}
+
+// GetUserProfile returns the public profile of the user with the given username
+// along with their most recent posts
+func GetUserProfile(db *sql.DB, username string, postLimit int) (models.UserProfile, error) {
+	var profile models.UserProfile
+	err := db.QueryRow(`SELECT id, username, role, reputation FROM users WHERE username = ?`, username).
+		Scan(&profile.ID, &profile.Username, &profile.Role, &profile.Reputation)
+	if err == sql.ErrNoRows {
+		return profile, ErrUserNotFound
+	}
+	if err != nil {
+		return profile, fmt.Errorf("failed to look up user %s: %w", username, err)
+	}
+
+	rows, err := db.Query(`
+		SELECT id, title, category, timestamp, comment_count
+		FROM posts
+		WHERE user_id = ? AND deleted_at IS NULL
+		ORDER BY timestamp DESC
+		LIMIT ?
+	`, profile.ID, postLimit)
+	if err != nil {
+		return profile, fmt.Errorf("failed to fetch posts of user %s: %w", username, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		post := models.Post{Author: profile.Username, UserID: profile.ID}
+		if err := rows.Scan(&post.ID, &post.Title, &post.Category, &post.Timestamp, &post.CommentCount); err != nil {
+			return profile, fmt.Errorf("failed to scan post: %w", err)
+		}
+		profile.Posts = append(profile.Posts, post)
+	}
+	return profile, rows.Err()
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+	"github.com/Raymond9734/forum.git/BackEnd/ranking"
+)
+
+var (
+	ErrInvalidVote        = errors.New("vote must be either 'like' or 'dislike'")
+	ErrVoteTargetNotFound = errors.New("the post or comment voted on does not exist")
+)
+
+// voteTable describes where the votes on one kind of target are stored and counted
+type voteTable struct {
+	// votes holds one row per voter and target, unique on (target, user_id)
+	votes  string
+	target string
+	vote   string
+	// created holds when the vote was last cast
+	created string
+	// counted caches the likes and dislikes of each target; post selects the target's post
+	counted string
+	post    string
+	// rescore updates what is derived from a target's counts, inside the vote transaction
+	rescore func(tx *sql.Tx, targetID, likes, dislikes int) error
+}
+
+var voteTables = map[string]voteTable{
+	models.VoteTargetPost: {
+		votes: "likes", target: "post_id", vote: "user_vote", created: "created_at", counted: "posts", post: "id",
+		rescore: rescorePost,
+	},
+	models.VoteTargetComment: {
+		votes: "comment_votes", target: "comment_id", vote: "vote_type", created: "timestamp", counted: "comments",
+		post: "post_id",
+	},
+}
+
+type VoteController struct {
+	DB *sql.DB
+}
+
+func NewVoteController(db *sql.DB) *VoteController {
+	return &VoteController{DB: db}
+}
+
+// Vote toggles a user's vote on a post or comment: voting the same way again removes the vote and
+// voting the other way replaces it. The vote, the target's counts and its author's reputation change
+// in one transaction, so concurrent votes never leave duplicate rows or stale counts behind.
+// Casting a dislike requires the downvote privilege; removing one does not
+func (vc *VoteController) Vote(targetType string, targetID, userID int, vote string) (models.VoteTally, error) {
+	var tally models.VoteTally
+	table, ok := voteTables[targetType]
+	if !ok {
+		return tally, fmt.Errorf("unknown vote target %q", targetType)
+	}
+	if vote != models.VoteLike && vote != models.VoteDislike {
+		return tally, ErrInvalidVote
+	}
+
+	tx, err := vc.DB.Begin()
+	if err != nil {
+		return tally, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Writing first takes SQLite's write lock at once, so concurrent votes wait for each other
+	// instead of failing to upgrade a read lock
+	result, err := tx.Exec(`DELETE FROM `+table.votes+` WHERE `+table.target+` = ? AND user_id = ? AND `+table.vote+` = ?`,
+		targetID, userID, vote)
+	if err != nil {
+		return tally, fmt.Errorf("failed to remove vote: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return tally, fmt.Errorf("failed to remove vote: %w", err)
+	}
+	previous := ""
+	if removed > 0 {
+		previous = vote
+	} else {
+		// Reading is safe now that the transaction holds the write lock
+		err = tx.QueryRow(`SELECT `+table.vote+` FROM `+table.votes+` WHERE `+table.target+` = ? AND user_id = ?`,
+			targetID, userID).Scan(&previous)
+		if err != nil && err != sql.ErrNoRows {
+			return tally, fmt.Errorf("failed to fetch vote: %w", err)
+		}
+		if vote == models.VoteDislike {
+			if err := checkPrivilege(tx, userID, models.PrivilegeDownvote); err != nil {
+				return tally, err
+			}
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO `+table.votes+` (`+table.target+`, user_id, `+table.vote+`, `+table.created+`) VALUES (?, ?, ?, ?)
+			ON CONFLICT (`+table.target+`, user_id)
+			DO UPDATE SET `+table.vote+` = excluded.`+table.vote+`, `+table.created+` = excluded.`+table.created,
+			targetID, userID, vote, time.Now())
+		if err != nil {
+			return tally, fmt.Errorf("failed to save vote: %w", err)
+		}
+		tally.UserVote = vote
+	}
+
+	var authorID, postID int
+	tally.Likes, tally.Dislikes, authorID, postID, err = recountVotes(tx, table, targetID)
+	if err != nil {
+		return tally, err
+	}
+	if err := recordReputation(tx, targetType, targetID, postID, authorID, userID, previous, tally.UserVote); err != nil {
+		return tally, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return tally, fmt.Errorf("failed to commit vote: %w", err)
+	}
+	return tally, nil
+}
+
+// recountVotes counts the votes on a target again and updates its cached counts and what is derived
+// from them, inside the vote transaction. It returns the counts along with the target's author and post
+func recountVotes(tx *sql.Tx, table voteTable, targetID int) (likes, dislikes, authorID, postID int, err error) {
+	err = tx.QueryRow(`
+		SELECT COALESCE(SUM(`+table.vote+` = 'like'), 0), COALESCE(SUM(`+table.vote+` = 'dislike'), 0)
+		FROM `+table.votes+` WHERE `+table.target+` = ?
+	`, targetID).Scan(&likes, &dislikes)
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("failed to count votes: %w", err)
+	}
+	result, err := tx.Exec(`UPDATE `+table.counted+` SET likes = ?, dislikes = ? WHERE id = ? AND deleted_at IS NULL`,
+		likes, dislikes, targetID)
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("failed to update vote counts: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("failed to update vote counts: %w", err)
+	}
+	if updated == 0 {
+		return 0, 0, 0, 0, ErrVoteTargetNotFound
+	}
+
+	err = tx.QueryRow(`SELECT user_id, `+table.post+` FROM `+table.counted+` WHERE id = ?`, targetID).Scan(&authorID, &postID)
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("failed to fetch author: %w", err)
+	}
+	if table.rescore != nil {
+		if err := table.rescore(tx, targetID, likes, dislikes); err != nil {
+			return 0, 0, 0, 0, err
+		}
+	}
+	return likes, dislikes, authorID, postID, nil
+}
+
+// rescorePost updates the ranking scores derived from a post's votes
+func rescorePost(tx *sql.Tx, postID, likes, dislikes int) error {
+	var created time.Time
+	if err := tx.QueryRow(`SELECT timestamp FROM posts WHERE id = ?`, postID).Scan(&created); err != nil {
+		return fmt.Errorf("failed to retrieve post %d: %w", postID, err)
+	}
+	_, err := tx.Exec(`UPDATE posts SET hot_score = ?, controversy_score = ? WHERE id = ?`,
+		ranking.Hot(likes, dislikes, created), ranking.Controversy(likes, dislikes), postID)
+	if err != nil {
+		return fmt.Errorf("failed to update ranking scores of post %d: %w", postID, err)
+	}
+	return nil
+}
+
+// GetUserVotes lists the votes a user cast on posts and comments that are not deleted, most
+// recent first; a targetType of "" lists both kinds
+func (vc *VoteController) GetUserVotes(userID int, targetType string) ([]models.UserVote, error) {
+	type castVote struct {
+		vote   models.UserVote
+		castAt sql.NullTime
+	}
+	var cast []castVote
+	for _, kind := range []string{models.VoteTargetPost, models.VoteTargetComment} {
+		if targetType != "" && targetType != kind {
+			continue
+		}
+		table := voteTables[kind]
+		rows, err := vc.DB.Query(`
+			SELECT v.`+table.target+`, v.`+table.vote+`, v.`+table.created+`
+			FROM `+table.votes+` v
+			JOIN `+table.counted+` t ON t.id = v.`+table.target+` AND t.deleted_at IS NULL
+			WHERE v.user_id = ?
+		`, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch votes: %w", err)
+		}
+		for rows.Next() {
+			c := castVote{vote: models.UserVote{TargetType: kind}}
+			if err := rows.Scan(&c.vote.TargetID, &c.vote.Vote, &c.castAt); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan vote: %w", err)
+			}
+			cast = append(cast, c)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch votes: %w", err)
+		}
+	}
+
+	// Votes cast before vote times were recorded sort last
+	sort.SliceStable(cast, func(i, j int) bool { return cast[i].castAt.Time.After(cast[j].castAt.Time) })
+	votes := make([]models.UserVote, len(cast))
+	for i, c := range cast {
+		votes[i] = c.vote
+	}
+	return votes, nil
+}
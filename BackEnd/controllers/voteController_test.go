@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+func TestVoteToggle(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := InsertTestUser(db, "test@example.com", "testuser", "password123"); err != nil {
+		t.Fatalf("Failed to insert test user: %v", err)
+	}
+	postID, err := NewPostController(db).InsertPost(models.Post{
+		Title: "votes", Author: "testuser", UserID: 1, Category: "music", Content: "votes", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("InsertPost() error = %v", err)
+	}
+
+	vc := NewVoteController(db)
+	tests := []struct {
+		name   string
+		target string
+		id     int
+		vote   string
+		want   models.VoteTally
+		err    error
+	}{
+		{"like", models.VoteTargetPost, postID, models.VoteLike, models.VoteTally{Likes: 1, UserVote: models.VoteLike}, nil},
+		{"switch to dislike", models.VoteTargetPost, postID, models.VoteDislike, models.VoteTally{Dislikes: 1, UserVote: models.VoteDislike}, nil},
+		{"dislike again removes", models.VoteTargetPost, postID, models.VoteDislike, models.VoteTally{}, nil},
+		{"invalid vote", models.VoteTargetPost, postID, "love", models.VoteTally{}, ErrInvalidVote},
+		{"missing post", models.VoteTargetPost, 999, models.VoteLike, models.VoteTally{}, ErrVoteTargetNotFound},
+		{"missing comment", models.VoteTargetComment, 999, models.VoteLike, models.VoteTally{}, ErrVoteTargetNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := vc.Vote(tt.target, tt.id, 1, tt.vote)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Vote() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("Vote() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// A rejected vote leaves no row behind
+	var rows int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM likes`).Scan(&rows); err != nil {
+		t.Fatalf("Failed to count votes: %v", err)
+	}
+	if rows != 0 {
+		t.Errorf("likes has %d rows, want 0", rows)
+	}
+}
+
+// TestVoteConcurrency hammers the same post and comment from many goroutines and checks that no
+// duplicate votes are stored and that the cached counts match the stored votes. The database is a
+// file with several connections, so the votes contend for SQLite's locks as they do in production
+func TestVoteConcurrency(t *testing.T) {
+	db, err := database.InitFile(filepath.Join(t.TempDir(), "forum.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(8)
+
+	if err := InsertTestUser(db, "test@example.com", "testuser", "password123"); err != nil {
+		t.Fatalf("Failed to insert test user: %v", err)
+	}
+	postID, err := NewPostController(db).InsertPost(models.Post{
+		Title: "busy", Author: "testuser", UserID: 1, Category: "music", Content: "busy", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("InsertPost() error = %v", err)
+	}
+	commentID, err := InsertTestComment(db, models.Comment{
+		PostID: postID, UserID: 1, Author: "testuser", Content: "busy", Timestamp: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("InsertTestComment() error = %v", err)
+	}
+
+	const (
+		likers     = 40
+		dislikers  = 20
+		clicks     = 7 // an odd number of clicks leaves the vote in place
+		doubleUser = likers + dislikers + 1
+	)
+	targets := []struct {
+		kind, votes, target, vote, counted string
+		id                                 int
+	}{
+		{models.VoteTargetPost, "likes", "post_id", "user_vote", "posts", postID},
+		{models.VoteTargetComment, "comment_votes", "comment_id", "vote_type", "comments", int(commentID)},
+	}
+	for _, target := range targets {
+		t.Run(target.kind, func(t *testing.T) {
+			vc := NewVoteController(db)
+			var wg sync.WaitGroup
+			errs := make(chan error, (likers+dislikers+1)*clicks)
+			vote := func(userID int, vote string) {
+				defer wg.Done()
+				if _, err := vc.Vote(target.kind, target.id, userID, vote); err != nil {
+					errs <- err
+				}
+			}
+			for i := 0; i < clicks; i++ {
+				for userID := 1; userID <= likers+dislikers; userID++ {
+					v := models.VoteLike
+					if userID > likers {
+						v = models.VoteDislike
+					}
+					wg.Add(1)
+					go vote(userID, v)
+				}
+				// One user mashes both buttons; whichever lands last decides their vote
+				wg.Add(1)
+				go vote(doubleUser, []string{models.VoteLike, models.VoteDislike}[i%2])
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Errorf("Vote() error = %v", err)
+			}
+
+			var rows, voters int
+			err := db.QueryRow(`SELECT COUNT(*), COUNT(DISTINCT user_id) FROM `+target.votes+` WHERE `+target.target+` = ?`,
+				target.id).Scan(&rows, &voters)
+			if err != nil {
+				t.Fatalf("Failed to count votes: %v", err)
+			}
+			if rows != voters {
+				t.Errorf("%d vote rows for %d voters", rows, voters)
+			}
+
+			var likes, dislikes, storedLikes, storedDislikes int
+			err = db.QueryRow(`
+				SELECT COALESCE(SUM(`+target.vote+` = 'like'), 0), COALESCE(SUM(`+target.vote+` = 'dislike'), 0)
+				FROM `+target.votes+` WHERE `+target.target+` = ? AND user_id <= ?
+			`, target.id, likers+dislikers).Scan(&likes, &dislikes)
+			if err != nil {
+				t.Fatalf("Failed to count votes: %v", err)
+			}
+			if likes != likers || dislikes != dislikers {
+				t.Errorf("got %d likes and %d dislikes, want %d and %d", likes, dislikes, likers, dislikers)
+			}
+
+			err = db.QueryRow(`
+				SELECT c.likes, c.dislikes,
+					(SELECT COALESCE(SUM(`+target.vote+` = 'like'), 0) FROM `+target.votes+` WHERE `+target.target+` = c.id),
+					(SELECT COALESCE(SUM(`+target.vote+` = 'dislike'), 0) FROM `+target.votes+` WHERE `+target.target+` = c.id)
+				FROM `+target.counted+` c WHERE c.id = ?
+			`, target.id).Scan(&storedLikes, &storedDislikes, &likes, &dislikes)
+			if err != nil {
+				t.Fatalf("Failed to read counts: %v", err)
+			}
+			if storedLikes != likes || storedDislikes != dislikes {
+				t.Errorf("cached counts %d/%d, want %d/%d", storedLikes, storedDislikes, likes, dislikes)
+			}
+		})
+	}
+}
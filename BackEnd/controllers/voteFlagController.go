@@ -0,0 +1,434 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/config"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+var ErrVoteFlagNotFound = errors.New("vote flag not found")
+
+// Defaults of the vote manipulation analyzer, overridden through FORUM_VOTE_* variables
+const (
+	DefaultNewAccountAge = 7 * 24 * time.Hour
+	DefaultBurstWindow   = time.Hour
+	DefaultBurstMin      = 5
+	DefaultRingMin       = 5
+)
+
+type VoteFlagController struct {
+	DB *sql.DB
+}
+
+func NewVoteFlagController(db *sql.DB) *VoteFlagController {
+	return &VoteFlagController{DB: db}
+}
+
+// RecordFingerprints remembers the hashed IP address and device a user was seen with, so that
+// accounts used from the same place can be related. Only hashes are stored
+func RecordFingerprints(db *sql.DB, userID int, r *http.Request) error {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	now := time.Now()
+	for kind, value := range map[string]string{
+		models.FingerprintIP:     ip,
+		models.FingerprintDevice: ip + "\x00" + r.UserAgent() + "\x00" + r.Header.Get("Accept-Language"),
+	} {
+		sum := sha256.Sum256([]byte(kind + "\x00" + value))
+		_, err := db.Exec(`
+			INSERT INTO user_fingerprints (user_id, kind, fingerprint, first_seen, last_seen)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (user_id, kind, fingerprint) DO UPDATE SET last_seen = excluded.last_seen
+		`, userID, kind, hex.EncodeToString(sum[:]), now, now)
+		if err != nil {
+			return fmt.Errorf("failed to record fingerprint: %w", err)
+		}
+	}
+	return nil
+}
+
+// castVote is a like or dislike as seen by the analyzer
+type castVote struct {
+	targetType string
+	targetID   int
+	voterID    int
+	authorID   int
+	vote       string
+	// castAt is unknown for post votes cast before vote times were recorded, and voterCreated
+	// for accounts registered before account times were
+	castAt       sql.NullTime
+	voterCreated sql.NullTime
+}
+
+// loadVotes returns the votes on posts and comments that are not deleted, leaving out self votes
+func (fc *VoteFlagController) loadVotes() ([]castVote, error) {
+	var votes []castVote
+	for _, targetType := range []string{models.VoteTargetPost, models.VoteTargetComment} {
+		table := voteTables[targetType]
+		rows, err := fc.DB.Query(`
+			SELECT v.` + table.target + `, v.user_id, t.user_id, v.` + table.vote + `, v.` + table.created + `, u.created_at
+			FROM ` + table.votes + ` v
+			JOIN ` + table.counted + ` t ON t.id = v.` + table.target + ` AND t.deleted_at IS NULL
+			JOIN users u ON u.id = v.user_id
+			WHERE v.user_id != t.user_id
+		`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch votes: %w", err)
+		}
+		for rows.Next() {
+			v := castVote{targetType: targetType}
+			if err := rows.Scan(&v.targetID, &v.voterID, &v.authorID, &v.vote, &v.castAt, &v.voterCreated); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan vote: %w", err)
+			}
+			votes = append(votes, v)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch votes: %w", err)
+		}
+	}
+	return votes, nil
+}
+
+// voterAuthor identifies the likes one user gave another
+type voterAuthor struct{ voter, author int }
+
+// likesBetween counts the likes each voter gave each author
+func likesBetween(votes []castVote) map[voterAuthor]int {
+	likes := make(map[voterAuthor]int)
+	for _, v := range votes {
+		if v.vote == models.VoteLike {
+			likes[voterAuthor{v.voterID, v.authorID}]++
+		}
+	}
+	return likes
+}
+
+// detectSharedFingerprints flags voters who share an IP address or device with the author they
+// like, or with another account liking the same author
+func (fc *VoteFlagController) detectSharedFingerprints(likes map[voterAuthor]int) ([]models.VoteFlag, error) {
+	rows, err := fc.DB.Query(`
+		SELECT DISTINCT a.user_id, b.user_id, a.kind
+		FROM user_fingerprints a
+		JOIN user_fingerprints b ON b.kind = a.kind AND b.fingerprint = a.fingerprint AND b.user_id != a.user_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch shared fingerprints: %w", err)
+	}
+	defer rows.Close()
+
+	// shared maps pairs of users to how they are related; a shared device beats a shared IP
+	shared := make(map[voterAuthor]string)
+	for rows.Next() {
+		var a, b int
+		var kind string
+		if err := rows.Scan(&a, &b, &kind); err != nil {
+			return nil, fmt.Errorf("failed to scan shared fingerprint: %w", err)
+		}
+		if shared[voterAuthor{a, b}] != models.FingerprintDevice {
+			shared[voterAuthor{a, b}] = kind
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch shared fingerprints: %w", err)
+	}
+
+	likers := make(map[int][]int)
+	for pair := range likes {
+		likers[pair.author] = append(likers[pair.author], pair.voter)
+	}
+	describe := map[string]string{models.FingerprintIP: "an IP address", models.FingerprintDevice: "a device"}
+
+	var flags []models.VoteFlag
+	for pair, count := range likes {
+		detail := ""
+		if kind, ok := shared[pair]; ok {
+			detail = fmt.Sprintf("shares %s with the author", describe[kind])
+		} else {
+			for _, other := range likers[pair.author] {
+				if kind, ok := shared[voterAuthor{pair.voter, other}]; ok {
+					detail = fmt.Sprintf("shares %s with another account liking the author", describe[kind])
+					break
+				}
+			}
+		}
+		if detail != "" {
+			flags = append(flags, models.VoteFlag{
+				Kind: models.FlagSharedFingerprint, VoterID: pair.voter, AuthorID: pair.author, Votes: count, Detail: detail,
+			})
+		}
+	}
+	return flags, nil
+}
+
+// detectNewAccountBursts flags accounts younger than newAccountAge that like an author within
+// window of at least min other new accounts liking them too
+func detectNewAccountBursts(votes []castVote, likes map[voterAuthor]int, newAccountAge, window time.Duration, min int) []models.VoteFlag {
+	byAuthor := make(map[int][]castVote)
+	for _, v := range votes {
+		if v.vote != models.VoteLike || !v.castAt.Valid || !v.voterCreated.Valid {
+			continue
+		}
+		if v.castAt.Time.Sub(v.voterCreated.Time) < newAccountAge {
+			byAuthor[v.authorID] = append(byAuthor[v.authorID], v)
+		}
+	}
+
+	var flags []models.VoteFlag
+	for authorID, fresh := range byAuthor {
+		sort.Slice(fresh, func(i, j int) bool { return fresh[i].castAt.Time.Before(fresh[j].castAt.Time) })
+
+		flagged := make(map[int]bool)
+		start := 0
+		for end := range fresh {
+			for fresh[end].castAt.Time.Sub(fresh[start].castAt.Time) > window {
+				start++
+			}
+			voters := make(map[int]bool)
+			for _, v := range fresh[start : end+1] {
+				voters[v.voterID] = true
+			}
+			if len(voters) >= min {
+				for voterID := range voters {
+					flagged[voterID] = true
+				}
+			}
+		}
+		for voterID := range flagged {
+			flags = append(flags, models.VoteFlag{
+				Kind: models.FlagNewAccountBurst, VoterID: voterID, AuthorID: authorID,
+				Votes:  likes[voterAuthor{voterID, authorID}],
+				Detail: fmt.Sprintf("one of at least %d new accounts liking the author within %s", min, window),
+			})
+		}
+	}
+	return flags
+}
+
+// detectReciprocalVoting flags pairs of users who each gave the other at least min likes
+func detectReciprocalVoting(likes map[voterAuthor]int, min int) []models.VoteFlag {
+	var flags []models.VoteFlag
+	for pair, count := range likes {
+		back := likes[voterAuthor{pair.author, pair.voter}]
+		if count >= min && back >= min {
+			flags = append(flags, models.VoteFlag{
+				Kind: models.FlagReciprocalVoting, VoterID: pair.voter, AuthorID: pair.author, Votes: count,
+				Detail: fmt.Sprintf("gave the author %d likes and got %d back", count, back),
+			})
+		}
+	}
+	return flags
+}
+
+// Analyze runs every detector over the current votes and records what they flag. Flags that were
+// dismissed stay dismissed; others are reopened when found again. With FORUM_VOTE_AUTO_NULLIFY set
+// the votes of every open flag are removed at once. It returns how many flags were recorded
+func (fc *VoteFlagController) Analyze() (int, error) {
+	votes, err := fc.loadVotes()
+	if err != nil {
+		return 0, err
+	}
+	likes := likesBetween(votes)
+
+	flags, err := fc.detectSharedFingerprints(likes)
+	if err != nil {
+		return 0, err
+	}
+	flags = append(flags, detectNewAccountBursts(votes, likes,
+		config.Duration("FORUM_VOTE_NEW_ACCOUNT_AGE", DefaultNewAccountAge),
+		config.Duration("FORUM_VOTE_BURST_WINDOW", DefaultBurstWindow),
+		config.Int("FORUM_VOTE_BURST_MIN", DefaultBurstMin))...)
+	flags = append(flags, detectReciprocalVoting(likes, config.Int("FORUM_VOTE_RING_MIN", DefaultRingMin))...)
+
+	now := time.Now()
+	for _, flag := range flags {
+		_, err := fc.DB.Exec(`
+			INSERT INTO vote_flags (kind, voter_id, author_id, votes, detail, status, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, 'open', ?, ?)
+			ON CONFLICT (kind, voter_id, author_id) DO UPDATE SET
+				votes = excluded.votes,
+				detail = excluded.detail,
+				status = CASE WHEN status = 'dismissed' THEN status ELSE 'open' END,
+				updated_at = excluded.updated_at
+		`, flag.Kind, flag.VoterID, flag.AuthorID, flag.Votes, flag.Detail, now, now)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record vote flag: %w", err)
+		}
+	}
+
+	if config.Bool("FORUM_VOTE_AUTO_NULLIFY", false) {
+		open, err := fc.GetFlags(models.FlagOpen, 0)
+		if err != nil {
+			return 0, err
+		}
+		for _, flag := range open {
+			if _, err := fc.Nullify(flag.ID); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(flags), nil
+}
+
+// GetFlags returns the vote flags with the given status, most recently analyzed first; a limit of
+// 0 returns all of them
+func (fc *VoteFlagController) GetFlags(status string, limit int) ([]models.VoteFlag, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := fc.DB.Query(`
+		SELECT f.id, f.kind, f.voter_id, COALESCE(v.username, ''), f.author_id, COALESCE(a.username, ''),
+		       f.votes, f.detail, f.status, f.created_at, f.updated_at
+		FROM vote_flags f
+		LEFT JOIN users v ON v.id = f.voter_id
+		LEFT JOIN users a ON a.id = f.author_id
+		WHERE f.status = ?
+		ORDER BY f.updated_at DESC, f.id DESC
+		LIMIT ?
+	`, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch vote flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []models.VoteFlag{}
+	for rows.Next() {
+		var f models.VoteFlag
+		err := rows.Scan(&f.ID, &f.Kind, &f.VoterID, &f.Voter, &f.AuthorID, &f.Author,
+			&f.Votes, &f.Detail, &f.Status, &f.CreatedAt, &f.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan vote flag: %w", err)
+		}
+		flags = append(flags, f)
+	}
+	return flags, rows.Err()
+}
+
+// Dismiss marks a flag as a false positive; the analyzer will not reopen it
+func (fc *VoteFlagController) Dismiss(flagID int) error {
+	result, err := fc.DB.Exec(`UPDATE vote_flags SET status = ?, updated_at = ? WHERE id = ?`,
+		models.FlagDismissed, time.Now(), flagID)
+	if err != nil {
+		return fmt.Errorf("failed to dismiss vote flag: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrVoteFlagNotFound
+	}
+	return nil
+}
+
+// Nullify deletes every vote the flagged voter cast on the author's posts and comments and marks
+// the flag nullified in one transaction, counting the votes, rankings and reputation of the targets
+// again. It returns how many votes were removed
+func (fc *VoteFlagController) Nullify(flagID int) (int, error) {
+	tx, err := fc.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Writing first takes SQLite's write lock, so no vote on the targets lands while they are recounted
+	result, err := tx.Exec(`UPDATE vote_flags SET status = ?, updated_at = ? WHERE id = ?`, models.FlagNullified, time.Now(), flagID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to nullify vote flag: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return 0, ErrVoteFlagNotFound
+	}
+	var voterID, authorID int
+	err = tx.QueryRow(`SELECT voter_id, author_id FROM vote_flags WHERE id = ?`, flagID).Scan(&voterID, &authorID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch vote flag: %w", err)
+	}
+
+	removed := 0
+	for _, targetType := range []string{models.VoteTargetPost, models.VoteTargetComment} {
+		table := voteTables[targetType]
+		targets := `SELECT id FROM ` + table.counted + ` WHERE user_id = ? AND deleted_at IS NULL`
+		rows, err := tx.Query(`
+			SELECT `+table.target+`, `+table.vote+` FROM `+table.votes+`
+			WHERE user_id = ? AND `+table.target+` IN (`+targets+`)
+		`, voterID, authorID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to fetch votes: %w", err)
+		}
+		votes := make(map[int]string)
+		for rows.Next() {
+			var targetID int
+			var vote string
+			if err := rows.Scan(&targetID, &vote); err != nil {
+				rows.Close()
+				return 0, fmt.Errorf("failed to scan vote: %w", err)
+			}
+			votes[targetID] = vote
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return 0, fmt.Errorf("failed to fetch votes: %w", err)
+		}
+		if len(votes) == 0 {
+			continue
+		}
+
+		_, err = tx.Exec(`DELETE FROM `+table.votes+` WHERE user_id = ? AND `+table.target+` IN (`+targets+`)`, voterID, authorID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to remove votes: %w", err)
+		}
+		for targetID, vote := range votes {
+			_, _, _, postID, err := recountVotes(tx, table, targetID)
+			if err != nil {
+				return 0, fmt.Errorf("failed to recount votes on %s %d: %w", targetType, targetID, err)
+			}
+			if err := recordReputation(tx, targetType, targetID, postID, authorID, voterID, vote, ""); err != nil {
+				return 0, err
+			}
+			removed++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit vote nullification: %w", err)
+	}
+	logger.Info("Nullified %d votes of user %d for user %d", removed, voterID, authorID)
+	return removed, nil
+}
+
+// DetectVoteManipulation runs the vote analyzer every hour
+func DetectVoteManipulation(ctx context.Context, db *sql.DB) {
+	ticker := time.NewTicker(1 * time.Hour) // Run analysis every hour
+	defer ticker.Stop()
+
+	fc := NewVoteFlagController(db)
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping vote analysis task...")
+			return
+		case <-ticker.C:
+			logger.Info("Analyzing votes...")
+			flagged, err := fc.Analyze()
+			if err != nil {
+				logger.Error("Failed to analyze votes: %v", err)
+			}
+			if flagged > 0 {
+				logger.Info("Flagged %d suspicious voters", flagged)
+			}
+		}
+	}
+}
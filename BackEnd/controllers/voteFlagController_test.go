@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+func TestVoteManipulation(t *testing.T) {
+	t.Setenv("FORUM_VOTE_BURST_MIN", "3")
+	t.Setenv("FORUM_VOTE_RING_MIN", "2")
+
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	// author (1), their sock puppet (2), three new accounts (3-5) and two friends (6, 7)
+	for i, name := range []string{"author", "puppet", "new1", "new2", "new3", "friend1", "friend2"} {
+		if err := InsertTestUser(db, name+"@example.com", name, "password123"); err != nil {
+			t.Fatalf("Failed to insert test user: %v", err)
+		}
+		if i >= 2 && i <= 4 {
+			if _, err := db.Exec(`UPDATE users SET created_at = ? WHERE id = ?`, time.Now(), i+1); err != nil {
+				t.Fatalf("Failed to set account age: %v", err)
+			}
+		}
+	}
+	for _, userID := range []int{1, 2} {
+		r := httptest.NewRequest("POST", "/likePost", nil)
+		r.RemoteAddr = "203.0.113.7:5000"
+		r.Header.Set("User-Agent", "same-browser")
+		if err := RecordFingerprints(db, userID, r); err != nil {
+			t.Fatalf("RecordFingerprints() error = %v", err)
+		}
+	}
+
+	pc := NewPostController(db)
+	insertPost := func(userID int) int {
+		id, err := pc.InsertPost(models.Post{
+			Title: "post", Author: "user" + strconv.Itoa(userID), UserID: userID, Category: "music", Content: "post",
+			Timestamp: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("InsertPost() error = %v", err)
+		}
+		return id
+	}
+	authorPost := insertPost(1)
+	friendPosts := map[int][]int{6: {insertPost(6), insertPost(6)}, 7: {insertPost(7), insertPost(7)}}
+
+	vc := NewVoteController(db)
+	like := func(postID, voterID int) {
+		if _, err := vc.Vote(models.VoteTargetPost, postID, voterID, models.VoteLike); err != nil {
+			t.Fatalf("Vote() error = %v", err)
+		}
+	}
+	for voterID := 2; voterID <= 5; voterID++ {
+		like(authorPost, voterID)
+	}
+	for _, postID := range friendPosts[7] {
+		like(postID, 6)
+	}
+	for _, postID := range friendPosts[6] {
+		like(postID, 7)
+	}
+
+	fc := NewVoteFlagController(db)
+	flagged, err := fc.Analyze()
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if flagged != 6 {
+		t.Errorf("Analyze() flagged %d voters, want 6", flagged)
+	}
+
+	flags, err := fc.GetFlags(models.FlagOpen, 0)
+	if err != nil {
+		t.Fatalf("GetFlags() error = %v", err)
+	}
+	byKind := make(map[voterAuthor]string)
+	ids := make(map[voterAuthor]int)
+	for _, flag := range flags {
+		byKind[voterAuthor{flag.VoterID, flag.AuthorID}] = flag.Kind
+		ids[voterAuthor{flag.VoterID, flag.AuthorID}] = flag.ID
+	}
+	for pair, want := range map[voterAuthor]string{
+		{2, 1}: models.FlagSharedFingerprint,
+		{3, 1}: models.FlagNewAccountBurst,
+		{4, 1}: models.FlagNewAccountBurst,
+		{5, 1}: models.FlagNewAccountBurst,
+		{6, 7}: models.FlagReciprocalVoting,
+		{7, 6}: models.FlagReciprocalVoting,
+	} {
+		if byKind[pair] != want {
+			t.Errorf("flag of user %d voting for user %d = %q, want %q", pair.voter, pair.author, byKind[pair], want)
+		}
+	}
+
+	// Dismissed flags stay dismissed when analyzed again
+	if err := fc.Dismiss(ids[voterAuthor{3, 1}]); err != nil {
+		t.Fatalf("Dismiss() error = %v", err)
+	}
+	if _, err := fc.Analyze(); err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if flags, err := fc.GetFlags(models.FlagOpen, 0); err != nil || len(flags) != 5 {
+		t.Errorf("GetFlags() = %d flags, %v, want 5 open", len(flags), err)
+	}
+
+	// Nullifying removes the votes on posts and comments along with the reputation they gave
+	authorComment, err := InsertTestComment(db, models.Comment{PostID: friendPosts[6][0], UserID: 1, Author: "author", Content: "comment", Timestamp: time.Now()})
+	if err != nil {
+		t.Fatalf("Failed to insert test comment: %v", err)
+	}
+	if _, err := vc.Vote(models.VoteTargetComment, int(authorComment), 2, models.VoteLike); err != nil {
+		t.Fatalf("Vote() error = %v", err)
+	}
+	removed, err := fc.Nullify(ids[voterAuthor{2, 1}])
+	if err != nil || removed != 2 {
+		t.Fatalf("Nullify() = %d, %v, want 2 votes removed", removed, err)
+	}
+	var likes, commentLikes, given int
+	if err := db.QueryRow(`SELECT likes FROM posts WHERE id = ?`, authorPost).Scan(&likes); err != nil {
+		t.Fatalf("Failed to fetch likes: %v", err)
+	}
+	if err := db.QueryRow(`SELECT likes FROM comments WHERE id = ?`, authorComment).Scan(&commentLikes); err != nil {
+		t.Fatalf("Failed to fetch likes: %v", err)
+	}
+	if reputation, err := GetReputation(db, 1); err != nil || likes != 3 || commentLikes != 0 || reputation != 30 {
+		t.Errorf("after Nullify() likes = %d, comment likes = %d, reputation = %d, %v, want 3, 0 and 30",
+			likes, commentLikes, reputation, err)
+	}
+	if err := db.QueryRow(`SELECT COALESCE(SUM(delta), 0) FROM reputation_ledger WHERE voter_id = 2`).Scan(&given); err != nil || given != 0 {
+		t.Errorf("reputation the nullified voter gave = %d, %v, want 0", given, err)
+	}
+	if flags, err := fc.GetFlags(models.FlagNullified, 0); err != nil || len(flags) != 1 {
+		t.Errorf("GetFlags() = %d nullified flags, %v, want 1", len(flags), err)
+	}
+	if _, err := fc.Nullify(999); err != ErrVoteFlagNotFound {
+		t.Errorf("Nullify() of a missing flag error = %v, want %v", err, ErrVoteFlagNotFound)
+	}
+
+	// Automatic nullification clears every open flag
+	t.Setenv("FORUM_VOTE_AUTO_NULLIFY", "true")
+	if _, err := fc.Analyze(); err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if flags, err := fc.GetFlags(models.FlagOpen, 0); err != nil || len(flags) != 0 {
+		t.Errorf("GetFlags() after automatic nullification = %+v, %v, want none", flags, err)
+	}
+	if err := db.QueryRow(`SELECT likes FROM posts WHERE id = ?`, authorPost).Scan(&likes); err != nil {
+		t.Fatalf("Failed to fetch likes: %v", err)
+	}
+	if likes != 1 {
+		t.Errorf("likes after automatic nullification = %d, want 1 from the dismissed voter", likes)
+	}
+}
@@ -10,26 +10,36 @@ import (
 var GloabalDB *sql.DB
 
 func Init(env string) (*sql.DB, error) {
-	var DB *sql.DB
-	var err error
-	if env == "Test" {
-		DB, err = sql.Open("sqlite3", ":memory:")
-		if err != nil {
-			logger.Error("Failed to open Test database connection: %v", err)
-			return nil, err
-		}
-	} else {
-		DB, err = sql.Open("sqlite3", "./BackEnd/database/storage/forum.db")
-		if err != nil {
-			logger.Error("Failed to open database connection: %v", err)
-			return nil, err
-		}
+	if env != "Test" {
+		return InitFile("./BackEnd/database/storage/forum.db")
 	}
 
+	DB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		logger.Error("Failed to open Test database connection: %v", err)
+		return nil, err
+	}
+	// Every connection to ":memory:" opens a separate database, so keep a single one
+	DB.SetMaxOpenConns(1)
+	return setup(DB)
+}
+
+// InitFile opens the database stored in the file at path, creating and migrating its tables
+func InitFile(path string) (*sql.DB, error) {
+	DB, err := sql.Open("sqlite3", path)
+	if err != nil {
+		logger.Error("Failed to open database connection: %v", err)
+		return nil, err
+	}
+	return setup(DB)
+}
+
+// setup creates the tables of a newly opened database and applies the migrations
+func setup(DB *sql.DB) (*sql.DB, error) {
 	GloabalDB = DB
 
 	// Create Users table
-	_, err = DB.Exec(`
+	_, err := DB.Exec(`
         CREATE TABLE IF NOT EXISTS users (
             id INTEGER PRIMARY KEY AUTOINCREMENT,
             email TEXT UNIQUE,
@@ -150,5 +160,303 @@ func Init(env string) (*sql.DB, error) {
 		return nil, err
 	}
 
+	// Create Files table tracking content-addressed uploads
+	_, err = DB.Exec(`
+        CREATE TABLE IF NOT EXISTS files (
+            hash TEXT PRIMARY KEY,
+            storage_key TEXT NOT NULL UNIQUE,
+            size INTEGER NOT NULL,
+            content_type TEXT,
+            ref_count INTEGER NOT NULL DEFAULT 0,
+            uploaded_by INTEGER,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (uploaded_by) REFERENCES users (id) ON DELETE SET NULL
+        );
+    `)
+	if err != nil {
+		logger.Error("Failed to create files table: %v", err)
+		return nil, err
+	}
+
+	// Create Polls tables; a ballot holds the choices of one user, one ballot per user per poll
+	_, err = DB.Exec(`
+        CREATE TABLE IF NOT EXISTS polls (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            post_id INTEGER NOT NULL UNIQUE,
+            question TEXT NOT NULL,
+            multiple_choice BOOLEAN NOT NULL DEFAULT 0,
+            closes_at DATETIME,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+        );
+
+        CREATE TABLE IF NOT EXISTS poll_options (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            poll_id INTEGER NOT NULL,
+            position INTEGER NOT NULL,
+            text TEXT NOT NULL,
+            FOREIGN KEY (poll_id) REFERENCES polls (id) ON DELETE CASCADE,
+            UNIQUE(poll_id, position)
+        );
+
+        CREATE TABLE IF NOT EXISTS poll_ballots (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            poll_id INTEGER NOT NULL,
+            user_id INTEGER NOT NULL,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (poll_id) REFERENCES polls (id) ON DELETE CASCADE,
+            FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+            UNIQUE(poll_id, user_id)
+        );
+
+        CREATE TABLE IF NOT EXISTS poll_ballot_options (
+            ballot_id INTEGER NOT NULL,
+            option_id INTEGER NOT NULL,
+            PRIMARY KEY (ballot_id, option_id),
+            FOREIGN KEY (ballot_id) REFERENCES poll_ballots (id) ON DELETE CASCADE,
+            FOREIGN KEY (option_id) REFERENCES poll_options (id) ON DELETE CASCADE
+        );
+    `)
+	if err != nil {
+		logger.Error("Failed to create polls tables: %v", err)
+		return nil, err
+	}
+
+	// Create Bookmarks table; a bookmark targets either a post or a comment
+	_, err = DB.Exec(`
+        CREATE TABLE IF NOT EXISTS bookmarks (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            user_id INTEGER NOT NULL,
+            target_type TEXT NOT NULL CHECK(target_type IN ('post', 'comment')),
+            target_id INTEGER NOT NULL,
+            folder TEXT NOT NULL DEFAULT '',
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+            UNIQUE(user_id, target_type, target_id)
+        );
+
+        CREATE INDEX IF NOT EXISTS idx_bookmarks_user_folder ON bookmarks (user_id, folder, created_at);
+    `)
+	if err != nil {
+		logger.Error("Failed to create bookmarks table: %v", err)
+		return nil, err
+	}
+
+	// Create Subscriptions table; a subscription follows either a post (category is empty)
+	// or a category (post_id is 0) at one of the watch levels
+	_, err = DB.Exec(`
+        CREATE TABLE IF NOT EXISTS subscriptions (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            user_id INTEGER NOT NULL,
+            post_id INTEGER NOT NULL DEFAULT 0,
+            category TEXT NOT NULL DEFAULT '',
+            level TEXT NOT NULL CHECK(level IN ('all', 'replies', 'muted')),
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+            UNIQUE(user_id, post_id, category)
+        );
+
+        CREATE INDEX IF NOT EXISTS idx_subscriptions_post ON subscriptions (post_id);
+        CREATE INDEX IF NOT EXISTS idx_subscriptions_category ON subscriptions (category);
+    `)
+	if err != nil {
+		logger.Error("Failed to create subscriptions table: %v", err)
+		return nil, err
+	}
+
+	// Create Subscription Events table; every event is fanned out to one row per subscriber
+	// for notifications and digests to consume
+	_, err = DB.Exec(`
+        CREATE TABLE IF NOT EXISTS subscription_events (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            user_id INTEGER NOT NULL,
+            event_type TEXT NOT NULL,
+            actor_id INTEGER NOT NULL,
+            post_id INTEGER NOT NULL,
+            comment_id INTEGER NOT NULL DEFAULT 0,
+            reason TEXT NOT NULL,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+        );
+
+        CREATE INDEX IF NOT EXISTS idx_subscription_events_user ON subscription_events (user_id, created_at);
+    `)
+	if err != nil {
+		logger.Error("Failed to create subscription events table: %v", err)
+		return nil, err
+	}
+
+	// Create User Blocks table; a blocked user can no longer message the blocker
+	_, err = DB.Exec(`
+        CREATE TABLE IF NOT EXISTS user_blocks (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            blocker_id INTEGER NOT NULL,
+            blocked_id INTEGER NOT NULL,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (blocker_id) REFERENCES users (id) ON DELETE CASCADE,
+            FOREIGN KEY (blocked_id) REFERENCES users (id) ON DELETE CASCADE,
+            UNIQUE(blocker_id, blocked_id)
+        );
+
+        CREATE INDEX IF NOT EXISTS idx_user_blocks_blocked ON user_blocks (blocked_id);
+    `)
+	if err != nil {
+		logger.Error("Failed to create user blocks table: %v", err)
+		return nil, err
+	}
+
+	// Create private messaging tables; last_read_message_id tracks what each member has seen
+	_, err = DB.Exec(`
+        CREATE TABLE IF NOT EXISTS conversations (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            subject TEXT NOT NULL DEFAULT '',
+            created_by INTEGER NOT NULL,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE CASCADE
+        );
+
+        CREATE TABLE IF NOT EXISTS conversation_members (
+            conversation_id INTEGER NOT NULL,
+            user_id INTEGER NOT NULL,
+            last_read_message_id INTEGER NOT NULL DEFAULT 0,
+            joined_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (conversation_id, user_id),
+            FOREIGN KEY (conversation_id) REFERENCES conversations (id) ON DELETE CASCADE,
+            FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+        );
+
+        CREATE TABLE IF NOT EXISTS messages (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            conversation_id INTEGER NOT NULL,
+            sender_id INTEGER NOT NULL,
+            content TEXT NOT NULL,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (conversation_id) REFERENCES conversations (id) ON DELETE CASCADE,
+            FOREIGN KEY (sender_id) REFERENCES users (id) ON DELETE CASCADE
+        );
+
+        CREATE INDEX IF NOT EXISTS idx_conversation_members_user ON conversation_members (user_id);
+        CREATE INDEX IF NOT EXISTS idx_messages_conversation ON messages (conversation_id, id);
+    `)
+	if err != nil {
+		logger.Error("Failed to create messaging tables: %v", err)
+		return nil, err
+	}
+
+	// Create Mentions table; one row per user @mentioned in a post or comment
+	_, err = DB.Exec(`
+        CREATE TABLE IF NOT EXISTS mentions (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            source_type TEXT NOT NULL CHECK (source_type IN ('post', 'comment')),
+            source_id INTEGER NOT NULL,
+            post_id INTEGER NOT NULL,
+            user_id INTEGER NOT NULL,
+            author_id INTEGER NOT NULL,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+            FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE CASCADE,
+            UNIQUE(source_type, source_id, user_id)
+        );
+
+        CREATE INDEX IF NOT EXISTS idx_mentions_user ON mentions (user_id, created_at);
+    `)
+	if err != nil {
+		logger.Error("Failed to create mentions table: %v", err)
+		return nil, err
+	}
+
+	// Create the reputation ledger; every vote and its reversal is recorded so that a user's
+	// reputation is the sum of their entries
+	_, err = DB.Exec(`
+        CREATE TABLE IF NOT EXISTS reputation_ledger (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            user_id INTEGER NOT NULL,
+            voter_id INTEGER NOT NULL,
+            target_type TEXT NOT NULL CHECK(target_type IN ('post', 'comment')),
+            target_id INTEGER NOT NULL,
+            post_id INTEGER NOT NULL,
+            vote TEXT NOT NULL CHECK(vote IN ('like', 'dislike')),
+            reason TEXT NOT NULL CHECK(reason IN ('vote', 'unvote')),
+            delta INTEGER NOT NULL,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+            FOREIGN KEY (voter_id) REFERENCES users (id) ON DELETE CASCADE
+        );
+
+        CREATE INDEX IF NOT EXISTS idx_reputation_ledger_user ON reputation_ledger (user_id, created_at);
+        CREATE INDEX IF NOT EXISTS idx_reputation_ledger_vote ON reputation_ledger (voter_id, target_type, target_id);
+    `)
+	if err != nil {
+		logger.Error("Failed to create reputation_ledger table: %v", err)
+		return nil, err
+	}
+
+	// Create Reactions table; a user has at most one reaction on each post or comment. The
+	// reaction set is configurable, so which reactions are allowed is checked when reacting
+	_, err = DB.Exec(`
+        CREATE TABLE IF NOT EXISTS reactions (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            user_id INTEGER NOT NULL,
+            target_type TEXT NOT NULL CHECK(target_type IN ('post', 'comment')),
+            target_id INTEGER NOT NULL,
+            reaction TEXT NOT NULL,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+            UNIQUE(user_id, target_type, target_id)
+        );
+
+        CREATE INDEX IF NOT EXISTS idx_reactions_target ON reactions (target_type, target_id, reaction);
+    `)
+	if err != nil {
+		logger.Error("Failed to create reactions table: %v", err)
+		return nil, err
+	}
+
+	// Create the tables of the vote manipulation analyzer: hashed IP and device fingerprints seen
+	// for each user, and the votes flagged for moderators
+	_, err = DB.Exec(`
+        CREATE TABLE IF NOT EXISTS user_fingerprints (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            user_id INTEGER NOT NULL,
+            kind TEXT NOT NULL CHECK(kind IN ('ip', 'device')),
+            fingerprint TEXT NOT NULL,
+            first_seen DATETIME NOT NULL,
+            last_seen DATETIME NOT NULL,
+            FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+            UNIQUE(user_id, kind, fingerprint)
+        );
+
+        CREATE INDEX IF NOT EXISTS idx_user_fingerprints_fingerprint ON user_fingerprints (kind, fingerprint);
+
+        CREATE TABLE IF NOT EXISTS vote_flags (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            kind TEXT NOT NULL CHECK(kind IN ('shared_fingerprint', 'new_account_burst', 'reciprocal_voting')),
+            voter_id INTEGER NOT NULL,
+            author_id INTEGER NOT NULL,
+            votes INTEGER NOT NULL,
+            detail TEXT NOT NULL,
+            status TEXT NOT NULL DEFAULT 'open' CHECK(status IN ('open', 'dismissed', 'nullified')),
+            created_at DATETIME NOT NULL,
+            updated_at DATETIME NOT NULL,
+            FOREIGN KEY (voter_id) REFERENCES users (id) ON DELETE CASCADE,
+            FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE CASCADE,
+            UNIQUE(kind, voter_id, author_id)
+        );
+
+        CREATE INDEX IF NOT EXISTS idx_vote_flags_status ON vote_flags (status, updated_at);
+    `)
+	if err != nil {
+		logger.Error("Failed to create vote manipulation tables: %v", err)
+		return nil, err
+	}
+
+	// Apply schema changes made after the tables above were first created
+	if err := runMigrations(DB); err != nil {
+		logger.Error("Failed to migrate database: %v", err)
+		return nil, err
+	}
+
 	return DB, nil
 }
@@ -0,0 +1,315 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/config"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+	"github.com/Raymond9734/forum.git/BackEnd/ranking"
+)
+
+// migration is a schema change applied once, in order, on top of the base tables
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations must only ever be appended to; applied versions are recorded in schema_migrations
+var migrations = []migration{
+	{1, "add users.role", func(tx *sql.Tx) error {
+		return addColumn(tx, "users", "role", "TEXT NOT NULL DEFAULT 'user' CHECK(role IN ('user', 'moderator', 'admin'))")
+	}},
+	{2, "index files by uploader", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_files_uploaded_by ON files (uploaded_by)`)
+		return err
+	}},
+	{3, "add post thread states", func(tx *sql.Tx) error {
+		for _, column := range []struct{ name, definition string }{
+			{"pinned", "INTEGER NOT NULL DEFAULT 0"},
+			{"pinned_category", "TEXT NOT NULL DEFAULT ''"},
+			{"pinned_at", "DATETIME"},
+			{"locked", "INTEGER NOT NULL DEFAULT 0"},
+			{"archived", "INTEGER NOT NULL DEFAULT 0"},
+		} {
+			if err := addColumn(tx, "posts", column.name, column.definition); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_posts_pinned ON posts (pinned, pinned_category)`)
+		return err
+	}},
+	{4, "add soft delete columns", func(tx *sql.Tx) error {
+		for _, table := range []string{"posts", "comments"} {
+			if err := addColumn(tx, table, "deleted_at", "DATETIME"); err != nil {
+				return err
+			}
+			if err := addColumn(tx, table, "deleted_by", "INTEGER"); err != nil {
+				return err
+			}
+			_, err := tx.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_%s_deleted_at ON %s (deleted_at)`, table, table))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{5, "add post ranking scores", func(tx *sql.Tx) error {
+		for _, column := range []struct{ name, definition string }{
+			{"hot_score", "REAL NOT NULL DEFAULT 0"},
+			{"controversy_score", "REAL NOT NULL DEFAULT 0"},
+			{"comment_count", "INTEGER NOT NULL DEFAULT 0"},
+		} {
+			if err := addColumn(tx, "posts", column.name, column.definition); err != nil {
+				return err
+			}
+		}
+		for _, index := range []string{
+			`CREATE INDEX IF NOT EXISTS idx_posts_hot_score ON posts (hot_score)`,
+			`CREATE INDEX IF NOT EXISTS idx_posts_controversy_score ON posts (controversy_score)`,
+			`CREATE INDEX IF NOT EXISTS idx_posts_comment_count ON posts (comment_count)`,
+			`CREATE INDEX IF NOT EXISTS idx_posts_timestamp ON posts (timestamp)`,
+		} {
+			if _, err := tx.Exec(index); err != nil {
+				return err
+			}
+		}
+		return backfillRankingScores(tx)
+	}},
+	{6, "add user_blocks.kind", func(tx *sql.Tx) error {
+		return addColumn(tx, "user_blocks", "kind", "TEXT NOT NULL DEFAULT 'block' CHECK(kind IN ('block', 'mute'))")
+	}},
+	{7, "add comment quotes", func(tx *sql.Tx) error {
+		if err := addColumn(tx, "comments", "quote_id", "INTEGER"); err != nil {
+			return err
+		}
+		return addColumn(tx, "comments", "quote_text", "TEXT NOT NULL DEFAULT ''")
+	}},
+	{8, "add posts.accepted_comment_id", func(tx *sql.Tx) error {
+		return addColumn(tx, "posts", "accepted_comment_id", "INTEGER")
+	}},
+	{9, "deduplicate votes", dedupeVotes},
+	{10, "add users.reputation", func(tx *sql.Tx) error {
+		if err := addColumn(tx, "users", "reputation", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return err
+		}
+		return backfillReputation(tx)
+	}},
+	{11, "add account and post vote timestamps", func(tx *sql.Tx) error {
+		if err := addColumn(tx, "users", "created_at", "DATETIME"); err != nil {
+			return err
+		}
+		return addColumn(tx, "likes", "created_at", "DATETIME")
+	}},
+	{12, "add messages.attachment_url", func(tx *sql.Tx) error {
+		return addColumn(tx, "messages", "attachment_url", "TEXT NOT NULL DEFAULT ''")
+	}},
+}
+
+// runMigrations applies every migration that has not been recorded yet
+func runMigrations(db *sql.DB) error {
+	_, err := db.Exec(`
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version INTEGER PRIMARY KEY,
+            name TEXT NOT NULL,
+            applied_at DATETIME NOT NULL
+        );
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	applied, err := appliedVersions(context.Background(), db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", m.version, err)
+		}
+		if err := m.up(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.version, m.name, time.Now())
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", m.version, err)
+		}
+		logger.Info("Applied database migration %d: %s", m.version, m.name)
+	}
+
+	return nil
+}
+
+// PendingMigrations returns how many migrations have not been applied to db
+func PendingMigrations(ctx context.Context, db *sql.DB) (int, error) {
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, m := range migrations {
+		if !applied[m.version] {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func appliedVersions(ctx context.Context, db *sql.DB) (map[int]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// addColumn adds a column unless a previous run already created it
+func addColumn(tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return err
+	}
+	exists := false
+	for rows.Next() {
+		var (
+			cid        int
+			name, kind string
+			notNull    int
+			dflt       sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &kind, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		if name == column {
+			exists = true
+		}
+	}
+	rows.Close()
+
+	if exists {
+		return nil
+	}
+	_, err = tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	return err
+}
+
+// backfillRankingScores computes the stored ranking inputs of existing posts
+func backfillRankingScores(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		UPDATE posts SET comment_count =
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = posts.id AND c.deleted_at IS NULL)
+	`)
+	if err != nil {
+		return err
+	}
+
+	type postVotes struct {
+		id, likes, dislikes int
+		created             time.Time
+	}
+	rows, err := tx.Query(`SELECT id, COALESCE(likes, 0), COALESCE(dislikes, 0), timestamp FROM posts`)
+	if err != nil {
+		return err
+	}
+	var posts []postVotes
+	for rows.Next() {
+		var p postVotes
+		if err := rows.Scan(&p.id, &p.likes, &p.dislikes, &p.created); err != nil {
+			rows.Close()
+			return err
+		}
+		posts = append(posts, p)
+	}
+	rows.Close()
+
+	for _, p := range posts {
+		_, err := tx.Exec(`UPDATE posts SET hot_score = ?, controversy_score = ? WHERE id = ?`,
+			ranking.Hot(p.likes, p.dislikes, p.created), ranking.Controversy(p.likes, p.dislikes), p.id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dedupeVotes keeps the latest vote of each user on each post, makes (post_id, user_id) unique and
+// recounts the cached likes and dislikes of posts and comments
+func dedupeVotes(tx *sql.Tx) error {
+	for _, statement := range []string{
+		`DELETE FROM likes WHERE user_vote IS NULL
+			OR id NOT IN (SELECT MAX(id) FROM likes GROUP BY post_id, user_id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_likes_post_user ON likes (post_id, user_id)`,
+		`DELETE FROM comment_votes WHERE vote_type IS NULL`,
+		`UPDATE posts SET
+			likes = (SELECT COUNT(*) FROM likes l WHERE l.post_id = posts.id AND l.user_vote = 'like'),
+			dislikes = (SELECT COUNT(*) FROM likes l WHERE l.post_id = posts.id AND l.user_vote = 'dislike')`,
+		`UPDATE comments SET
+			likes = (SELECT COUNT(*) FROM comment_votes v WHERE v.comment_id = comments.id AND v.vote_type = 'like'),
+			dislikes = (SELECT COUNT(*) FROM comment_votes v WHERE v.comment_id = comments.id AND v.vote_type = 'dislike')`,
+	} {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return backfillRankingScores(tx)
+}
+
+// backfillReputation records the existing votes in the reputation ledger with the configured
+// weights and sums them into users.reputation; votes on one's own posts and comments count for nothing
+func backfillReputation(tx *sql.Tx) error {
+	sources := map[string]string{
+		models.VoteTargetPost: `
+			SELECT p.user_id, l.user_id, 'post', p.id, p.id, l.user_vote, 'vote', ?, CURRENT_TIMESTAMP
+			FROM likes l JOIN posts p ON p.id = l.post_id
+			WHERE l.user_vote = ? AND l.user_id != p.user_id`,
+		models.VoteTargetComment: `
+			SELECT c.user_id, v.user_id, 'comment', c.id, c.post_id, v.vote_type, 'vote', ?, COALESCE(v.timestamp, CURRENT_TIMESTAMP)
+			FROM comment_votes v JOIN comments c ON c.id = v.comment_id
+			WHERE v.vote_type = ? AND v.user_id != c.user_id`,
+	}
+	for _, weight := range models.ReputationWeights {
+		delta := config.Int(weight.Env, weight.Default)
+		if delta == 0 {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO reputation_ledger (user_id, voter_id, target_type, target_id, post_id, vote, reason, delta, created_at)
+		`+sources[weight.TargetType], delta, weight.Vote)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec(`
+		UPDATE users SET reputation =
+			(SELECT COALESCE(SUM(delta), 0) FROM reputation_ledger r WHERE r.user_id = users.id)
+	`)
+	return err
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+)
+
+func init() {
+	// Initialize the logger for tests
+	logger.Init(logger.Options{})
+}
+
+func TestDedupeVotes(t *testing.T) {
+	db, err := Init("Test")
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer db.Close()
+
+	// Recreate the state of databases from before the unique index
+	for _, statement := range []string{
+		`DROP INDEX idx_likes_post_user`,
+		`INSERT INTO posts (id, title, user_id, author, category, content, timestamp, likes, dislikes)
+			VALUES (1, 'post', 1, 'author', 'music', 'post', CURRENT_TIMESTAMP, 5, 0)`,
+		`INSERT INTO likes (post_id, user_id, user_vote) VALUES
+			(1, 1, 'like'), (1, 1, 'like'), (1, 2, 'like'), (1, 2, 'dislike'), (1, 3, NULL)`,
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("Failed to seed votes: %v", err)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := dedupeVotes(tx); err != nil {
+		tx.Rollback()
+		t.Fatalf("dedupeVotes() error = %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	var likes, dislikes, rows int
+	if err := db.QueryRow(`SELECT likes, dislikes FROM posts WHERE id = 1`).Scan(&likes, &dislikes); err != nil {
+		t.Fatalf("Failed to read counts: %v", err)
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM likes`).Scan(&rows); err != nil {
+		t.Fatalf("Failed to count votes: %v", err)
+	}
+	// The latest vote of each user wins
+	if rows != 2 || likes != 1 || dislikes != 1 {
+		t.Errorf("got %d votes, %d likes and %d dislikes, want 2, 1 and 1", rows, likes, dislikes)
+	}
+
+	if _, err := db.Exec(`INSERT INTO likes (post_id, user_id, user_vote) VALUES (1, 1, 'dislike')`); err == nil {
+		t.Errorf("a second vote of the same user on the same post should violate the unique index")
+	}
+}
+
+func TestBackfillReputation(t *testing.T) {
+	db, err := Init("Test")
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	defer db.Close()
+
+	for _, statement := range []string{
+		`INSERT INTO users (id, email, username, password) VALUES (1, 'a@example.com', 'a', ''), (2, 'b@example.com', 'b', '')`,
+		`INSERT INTO posts (id, title, user_id, author, category, content, timestamp) VALUES
+			(1, 'post', 1, 'a', 'music', 'post', CURRENT_TIMESTAMP)`,
+		`INSERT INTO comments (id, post_id, user_id, author, content) VALUES (1, 1, 2, 'b', 'comment')`,
+		// A like from b, a self-like from a, and a dislike from a on b's comment
+		`INSERT INTO likes (post_id, user_id, user_vote) VALUES (1, 2, 'like'), (1, 1, 'like')`,
+		`INSERT INTO comment_votes (comment_id, user_id, vote_type) VALUES (1, 1, 'dislike')`,
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("Failed to seed votes: %v", err)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := backfillReputation(tx); err != nil {
+		tx.Rollback()
+		t.Fatalf("backfillReputation() error = %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	for userID, want := range map[int]int{1: 10, 2: -1} {
+		var reputation int
+		if err := db.QueryRow(`SELECT reputation FROM users WHERE id = ?`, userID).Scan(&reputation); err != nil {
+			t.Fatalf("Failed to read reputation: %v", err)
+		}
+		if reputation != want {
+			t.Errorf("reputation of user %d = %d, want %d", userID, reputation, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Package events lets features react to forum activity without the handlers knowing about them.
+// Handlers publish an Event once the change is stored, together with the users it was fanned out to;
+// consumers such as notifications and digests register a Listener to receive them.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+)
+
+// Event types
+const (
+	PostCreated    = "post.created"
+	CommentCreated = "comment.created"
+	Mentioned      = "mention.created"
+)
+
+// Delivery reasons, recording why a subscriber received an event
+const (
+	ReasonThread   = "thread"   // watching all activity in the thread
+	ReasonReply    = "reply"    // someone replied to the subscriber's post or comment
+	ReasonCategory = "category" // watching all activity in one of the post's categories
+	ReasonMention  = "mention"  // the subscriber was @mentioned
+)
+
+// Event describes something that happened in the forum
+type Event struct {
+	Type      string
+	ActorID   int
+	PostID    int
+	CommentID int
+	// Categories of the post the event happened in
+	Categories []string
+	CreatedAt  time.Time
+}
+
+// Recipient is a subscriber an event was fanned out to
+type Recipient struct {
+	UserID int
+	Reason string
+}
+
+// Listener consumes published events
+type Listener func(Event, []Recipient)
+
+var (
+	mu        sync.RWMutex
+	listeners = make(map[int]Listener)
+	nextID    int
+)
+
+// Register adds a listener and returns a function that removes it again
+func Register(l Listener) (unregister func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	id := nextID
+	nextID++
+	listeners[id] = l
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(listeners, id)
+	}
+}
+
+// Publish hands the event to every registered listener in turn.
+// A listener that panics is logged and does not stop the others.
+func Publish(ev Event, recipients []Recipient) {
+	mu.RLock()
+	current := make([]Listener, 0, len(listeners))
+	for _, l := range listeners {
+		current = append(current, l)
+	}
+	mu.RUnlock()
+
+	for _, l := range current {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Error("Event listener panicked on %s: %v", ev.Type, r)
+				}
+			}()
+			l(ev, recipients)
+		}()
+	}
+}
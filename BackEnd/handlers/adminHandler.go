@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+)
+
+// StorageReportHandler lists the users consuming the most upload storage
+func StorageReportHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := 20
+		if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+
+		usage, err := controllers.GetTopStorageConsumers(db, limit)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to build storage report: %v", err)
+			WriteError(w, r, apperror.Internal("Failed to build storage report"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(usage)
+	}
+}
+
+// LogLevelHandler reports the log level on GET and changes it on PUT, so debug logs can be turned
+// on for a while without restarting
+func LogLevelHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			var req struct {
+				Level string `json:"level"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				WriteError(w, r, apperror.BadRequest("Invalid input"))
+				return
+			}
+			if err := logger.SetLevel(req.Level); err != nil {
+				WriteError(w, r, apperror.Validation(err.Error(), map[string]string{"level": "Must be debug, info, warning or error"}))
+				return
+			}
+			logger.InfoContext(r.Context(), "Log level set to %s", logger.Level())
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"level": logger.Level(),
+		})
+	}
+}
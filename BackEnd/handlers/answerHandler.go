@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+)
+
+// AcceptAnswerHandler lets the author of a Q&A post, or a moderator, accept a comment as its answer.
+// It expects the form fields post_id and comment_id; a comment_id of 0 clears the accepted answer.
+func AcceptAnswerHandler(pc *controllers.PostController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loggedIn, userID := isLoggedIn(pc.DB, r)
+		if !loggedIn {
+			WriteError(w, r, apperror.Unauthorized("Must be logged in to accept an answer"))
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to parse form data: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		postID, err := strconv.Atoi(r.FormValue("post_id"))
+		if err != nil {
+			WriteError(w, r, apperror.BadRequest("Invalid post ID"))
+			return
+		}
+		commentID, err := strconv.Atoi(r.FormValue("comment_id"))
+		if err != nil || commentID < 0 {
+			WriteError(w, r, apperror.BadRequest("Invalid comment ID"))
+			return
+		}
+
+		err = pc.AcceptAnswer(postID, commentID, userID)
+		if err != nil {
+			appErr := apperror.Internal("Failed to accept answer")
+			switch {
+			case errors.Is(err, controllers.ErrPostNotFound), errors.Is(err, controllers.ErrNotAnswer):
+				appErr = apperror.NotFound(err.Error())
+			case errors.Is(err, controllers.ErrNotQAPost):
+				appErr = apperror.BadRequest(err.Error())
+			case errors.Is(err, controllers.ErrCannotAccept):
+				appErr = apperror.Forbidden(err.Error())
+			default:
+				logger.ErrorContext(r.Context(), "Failed to accept comment %d on post %d: %v", commentID, postID, err)
+			}
+			WriteError(w, r, appErr)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{
+			"acceptedCommentId": commentID,
+		})
+	}
+}
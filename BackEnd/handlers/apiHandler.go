@@ -0,0 +1,516 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/metrics"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+	"github.com/Raymond9734/forum.git/BackEnd/ranking"
+)
+
+// APIPrefix is where version 1 of the JSON API is served
+const APIPrefix = "/api/v1"
+
+// Page sizes of API list responses
+const (
+	apiDefaultLimit = 20
+	apiMaxLimit     = 100
+)
+
+// apiParam documents a query parameter of an API operation
+type apiParam struct {
+	name        string
+	description string
+	// integer parameters are documented and parsed as such, anything else is a string
+	integer bool
+	enum    []string
+}
+
+// apiRoute is an operation of the API. The OpenAPI document is generated from the routes, so
+// everything a client can rely on is declared here
+type apiRoute struct {
+	method string
+	// path is relative to APIPrefix; a "{name}" segment matches any single segment
+	path    string
+	summary string
+	query   []apiParam
+	// auth requires a session; operations that change data also require the CSRF token
+	auth bool
+	// request is the type of the JSON body, if any
+	request interface{}
+	// response is the type of the resource returned as data, a list of them when list is set
+	response interface{}
+	list     bool
+	handle   func(a *api, w http.ResponseWriter, r *http.Request, params map[string]string)
+}
+
+var pageParams = []apiParam{
+	{name: "page", description: "page to return, starting at 1", integer: true},
+	{name: "limit", description: "items per page, at most 100", integer: true},
+}
+
+// apiRoutes lists the operations of the API
+func apiRoutes() []apiRoute {
+	return []apiRoute{
+		{
+			method: http.MethodGet, path: "/posts", summary: "List posts",
+			query: append([]apiParam{
+				{name: "sort", description: "order of the posts", enum: ranking.Sorts},
+				{name: "t", description: "period the top sort covers", enum: ranking.Periods},
+				{name: "category", description: "only posts filed under this category"},
+				{name: "author", description: "only posts by this username"},
+				{name: "filter", description: "only Q&A posts without an accepted answer", enum: []string{controllers.FilterUnanswered}},
+			}, pageParams...),
+			response: models.APIPost{}, list: true, handle: (*api).listPosts,
+		},
+		{
+			method: http.MethodGet, path: "/posts/{id}", summary: "Get a post",
+			response: models.APIPost{}, handle: (*api).getPost,
+		},
+		{
+			method: http.MethodGet, path: "/posts/{id}/comments", summary: "List the comments on a post",
+			query: append([]apiParam{
+				{name: "sort", description: "order of the comments", enum: models.CommentSorts},
+				{name: "parent", description: "list the replies to this comment instead of the top level comments", integer: true},
+			}, pageParams...),
+			response: models.APIComment{}, list: true, handle: (*api).listComments,
+		},
+		{
+			method: http.MethodGet, path: "/categories", summary: "List categories",
+			query:    pageParams,
+			response: models.APICategory{}, list: true, handle: (*api).listCategories,
+		},
+		{
+			method: http.MethodGet, path: "/users/{username}", summary: "Get a user",
+			response: models.APIUser{}, handle: (*api).getUser,
+		},
+		{
+			method: http.MethodGet, path: "/votes", summary: "List the votes of the logged-in user",
+			query: append([]apiParam{
+				{name: "targetType", description: "only votes on posts or on comments", enum: []string{models.VoteTargetPost, models.VoteTargetComment}},
+			}, pageParams...),
+			auth: true, response: models.APIVote{}, list: true, handle: (*api).listVotes,
+		},
+		{
+			method: http.MethodPost, path: "/votes", summary: "Vote on a post or comment; the same vote again removes it",
+			auth: true, request: models.APIVoteRequest{}, response: models.APIVoteResult{}, handle: (*api).castVote,
+		},
+	}
+}
+
+type api struct {
+	db     *sql.DB
+	routes []apiRoute
+}
+
+// APIHandler serves version 1 of the JSON API and its OpenAPI document under APIPrefix
+func APIHandler(db *sql.DB) http.Handler {
+	return &api{db: db, routes: apiRoutes()}
+}
+
+func (a *api) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, APIPrefix), "/")
+	if path == "/openapi.json" {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			WriteError(w, r, apperror.New(apperror.KindMethodNotAllowed, "Method not allowed"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(OpenAPISpec())
+		return
+	}
+
+	var allowed []string
+	for _, route := range a.routes {
+		params, ok := matchAPIPath(route.path, path)
+		if !ok {
+			continue
+		}
+		if route.method != r.Method {
+			allowed = append(allowed, route.method)
+			continue
+		}
+
+		if route.auth {
+			if loggedIn, _ := isLoggedIn(a.db, r); !loggedIn {
+				WriteError(w, r, apperror.Unauthorized("Must be logged in"))
+				return
+			}
+			if r.Method != http.MethodGet && !controllers.VerifyCSRFToken(a.db, r) {
+				metrics.CSRFFailures.Inc()
+				WriteError(w, r, apperror.Forbidden("Invalid CSRF token"))
+				return
+			}
+		}
+		route.handle(a, w, r, params)
+		return
+	}
+
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		WriteError(w, r, apperror.New(apperror.KindMethodNotAllowed, "Method not allowed"))
+		return
+	}
+	WriteError(w, r, apperror.NotFound("No such API resource"))
+}
+
+// matchAPIPath matches a request path against a route path, returning the values of its parameters
+func matchAPIPath(pattern, path string) (map[string]string, bool) {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternParts) != len(pathParts) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if pathParts[i] == "" {
+				return nil, false
+			}
+			params[strings.Trim(part, "{}")] = pathParts[i]
+		} else if part != pathParts[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func writeAPIData(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": data,
+	})
+}
+
+func writeAPIList(w http.ResponseWriter, data interface{}, pagination models.APIPagination) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":       data,
+		"pagination": pagination,
+	})
+}
+
+// apiQuery reads the query parameters of a request, rejecting values an enum does not allow
+func apiQuery(r *http.Request, params []apiParam) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, param := range params {
+		value := strings.TrimSpace(r.URL.Query().Get(param.name))
+		if value == "" {
+			continue
+		}
+		if param.integer {
+			if _, err := strconv.Atoi(value); err != nil {
+				return nil, errors.New(param.name + " must be an integer")
+			}
+		}
+		if len(param.enum) > 0 {
+			valid := false
+			for _, allowed := range param.enum {
+				valid = valid || value == allowed
+			}
+			if !valid {
+				return nil, errors.New(param.name + " must be one of " + strings.Join(param.enum, ", "))
+			}
+		}
+		values[param.name] = value
+	}
+	return values, nil
+}
+
+// apiPage parses the page and limit parameters
+func apiPage(query map[string]string) (page, limit int, err error) {
+	page, limit = 1, apiDefaultLimit
+	if value, ok := query["page"]; ok {
+		page, _ = strconv.Atoi(value)
+	}
+	if value, ok := query["limit"]; ok {
+		limit, _ = strconv.Atoi(value)
+	}
+	if page < 1 {
+		return 0, 0, errors.New("page must be at least 1")
+	}
+	if limit < 1 || limit > apiMaxLimit {
+		return 0, 0, errors.New("limit must be between 1 and 100")
+	}
+	return page, limit, nil
+}
+
+// paginate returns the bounds of a page within n items
+func paginate(n, page, limit int) (start, end int, pagination models.APIPagination) {
+	start = min((page-1)*limit, n)
+	end = min(start+limit, n)
+	return start, end, models.APIPagination{Page: page, Limit: limit, HasMore: end < n}
+}
+
+// routeQuery parses the query parameters and page of the route serving the request
+func (a *api) routeQuery(w http.ResponseWriter, r *http.Request) (map[string]string, int, int, bool) {
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, APIPrefix), "/")
+	var params []apiParam
+	for _, route := range a.routes {
+		if _, ok := matchAPIPath(route.path, path); ok && route.method == r.Method {
+			params = route.query
+		}
+	}
+	query, err := apiQuery(r, params)
+	if err != nil {
+		WriteError(w, r, apperror.BadRequest(err.Error()))
+		return nil, 0, 0, false
+	}
+	page, limit, err := apiPage(query)
+	if err != nil {
+		WriteError(w, r, apperror.BadRequest(err.Error()))
+		return nil, 0, 0, false
+	}
+	return query, page, limit, true
+}
+
+func apiPost(post models.Post) models.APIPost {
+	resource := models.APIPost{
+		ID: post.ID, Title: post.Title, Content: post.Content, AuthorID: post.UserID, Author: post.Author,
+		Categories: controllers.PostCategories(post.Category), Likes: post.Likes, Dislikes: post.Dislikes,
+		CommentCount: post.CommentCount, IsPinned: post.IsPinned, IsLocked: post.IsLocked,
+		IsArchived: post.IsArchived, IsQuestion: post.IsQuestion, CreatedAt: post.Timestamp,
+	}
+	if resource.Categories == nil {
+		resource.Categories = []string{}
+	}
+	if post.ImageUrl.Valid {
+		resource.ImageURL = &post.ImageUrl.String
+	}
+	if post.AcceptedCommentID != 0 {
+		resource.AcceptedAnswerID = &post.AcceptedCommentID
+	}
+	return resource
+}
+
+func apiComment(comment models.Comment) models.APIComment {
+	resource := models.APIComment{
+		ID: comment.ID, PostID: comment.PostID, AuthorID: comment.UserID, Author: comment.Author,
+		Content: comment.Content, Likes: comment.Likes, Dislikes: comment.Dislikes, ReplyCount: comment.ReplyCount,
+		IsDeleted: comment.IsDeleted, IsAccepted: comment.IsAccepted, CreatedAt: comment.Timestamp,
+	}
+	if comment.ParentID.Valid {
+		parentID := int(comment.ParentID.Int64)
+		resource.ParentID = &parentID
+	}
+	return resource
+}
+
+// apiPostByID parses the id path parameter and loads the post, writing the error response if it fails
+func (a *api) apiPostByID(w http.ResponseWriter, r *http.Request, params map[string]string) (models.Post, bool) {
+	id, err := strconv.Atoi(params["id"])
+	if err != nil || id <= 0 {
+		WriteError(w, r, apperror.BadRequest("Invalid post ID"))
+		return models.Post{}, false
+	}
+	post, err := controllers.NewPostController(a.db).GetPostByID(strconv.Itoa(id))
+	if errors.Is(err, sql.ErrNoRows) {
+		WriteError(w, r, apperror.NotFound("Post not found"))
+		return post, false
+	}
+	if err != nil {
+		logger.ErrorContext(r.Context(), "API failed to fetch post %d: %v", id, err)
+		WriteError(w, r, apperror.Internal("Failed to fetch post"))
+		return post, false
+	}
+	return post, true
+}
+
+func (a *api) listPosts(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	query, page, limit, ok := a.routeQuery(w, r)
+	if !ok {
+		return
+	}
+
+	_, userID := isLoggedIn(a.db, r)
+	posts, hasMore, err := controllers.NewPostController(a.db).ListPosts(controllers.PostQuery{
+		Sort:       query["sort"],
+		Period:     query["t"],
+		Category:   query["category"],
+		Author:     query["author"],
+		ViewerID:   userID,
+		Unanswered: query["filter"] == controllers.FilterUnanswered,
+		Offset:     (page - 1) * limit,
+		Limit:      limit,
+	})
+	if err != nil {
+		logger.ErrorContext(r.Context(), "API failed to fetch posts: %v", err)
+		WriteError(w, r, apperror.Internal("Failed to fetch posts"))
+		return
+	}
+
+	resources := make([]models.APIPost, 0, len(posts))
+	for _, post := range posts {
+		resources = append(resources, apiPost(post))
+	}
+	writeAPIList(w, resources, models.APIPagination{Page: page, Limit: limit, HasMore: hasMore})
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *api) getPost(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	post, ok := a.apiPostByID(w, r, params)
+	if !ok {
+		return
+	}
+	writeAPIData(w, apiPost(post))
+}
+
+func (a *api) listComments(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	post, ok := a.apiPostByID(w, r, params)
+	if !ok {
+		return
+	}
+	query, page, limit, ok := a.routeQuery(w, r)
+	if !ok {
+		return
+	}
+	parentID, _ := strconv.Atoi(query["parent"])
+	if parentID < 0 {
+		WriteError(w, r, apperror.BadRequest("parent must not be negative"))
+		return
+	}
+
+	_, userID := isLoggedIn(a.db, r)
+	commentPage, err := controllers.NewCommentController(a.db).GetCommentPage(post.ID, parentID, userID,
+		controllers.ParseCommentSort(query["sort"]), (page-1)*limit, limit)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "API failed to fetch comments of post %d: %v", post.ID, err)
+		WriteError(w, r, apperror.Internal("Failed to fetch comments"))
+		return
+	}
+	hidden, err := controllers.HiddenAuthors(a.db, userID)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "API failed to fetch block list: %v", err)
+		WriteError(w, r, apperror.Internal("Failed to fetch comments"))
+		return
+	}
+	comments := controllers.HideComments(commentPage.Comments, hidden)
+	controllers.MarkAcceptedAnswer(comments, post.AcceptedCommentID)
+
+	resources := make([]models.APIComment, 0, len(comments))
+	for _, comment := range comments {
+		resources = append(resources, apiComment(comment))
+	}
+	writeAPIList(w, resources, models.APIPagination{Page: page, Limit: limit, HasMore: commentPage.HasMore})
+}
+
+func (a *api) listCategories(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	_, page, limit, ok := a.routeQuery(w, r)
+	if !ok {
+		return
+	}
+
+	categories, err := controllers.NewPostController(a.db).GetCategories()
+	if err != nil {
+		logger.ErrorContext(r.Context(), "API failed to fetch categories: %v", err)
+		WriteError(w, r, apperror.Internal("Failed to fetch categories"))
+		return
+	}
+
+	start, end, pagination := paginate(len(categories), page, limit)
+	resources := make([]models.APICategory, 0, end-start)
+	for _, category := range categories[start:end] {
+		resources = append(resources, models.APICategory{
+			Name: category.Name, PostCount: category.PostCount, IsQuestion: controllers.IsQACategory(category.Name),
+		})
+	}
+	writeAPIList(w, resources, pagination)
+}
+
+func (a *api) getUser(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	profile, err := controllers.GetUserProfile(a.db, params["username"], 0)
+	if errors.Is(err, controllers.ErrUserNotFound) {
+		WriteError(w, r, apperror.NotFound("User not found"))
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(r.Context(), "API failed to fetch user %s: %v", params["username"], err)
+		WriteError(w, r, apperror.Internal("Failed to fetch user"))
+		return
+	}
+	writeAPIData(w, models.APIUser{
+		ID: profile.ID, Username: profile.Username, Role: profile.Role, Reputation: profile.Reputation,
+	})
+}
+
+func (a *api) listVotes(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	query, page, limit, ok := a.routeQuery(w, r)
+	if !ok {
+		return
+	}
+	_, userID := isLoggedIn(a.db, r)
+
+	votes, err := controllers.NewVoteController(a.db).GetUserVotes(userID, query["targetType"])
+	if err != nil {
+		logger.ErrorContext(r.Context(), "API failed to fetch votes of user %d: %v", userID, err)
+		WriteError(w, r, apperror.Internal("Failed to fetch votes"))
+		return
+	}
+
+	start, end, pagination := paginate(len(votes), page, limit)
+	resources := make([]models.APIVote, 0, end-start)
+	for _, vote := range votes[start:end] {
+		resources = append(resources, models.APIVote{TargetType: vote.TargetType, TargetID: vote.TargetID, Vote: vote.Vote})
+	}
+	writeAPIList(w, resources, pagination)
+}
+
+func (a *api) castVote(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	_, userID := isLoggedIn(a.db, r)
+
+	var req models.APIVoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, r, apperror.BadRequest("Invalid JSON body"))
+		return
+	}
+	if req.TargetType != models.VoteTargetPost && req.TargetType != models.VoteTargetComment {
+		WriteError(w, r, apperror.BadRequest("targetType must be post or comment"))
+		return
+	}
+
+	tally, err := controllers.NewVoteController(a.db).Vote(req.TargetType, req.TargetID, userID, req.Vote)
+	var repErr *controllers.ReputationRequiredError
+	switch {
+	case errors.As(err, &repErr):
+		writeReputationRequired(w, r, repErr)
+		return
+	case errors.Is(err, controllers.ErrInvalidVote):
+		WriteError(w, r, apperror.BadRequest(err.Error()))
+		return
+	case errors.Is(err, controllers.ErrVoteTargetNotFound):
+		WriteError(w, r, apperror.NotFound(err.Error()))
+		return
+	case err != nil:
+		logger.ErrorContext(r.Context(), "API failed to vote on %s %d: %v", req.TargetType, req.TargetID, err)
+		WriteError(w, r, apperror.Internal("Failed to save vote"))
+		return
+	}
+
+	metrics.ObserveVote(req.TargetType, req.Vote, tally.UserVote)
+
+	// Remember where the vote came from for the vote manipulation analyzer
+	if err := controllers.RecordFingerprints(a.db, userID, r); err != nil {
+		logger.ErrorContext(r.Context(), "Failed to record fingerprints of user %d: %v", userID, err)
+	}
+
+	writeAPIData(w, models.APIVoteResult{
+		TargetType: req.TargetType, TargetID: req.TargetID, Likes: tally.Likes, Dislikes: tally.Dislikes, Vote: tally.UserVote,
+	})
+}
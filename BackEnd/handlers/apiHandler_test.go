@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+func init() {
+	logger.Init(logger.Options{})
+}
+
+// TestAPIContract checks that the responses of the API match its OpenAPI document
+func TestAPIContract(t *testing.T) {
+	// The voter has no reputation, so dislikes are refused
+	t.Setenv("FORUM_REP_DOWNVOTE", "15")
+
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	session, csrfToken := apiTestData(t, db)
+	handler := APIHandler(db)
+
+	// The document is served as JSON and validated in its decoded form, as a client would see it
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, APIPrefix+"/openapi.json", nil))
+	var spec map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("GET /openapi.json returned invalid JSON: %v", err)
+	}
+	if spec["openapi"] != "3.0.3" {
+		t.Fatalf("openapi = %v, want 3.0.3", spec["openapi"])
+	}
+	paths := spec["paths"].(map[string]interface{})
+
+	tests := []struct {
+		method    string
+		path      string
+		operation string // path of the operation in the document
+		body      string
+		auth      bool
+		csrf      bool
+		status    int
+		check     func(t *testing.T, body map[string]interface{})
+	}{
+		{method: "GET", path: "/posts", operation: "/posts", status: 200, check: wantCount(2)},
+		{method: "GET", path: "/posts?category=go", operation: "/posts", status: 200, check: wantCount(1)},
+		{method: "GET", path: "/posts?author=nobody", operation: "/posts", status: 200, check: wantCount(0)},
+		{method: "GET", path: "/posts?author=author&limit=1&page=2", operation: "/posts", status: 200, check: wantCount(1)},
+		{method: "GET", path: "/posts?limit=1", operation: "/posts", status: 200, check: wantHasMore(true)},
+		{method: "GET", path: "/posts?limit=1&page=2", operation: "/posts", status: 200, check: wantHasMore(false)},
+		{method: "GET", path: "/posts?sort=sideways", operation: "/posts", status: 400},
+		{method: "GET", path: "/posts?limit=1000", operation: "/posts", status: 400},
+		{method: "GET", path: "/posts?page=0", operation: "/posts", status: 400},
+		{method: "GET", path: "/posts/1", operation: "/posts/{id}", status: 200, check: wantField(2, "data", "commentCount")},
+		{method: "GET", path: "/posts/abc", operation: "/posts/{id}", status: 400},
+		{method: "GET", path: "/posts/999", operation: "/posts/{id}", status: 404},
+		{method: "GET", path: "/posts/1/comments", operation: "/posts/{id}/comments", status: 200, check: wantCount(1)},
+		{method: "GET", path: "/posts/1/comments?parent=1", operation: "/posts/{id}/comments", status: 200, check: wantCount(1)},
+		{method: "GET", path: "/posts/999/comments", operation: "/posts/{id}/comments", status: 404},
+		{method: "GET", path: "/categories", operation: "/categories", status: 200, check: wantCount(2)},
+		{method: "GET", path: "/users/author", operation: "/users/{username}", status: 200},
+		{method: "GET", path: "/users/nobody", operation: "/users/{username}", status: 404},
+		{method: "GET", path: "/votes", operation: "/votes", status: 401},
+		{method: "POST", path: "/votes", operation: "/votes", body: `{"targetType":"post","targetId":1,"vote":"like"}`, auth: true, status: 403},
+		{method: "POST", path: "/votes", operation: "/votes", body: `{"targetType":"post","targetId":1,"vote":"like"}`, auth: true, csrf: true, status: 200},
+		{method: "POST", path: "/votes", operation: "/votes", body: `{"targetType":"comment","targetId":1,"vote":"like"}`, auth: true, csrf: true, status: 200},
+		{method: "POST", path: "/votes", operation: "/votes", body: `{"targetType":"post","targetId":2,"vote":"dislike"}`, auth: true, csrf: true, status: 403},
+		{method: "POST", path: "/votes", operation: "/votes", body: `{"targetType":"post","targetId":999,"vote":"like"}`, auth: true, csrf: true, status: 404},
+		{method: "POST", path: "/votes", operation: "/votes", body: `{"targetType":"post","targetId":1,"vote":"meh"}`, auth: true, csrf: true, status: 400},
+		{method: "POST", path: "/votes", operation: "/votes", body: `not json`, auth: true, csrf: true, status: 400},
+		{method: "GET", path: "/votes", operation: "/votes", auth: true, status: 200, check: wantCount(2)},
+		{method: "GET", path: "/votes?targetType=comment", operation: "/votes", auth: true, status: 200, check: wantCount(1)},
+		{method: "DELETE", path: "/posts/1", status: 405},
+		{method: "GET", path: "/nothing", status: 404},
+	}
+
+	covered := make(map[string]bool)
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, APIPrefix+tt.path, strings.NewReader(tt.body))
+			if tt.auth {
+				req.AddCookie(&http.Cookie{Name: "session_token", Value: session})
+			}
+			if tt.csrf {
+				req.Header.Set("X-CSRF-Token", csrfToken)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.status, rec.Body.String())
+			}
+			wantType := "application/json"
+			if tt.status >= 400 {
+				wantType = "application/problem+json"
+			}
+			if contentType := rec.Header().Get("Content-Type"); contentType != wantType {
+				t.Errorf("Content-Type = %q, want %s", contentType, wantType)
+			}
+			var body interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid JSON body %q: %v", rec.Body.String(), err)
+			}
+
+			schema := map[string]interface{}{"$ref": "#/components/schemas/Problem"}
+			if tt.operation != "" {
+				operation, ok := lookup(paths, tt.operation, strings.ToLower(tt.method)).(map[string]interface{})
+				if !ok {
+					t.Fatalf("the document has no %s %s operation", tt.method, tt.operation)
+				}
+				responses := operation["responses"].(map[string]interface{})
+				response, ok := responses[strconv.Itoa(tt.status)]
+				if !ok {
+					t.Fatalf("the document does not list status %d for %s %s", tt.status, tt.method, tt.operation)
+				}
+				schema = lookup(response, "content", wantType, "schema").(map[string]interface{})
+				if tt.status == http.StatusOK {
+					covered[tt.method+" "+tt.operation] = true
+				}
+			}
+			if err := validateSchema(spec, schema, body, "body"); err != nil {
+				t.Errorf("response does not match the document: %v; body %s", err, rec.Body.String())
+			}
+			if tt.check != nil {
+				tt.check(t, body.(map[string]interface{}))
+			}
+		})
+	}
+
+	for _, route := range apiRoutes() {
+		if !covered[route.method+" "+route.path] {
+			t.Errorf("no successful request covers %s %s", route.method, route.path)
+		}
+	}
+}
+
+// apiTestData creates two users, two posts, a comment with a reply and a session for the second
+// user, returning the session token and its CSRF token
+func apiTestData(t *testing.T, db *sql.DB) (string, string) {
+	t.Helper()
+	ac := controllers.NewAuthController(db)
+	for _, name := range []string{"author", "voter"} {
+		if _, err := ac.RegisterUser(name+"@example.com", name, "password123"); err != nil {
+			t.Fatalf("RegisterUser() error = %v", err)
+		}
+	}
+
+	pc := controllers.NewPostController(db)
+	for _, category := range []string{"go", "music"} {
+		if _, err := pc.InsertPost(models.Post{
+			Title: category, Author: "author", UserID: 1, Category: category, Content: "post about " + category,
+			Timestamp: time.Now(),
+		}); err != nil {
+			t.Fatalf("InsertPost() error = %v", err)
+		}
+	}
+	cc := controllers.NewCommentController(db)
+	commentID, err := cc.InsertComment(models.Comment{PostID: 1, UserID: 1, Author: "author", Content: "comment", Timestamp: time.Now()})
+	if err != nil {
+		t.Fatalf("InsertComment() error = %v", err)
+	}
+	if _, err := cc.InsertComment(models.Comment{
+		PostID: 1, UserID: 2, Author: "voter", Content: "reply", Timestamp: time.Now(),
+		ParentID: sql.NullInt64{Int64: int64(commentID), Valid: true},
+	}); err != nil {
+		t.Fatalf("InsertComment() error = %v", err)
+	}
+
+	session := "api-test-session"
+	if err := controllers.AddSession(db, session, 2, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("AddSession() error = %v", err)
+	}
+	csrfToken, err := controllers.GenerateCSRFToken(db, session)
+	if err != nil {
+		t.Fatalf("GenerateCSRFToken() error = %v", err)
+	}
+	return session, csrfToken
+}
+
+func wantCount(n int) func(t *testing.T, body map[string]interface{}) {
+	return func(t *testing.T, body map[string]interface{}) {
+		if data, _ := body["data"].([]interface{}); len(data) != n {
+			t.Errorf("got %d items, want %d", len(data), n)
+		}
+	}
+}
+
+func wantHasMore(hasMore bool) func(t *testing.T, body map[string]interface{}) {
+	return func(t *testing.T, body map[string]interface{}) {
+		if got := lookup(body, "pagination", "hasMore"); got != hasMore {
+			t.Errorf("hasMore = %v, want %v", got, hasMore)
+		}
+	}
+}
+
+func wantField(want float64, keys ...string) func(t *testing.T, body map[string]interface{}) {
+	return func(t *testing.T, body map[string]interface{}) {
+		if got := lookup(body, keys...); got != want {
+			t.Errorf("%s = %v, want %v", strings.Join(keys, "."), got, want)
+		}
+	}
+}
+
+// lookup follows keys through nested JSON objects, returning nil if one is missing
+func lookup(value interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// validateSchema checks a decoded JSON value against the subset of OpenAPI schemas the document uses
+func validateSchema(spec, schema map[string]interface{}, value interface{}, at string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved, ok := lookup(spec, strings.Split(strings.TrimPrefix(ref, "#/"), "/")...).(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: unresolved reference %s", at, ref)
+		}
+		return validateSchema(spec, resolved, value, at)
+	}
+	if value == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+		return fmt.Errorf("%s: null is not allowed", at)
+	}
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			if err := validateSchema(spec, sub.(map[string]interface{}), value, at); err != nil {
+				return err
+			}
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			found = found || allowed == value
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", at, value, enum)
+		}
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: %v is not an object", at, value)
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				return fmt.Errorf("%s: missing required property %s", at, name)
+			}
+		}
+		for name, property := range object {
+			propertySchema, ok := properties[name].(map[string]interface{})
+			if !ok {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s: unexpected property %s", at, name)
+				}
+				continue
+			}
+			if err := validateSchema(spec, propertySchema, property, at+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: %v is not an array", at, value)
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range array {
+			if err := validateSchema(spec, items, item, at+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: %v is not a string", at, value)
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				return fmt.Errorf("%s: %q is not a date-time", at, s)
+			}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%s: %v is not an integer", at, value)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: %v is not a number", at, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: %v is not a boolean", at, value)
+		}
+	}
+	return nil
+}
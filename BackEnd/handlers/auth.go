@@ -3,12 +3,15 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
 	"github.com/Raymond9734/forum.git/BackEnd/auth"
 	"github.com/Raymond9734/forum.git/BackEnd/controllers"
 	"github.com/Raymond9734/forum.git/BackEnd/database"
 	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/metrics"
 	"github.com/Raymond9734/forum.git/BackEnd/models"
 )
 
@@ -17,47 +20,37 @@ func RegisterHandler(ac *controllers.AuthController) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req models.RegisterRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			logger.Error("Failed to decode registration request: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Invalid input",
-			})
+			logger.ErrorContext(r.Context(), "Failed to decode registration request: %v", err)
+			WriteError(w, r, apperror.BadRequest("Invalid input"))
 			return
 		}
 
-		logger.Debug("Registration attempt for email: %s, username: %s", req.Email, req.Username)
+		logger.DebugContext(r.Context(), "Registration attempt for email: %s, username: %s", req.Email, req.Username)
 
-		// Validate email
+		// Validate every field so the form can mark all invalid ones at once
+		fields := make(map[string]string)
 		if !ac.IsValidEmail(req.Email) {
-			logger.Warning("Invalid email format attempted: %s", req.Email)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Invalid email format",
-			})
-			return
+			logger.WarningContext(r.Context(), "Invalid email format attempted: %s", req.Email)
+			fields["email"] = "Invalid email format"
 		}
-
-		// Validate username
 		if !ac.IsValidUsername(req.Username) {
-			logger.Warning("Invalid username format attempted: %s", req.Username)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Username must be between 3 and 20 characters and contain only letters, numbers, and underscores",
-			})
-			return
+			logger.WarningContext(r.Context(), "Invalid username format attempted: %s", req.Username)
+			fields["username"] = "Username must be between 3 and 20 characters and contain only letters, numbers, and underscores"
 		}
-
-		// Validate password
 		if !ac.IsValidPassword(req.Password) {
-			logger.Warning("Invalid password format for user: %s", req.Username)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Password must be at least 8 characters long and include uppercase, lowercase, numbers, and special characters",
-			})
+			logger.WarningContext(r.Context(), "Invalid password format for user: %s", req.Username)
+			fields["password"] = "Password must be at least 8 characters long and include uppercase, lowercase, numbers, and special characters"
+		}
+		if len(fields) > 0 {
+			// The detail repeats the first invalid field for clients that show a single message
+			detail := fields["email"]
+			if detail == "" {
+				detail = fields["username"]
+			}
+			if detail == "" {
+				detail = fields["password"]
+			}
+			WriteError(w, r, apperror.Validation(detail, fields))
 			return
 		}
 
@@ -67,17 +60,21 @@ func RegisterHandler(ac *controllers.AuthController) http.HandlerFunc {
 
 		userID, err := ac.RegisterUser(sanitizedEmail, sanitizedUsername, req.Password)
 		if err != nil {
-			logger.Error("Registration failed for user %s: %v", sanitizedUsername, err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": err.Error(),
-			})
+			logger.ErrorContext(r.Context(), "Registration failed for user %s: %v", sanitizedUsername, err)
+			if errors.Is(err, controllers.ErrAccountTaken) {
+				WriteError(w, r, apperror.Conflict(err.Error()))
+			} else {
+				WriteError(w, r, apperror.BadRequest(err.Error()))
+			}
 			return
 		}
 
+		metrics.Registrations.Inc()
 		auth.CreateSession(ac.DB, w, int(userID))
-		logger.Info("Successfully registered user: %s (ID: %d)", sanitizedUsername, userID)
+		if err := controllers.RecordFingerprints(ac.DB, int(userID), r); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to record fingerprints of user %d: %v", int(userID), err)
+		}
+		logger.InfoContext(r.Context(), "Successfully registered user: %s (ID: %d)", sanitizedUsername, userID)
 
 		w.WriteHeader(302)
 		w.Header().Set("Content-Type", "application/json")
@@ -96,30 +93,25 @@ func LoginHandler(ac *controllers.AuthController) http.HandlerFunc {
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			logger.Error("Failed to decode login request: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Invalid input",
-			})
+			logger.ErrorContext(r.Context(), "Failed to decode login request: %v", err)
+			WriteError(w, r, apperror.BadRequest("Invalid input"))
 			return
 		}
 
-		logger.Debug("Login attempt for username: %s", req.Username)
+		logger.DebugContext(r.Context(), "Login attempt for username: %s", req.Username)
 
 		user, err := ac.AuthenticateUser(req.Username, req.Password)
 		if err != nil {
-			logger.Warning("Failed login attempt for user %s: %v", req.Username, err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": err.Error(),
-			})
+			logger.WarningContext(r.Context(), "Failed login attempt for user %s: %v", req.Username, err)
+			WriteError(w, r, apperror.Unauthorized(err.Error()))
 			return
 		}
 
 		auth.CreateSession(ac.DB, w, user.ID)
-		logger.Info("Successful login for user: %s (ID: %d)", user.Username, user.ID)
+		if err := controllers.RecordFingerprints(ac.DB, user.ID, r); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to record fingerprints of user %d: %v", user.ID, err)
+		}
+		logger.InfoContext(r.Context(), "Successful login for user: %s (ID: %d)", user.Username, user.ID)
 
 		w.WriteHeader(302)
 		w.Header().Set("Content-Type", "application/json")
@@ -149,8 +141,8 @@ func isLoggedIn(db *sql.DB, r *http.Request) (bool, int) {
 func CheckLoginHandler(w http.ResponseWriter, r *http.Request) {
 	loggedIn, userID := isLoggedIn(database.GloabalDB, r)
 
-	logger.Debug("Verifying logged-in status for user ID: %d", userID)
-	logger.Info("User %d loggin status: %v", userID, loggedIn)
+	logger.DebugContext(r.Context(), "Verifying logged-in status for user ID: %d", userID)
+	logger.InfoContext(r.Context(), "User %d loggin status: %v", userID, loggedIn)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]bool{
@@ -162,7 +154,7 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	// Get the session cookie
 	cookie, err := r.Cookie("session_token")
 	if err != nil {
-		logger.Debug("Logout attempted with no active session")
+		logger.DebugContext(r.Context(), "Logout attempted with no active session")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -171,7 +163,7 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	sessionToken := cookie.Value
 	err = controllers.DeleteSession(database.GloabalDB, sessionToken)
 	if err != nil {
-		logger.Error("Failed to delete session: %v", err)
+		logger.ErrorContext(r.Context(), "Failed to delete session: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -187,7 +179,7 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 		SameSite: http.SameSiteStrictMode,
 	})
 
-	logger.Info("User successfully logged out")
+	logger.InfoContext(r.Context(), "User successfully logged out")
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+// BlockUserHandler adds a user to or removes them from the logged-in user's block list.
+// It expects the form fields username and action ("block", "mute", "unblock" or "unmute").
+func BlockUserHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loggedIn, userID := isLoggedIn(db, r)
+		if !loggedIn {
+			WriteError(w, r, apperror.Unauthorized("Must be logged in to block users"))
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to parse form data: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		targetID, err := controllers.GetUserIDByUsername(db, r.FormValue("username"))
+		if err == nil {
+			switch action := r.FormValue("action"); action {
+			case "block":
+				err = controllers.BlockUser(db, userID, targetID)
+			case "mute":
+				err = controllers.MuteUser(db, userID, targetID)
+			case "unblock", "unmute":
+				err = controllers.UnblockUser(db, userID, targetID)
+			default:
+				WriteError(w, r, apperror.BadRequest("Invalid action"))
+				return
+			}
+		}
+		if err != nil {
+			appErr := apperror.Internal("Failed to update block list")
+			switch {
+			case errors.Is(err, controllers.ErrUserNotFound):
+				appErr = apperror.NotFound(err.Error())
+			case errors.Is(err, controllers.ErrCannotBlockSelf):
+				appErr = apperror.BadRequest(err.Error())
+			default:
+				logger.ErrorContext(r.Context(), "Failed to update block list of user %d: %v", userID, err)
+			}
+			WriteError(w, r, appErr)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Block list updated",
+		})
+	}
+}
+
+// BlockedUsersPageHandler renders the logged-in user's block list
+func BlockedUsersPageHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+
+		loggedIn, userID := isLoggedIn(db, r)
+		if !loggedIn {
+			http.Redirect(w, r, "/login_Page", http.StatusSeeOther)
+			return
+		}
+
+		sessionToken, err := controllers.GetSessionToken(r)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Error getting session token: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		csrfToken, err := controllers.GenerateCSRFToken(db, sessionToken)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Error generating CSRF token: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		blocked, err := controllers.GetBlockList(db, userID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch block list for user %d: %v", userID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		funcMap := template.FuncMap{
+			"formatTime": func(t time.Time) string {
+				return t.Format("Jan 02, 2006 at 15:04")
+			},
+		}
+		tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles(
+			"./FrontEnd/templates/layout.html",
+			"./FrontEnd/templates/blocked.html",
+		)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "An error occured while rendering template %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		data := struct {
+			IsAuthenticated bool
+			CSRFToken       string
+			Blocked         []models.BlockedUser
+			UserID          int
+		}{
+			IsAuthenticated: loggedIn,
+			CSRFToken:       csrfToken,
+			Blocked:         blocked,
+			UserID:          userID,
+		}
+		if err := tmpl.ExecuteTemplate(w, "layout.html", data); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to render block list: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+}
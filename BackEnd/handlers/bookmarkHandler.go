@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+// ToggleBookmarkHandler saves or unsaves a post or comment for the logged-in user.
+// It expects the form fields type ("post" or "comment"), id and an optional folder.
+func ToggleBookmarkHandler(bc *controllers.BookmarkController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loggedIn, userID := isLoggedIn(bc.DB, r)
+		if !loggedIn {
+			WriteError(w, r, apperror.Unauthorized("Must be logged in to save posts and comments"))
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to parse form data: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		targetID, err := strconv.Atoi(r.FormValue("id"))
+		targetType := r.FormValue("type")
+		if err != nil || (targetType != models.BookmarkPost && targetType != models.BookmarkComment) {
+			WriteError(w, r, apperror.BadRequest("Invalid bookmark target"))
+			return
+		}
+
+		folder, err := controllers.NormalizeFolder(r.FormValue("folder"))
+		if err != nil {
+			WriteError(w, r, apperror.BadRequest(err.Error()))
+			return
+		}
+
+		bookmarked, err := bc.ToggleBookmark(userID, targetType, targetID, folder)
+		if errors.Is(err, controllers.ErrBookmarkTargetNotFound) {
+			WriteError(w, r, apperror.NotFound(err.Error()))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to toggle bookmark on %s %d: %v", targetType, targetID, err)
+			WriteError(w, r, apperror.Internal("Failed to update bookmark"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{
+			"bookmarked": bookmarked,
+		})
+	}
+}
+
+// MoveBookmarkHandler files a bookmark under another folder.
+// It expects the form fields id and folder; an empty folder removes it from any folder.
+func MoveBookmarkHandler(bc *controllers.BookmarkController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loggedIn, userID := isLoggedIn(bc.DB, r)
+		if !loggedIn {
+			WriteError(w, r, apperror.Unauthorized("Must be logged in to organize bookmarks"))
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to parse form data: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		bookmarkID, err := strconv.Atoi(r.FormValue("id"))
+		if err != nil {
+			WriteError(w, r, apperror.BadRequest("Invalid bookmark ID"))
+			return
+		}
+		folder, err := controllers.NormalizeFolder(r.FormValue("folder"))
+		if err != nil {
+			WriteError(w, r, apperror.BadRequest(err.Error()))
+			return
+		}
+
+		err = bc.MoveBookmark(userID, bookmarkID, folder)
+		if errors.Is(err, controllers.ErrBookmarkNotFound) {
+			WriteError(w, r, apperror.NotFound(err.Error()))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to move bookmark %d: %v", bookmarkID, err)
+			WriteError(w, r, apperror.Internal("Failed to move bookmark"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Bookmark moved",
+		})
+	}
+}
+
+// SavedPageHandler renders the logged-in user's bookmarks, filtered by ?folder= and paginated by ?page=
+func SavedPageHandler(bc *controllers.BookmarkController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+
+		loggedIn, userID := isLoggedIn(bc.DB, r)
+		if !loggedIn {
+			http.Redirect(w, r, "/login_Page", http.StatusSeeOther)
+			return
+		}
+
+		sessionToken, err := controllers.GetSessionToken(r)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Error getting session token: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		csrfToken, err := controllers.GenerateCSRFToken(bc.DB, sessionToken)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Error generating CSRF token: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		saved, err := bc.GetBookmarks(userID, r.URL.Query().Get("folder"), page)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch bookmarks for user %d: %v", userID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		funcMap := template.FuncMap{
+			"formatTime": func(t time.Time) string {
+				return t.Format("Jan 02, 2006 at 15:04")
+			},
+			"add": func(a, b int) int { return a + b },
+		}
+		tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles(
+			"./FrontEnd/templates/layout.html",
+			"./FrontEnd/templates/saved.html",
+		)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "An error occured while rendering template %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		data := struct {
+			IsAuthenticated bool
+			CSRFToken       string
+			Saved           models.BookmarkPage
+			UserID          int
+		}{
+			IsAuthenticated: loggedIn,
+			CSRFToken:       csrfToken,
+			Saved:           saved,
+			UserID:          userID,
+		}
+		if err := tmpl.ExecuteTemplate(w, "layout.html", data); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to render saved page: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+}
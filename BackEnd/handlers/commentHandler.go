@@ -3,13 +3,15 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
 	"github.com/Raymond9734/forum.git/BackEnd/controllers"
 	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/metrics"
 	"github.com/Raymond9734/forum.git/BackEnd/models"
 )
 
@@ -19,68 +21,81 @@ func CommentHandler(cCtrl *controllers.CommentController) http.HandlerFunc {
 		// Check if the user is logged in
 		loggedIn, userID := isLoggedIn(cCtrl.DB, r)
 		if !loggedIn {
-			logger.Warning("Unauthorized attempt to create comment - remote_addr: %s, method: %s, path: %s, user_id: %d",
+			logger.WarningContext(r.Context(), "Unauthorized attempt to create comment - remote_addr: %s, method: %s, path: %s, user_id: %d",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 				userID,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "Must be logged in to create a comment",
-			})
+			WriteError(w, r, apperror.Unauthorized("Must be logged in to create a comment"))
 			return
 		}
 
 		// Extract postID from the URL path
-		pathParts := strings.Split(r.URL.Path, "/")
-		if len(pathParts) < 3 || pathParts[1] != "comment" {
-			logger.Error("Invalid URL path: %s", r.URL.Path)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Invalid URL path",
-			})
+		postId, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Invalid postID: %v", err)
+			WriteError(w, r, apperror.BadRequest("Invalid postID"))
 			return
 		}
 
-		postIDStr := pathParts[2]
-		postId, err := strconv.Atoi(postIDStr)
-		if err != nil {
-			logger.Error("Invalid postID: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Invalid postID",
-			})
+		// Locked and archived threads no longer accept comments
+		if err := controllers.CheckThreadOpen(cCtrl.DB, postId); err != nil {
+			appErr := apperror.Internal("Failed to create comment")
+			switch {
+			case errors.Is(err, controllers.ErrPostNotFound):
+				appErr = apperror.NotFound("Post not found")
+			case errors.Is(err, controllers.ErrThreadLocked), errors.Is(err, controllers.ErrThreadArchived):
+				appErr = apperror.Forbidden(err.Error())
+			default:
+				logger.ErrorContext(r.Context(), "Failed to check thread state: %v", err)
+			}
+			WriteError(w, r, appErr)
 			return
 		}
 
 		// Decode the request body into a CommentRequest object
 		var commentReq models.CommentRequest
 		if err := json.NewDecoder(r.Body).Decode(&commentReq); err != nil {
-			logger.Error("Failed to decode comment request: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Invalid input",
-			})
+			logger.ErrorContext(r.Context(), "Failed to decode comment request: %v", err)
+			WriteError(w, r, apperror.BadRequest("Invalid input"))
 			return
 		}
 
 		// Validate required fields
 		if commentReq.Content == "" {
-			logger.Warning("Invalid comment creation request: missing or empty content - remote_addr: %s, method: %s, path: %s",
+			logger.WarningContext(r.Context(), "Invalid comment creation request: missing or empty content - remote_addr: %s, method: %s, path: %s",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "Content is required",
-			})
+			WriteError(w, r, apperror.BadRequest("Content is required"))
+			return
+		}
+
+		// Users cannot reply to someone who blocked them
+		if err := controllers.CheckCanReply(cCtrl.DB, userID, postId, commentReq.ParentID); err != nil {
+			appErr := apperror.Internal("Failed to create comment")
+			if errors.Is(err, controllers.ErrBlockedByAuthor) {
+				appErr = apperror.Forbidden(err.Error())
+			} else {
+				logger.ErrorContext(r.Context(), "Failed to check block list: %v", err)
+			}
+			WriteError(w, r, appErr)
+			return
+		}
+
+		// A quote-reply embeds an excerpt of the comment being replied to
+		quote, err := cCtrl.QuoteParent(commentReq.ParentID, commentReq.Quote)
+		if err != nil {
+			appErr := apperror.Internal("Failed to create comment")
+			switch {
+			case errors.Is(err, controllers.ErrQuoteWithoutParent), errors.Is(err, controllers.ErrQuoteMismatch):
+				appErr = apperror.BadRequest(err.Error())
+			default:
+				logger.ErrorContext(r.Context(), "Failed to check quote: %v", err)
+			}
+			WriteError(w, r, appErr)
 			return
 		}
 
@@ -98,19 +113,27 @@ func CommentHandler(cCtrl *controllers.CommentController) http.HandlerFunc {
 			UserVote:  sql.NullString{String: "", Valid: false},
 			Timestamp: time.Now(),
 			ParentID:  sql.NullInt64{Int64: int64(commentReq.ParentID), Valid: commentReq.ParentID != 0},
+			Quote:     quote,
 		}
 
 		// Insert the comment into the database
 		commentID, err := cCtrl.InsertComment(comment)
 		if err != nil {
-			logger.Error("Failed to insert comment: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Failed to create comment",
-			})
+			logger.ErrorContext(r.Context(), "Failed to insert comment: %v", err)
+			WriteError(w, r, apperror.Internal("Failed to create comment"))
 			return
 		}
+		metrics.CommentsCreated.Inc()
+
+		// Subscribe the commenter and fan the new comment out to subscribers;
+		// the comment is already stored, so a failure here does not fail the request
+		comment.ID = commentID
+		if _, err := controllers.NewSubscriptionController(cCtrl.DB).CommentCreated(comment); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fan out comment %d to subscribers: %v", commentID, err)
+		}
+		if _, err := controllers.NewMentionController(cCtrl.DB).SaveMentions(models.MentionSourceComment, commentID, postId, userID, comment.Content); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to record mentions in comment %d: %v", commentID, err)
+		}
 
 		// Return the created comment ID in the response
 		w.Header().Set("Content-Type", "application/json")
@@ -127,87 +150,63 @@ func DeleteCommentHandler(cCtrl *controllers.CommentController) http.HandlerFunc
 		// Check if the user is logged in
 		loggedIn, userID := isLoggedIn(cCtrl.DB, r)
 		if !loggedIn {
-			logger.Warning("Unauthorized attempt to delete comment - remote_addr: %s, method: %s, path: %s, user_id: %d",
+			logger.WarningContext(r.Context(), "Unauthorized attempt to delete comment - remote_addr: %s, method: %s, path: %s, user_id: %d",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 				userID,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "Must be logged in to delete a comment",
-			})
+			WriteError(w, r, apperror.Unauthorized("Must be logged in to delete a comment"))
 			return
 		}
 
-		commentIDStr := r.URL.Query().Get("id")
+		commentIDStr := r.PathValue("id")
 		if commentIDStr == "" {
-			logger.Warning("Missing post ID in delete request - remote_addr: %s, method: %s, path: %s",
+			logger.WarningContext(r.Context(), "Missing post ID in delete request - remote_addr: %s, method: %s, path: %s",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Post ID is required",
-			})
+			WriteError(w, r, apperror.BadRequest("Post ID is required"))
 			return
 		}
 
 		commentID, err := strconv.Atoi(commentIDStr)
 		if err != nil {
-			logger.Error("Invalid commentID: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Invalid commentID",
-			})
+			logger.ErrorContext(r.Context(), "Invalid commentID: %v", err)
+			WriteError(w, r, apperror.BadRequest("Invalid commentID"))
 			return
 		}
 
 		// Verify that the user is the author of the comment
 		isAuthor, err := cCtrl.IsCommentAuthor(commentID, userID)
 		if err != nil {
-			logger.Error("Failed to verify comment author: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Failed to verify comment author",
-			})
+			logger.ErrorContext(r.Context(), "Failed to verify comment author: %v", err)
+			WriteError(w, r, apperror.Internal("Failed to verify comment author"))
 			return
 		}
 
 		if !isAuthor {
-			logger.Warning("Unauthorized attempt to delete comment - remote_addr: %s, method: %s, path: %s, user_id: %d",
+			logger.WarningContext(r.Context(), "Unauthorized attempt to delete comment - remote_addr: %s, method: %s, path: %s, user_id: %d",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 				userID,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusForbidden)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "You are not authorized to delete this comment",
-			})
+			WriteError(w, r, apperror.Forbidden("You are not authorized to delete this comment"))
 			return
 		}
 
-		logger.Info("User %d is authorized to delete comment %d", userID, commentID)
+		logger.InfoContext(r.Context(), "User %d is authorized to delete comment %d", userID, commentID)
 		// Delete the comment
-		err = cCtrl.DeleteComment(commentID)
+		err = cCtrl.DeleteComment(commentID, userID)
 		if err != nil {
-			logger.Error("Failed to delete comment: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Failed to delete comment",
-			})
+			logger.ErrorContext(r.Context(), "Failed to delete comment: %v", err)
+			WriteError(w, r, apperror.Internal("Failed to delete comment"))
 			return
 		}
 
-		logger.Info("Comment %d deleted successfully by user %d", commentID, userID)
+		logger.InfoContext(r.Context(), "Comment %d deleted successfully by user %d", commentID, userID)
 		// Return success response
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -219,8 +218,8 @@ func DeleteCommentHandler(cCtrl *controllers.CommentController) http.HandlerFunc
 
 func UpdateCommentHandler(cc *controllers.CommentController) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Get comment ID from query parameters
-		commentID, err := strconv.Atoi(r.URL.Query().Get("id"))
+		// Get comment ID from the URL
+		commentID, err := strconv.Atoi(r.PathValue("id"))
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			return
@@ -245,27 +244,96 @@ func UpdateCommentHandler(cc *controllers.CommentController) http.HandlerFunc {
 		// Verify user is the comment author
 		isAuthor, err := cc.IsCommentAuthor(commentID, userID)
 		if err != nil {
-			logger.Error("Failed to verify comment author: %v", err)
+			logger.ErrorContext(r.Context(), "Failed to verify comment author: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
 		if !isAuthor {
-			http.Error(w, "Not authorized to edit this comment", http.StatusForbidden)
+			WriteError(w, r, apperror.Forbidden("Not authorized to edit this comment"))
 			return
 		}
 
 		// Update the comment
 		err = cc.UpdateComment(commentID, updateReq.Content)
 		if err != nil {
-			logger.Error("Failed to update comment: %v", err)
+			logger.ErrorContext(r.Context(), "Failed to update comment: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
+		// Only users newly mentioned by the edit are alerted
+		if postID, err := cc.GetCommentPostID(commentID); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to record mentions in comment %d: %v", commentID, err)
+		} else if _, err := controllers.NewMentionController(cc.DB).SaveMentions(models.MentionSourceComment, commentID, postID, userID, updateReq.Content); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to record mentions in comment %d: %v", commentID, err)
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
 			"message": "Comment updated successfully",
 		})
 	}
 }
+
+// CommentPageHandler returns a page of comments as JSON: the replies to the parent query parameter,
+// or the top level comments of the post when it is absent. It takes sort and offset parameters
+func CommentPageHandler(cc *controllers.CommentController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		postID, err := strconv.Atoi(query.Get("post"))
+		if err != nil || postID <= 0 {
+			WriteError(w, r, apperror.BadRequest("Invalid post ID"))
+			return
+		}
+		parentID, _ := strconv.Atoi(query.Get("parent"))
+		offset, _ := strconv.Atoi(query.Get("offset"))
+		if parentID < 0 || offset < 0 {
+			WriteError(w, r, apperror.BadRequest("Invalid parent or offset"))
+			return
+		}
+		limit := models.CommentsPageSize
+		if parentID != 0 {
+			limit = models.RepliesPageSize
+		}
+
+		_, userID := isLoggedIn(cc.DB, r)
+		page, err := cc.GetCommentPage(postID, parentID, userID, controllers.ParseCommentSort(query.Get("sort")), offset, limit)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch comments of post %d: %v", postID, err)
+			WriteError(w, r, apperror.Internal("Failed to fetch comments"))
+			return
+		}
+
+		// Collapse comments by users the viewer blocked or muted and mark the ones they saved
+		hidden, err := controllers.HiddenAuthors(cc.DB, userID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch block list: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		page.Comments = controllers.HideComments(page.Comments, hidden)
+		saved, err := controllers.NewBookmarkController(cc.DB).BookmarkedIDs(userID, models.BookmarkComment)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch bookmarks: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		controllers.MarkBookmarkedComments(page.Comments, saved)
+		accepted, err := controllers.AcceptedAnswerID(cc.DB, postID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch accepted answer: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		controllers.MarkAcceptedAnswer(page.Comments, accepted)
+		if err := controllers.NewReactionController(cc.DB).AddCommentReactions(page.Comments, userID); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch reactions: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(page)
+	}
+}
@@ -2,11 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/Raymond9734/forum.git/BackEnd/controllers"
 	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/metrics"
 )
 
 func CreateCommentVoteHandler(cc *controllers.CommentVotesController) http.HandlerFunc {
@@ -19,43 +21,53 @@ func CreateCommentVoteHandler(cc *controllers.CommentVotesController) http.Handl
 
 		err := r.ParseForm()
 		if err != nil {
-			logger.Error("Error while Parsing Form %v", err)
+			logger.ErrorContext(r.Context(), "Error while Parsing Form %v", err)
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
 		commentID, err := strconv.Atoi(r.FormValue("comment_id"))
 		if err != nil {
-			logger.Error("Invalid Comment Id %v", err)
+			logger.ErrorContext(r.Context(), "Invalid Comment Id %v", err)
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
 		voteType := r.FormValue("vote")
 		if voteType != "like" && voteType != "dislike" {
-			logger.Warning("Invalid Vote Type request")
+			logger.WarningContext(r.Context(), "Invalid Vote Type request")
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
-		err = cc.HandleCommentVote(commentID, userID, voteType)
+		tally, err := cc.HandleCommentVote(commentID, userID, voteType)
+		var repErr *controllers.ReputationRequiredError
+		if errors.As(err, &repErr) {
+			writeReputationRequired(w, r, repErr)
+			return
+		}
+		if errors.Is(err, controllers.ErrVoteTargetNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
 		if err != nil {
-			logger.Error("Failed to handle comment vote: %v", err)
+			logger.ErrorContext(r.Context(), "Failed to handle comment vote: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 
 			return
 		}
 
-		likes, dislikes, err := cc.GetCommentVotes(commentID)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+		metrics.ObserveVote("comment", voteType, tally.UserVote)
+
+		// Remember where the vote came from for the vote manipulation analyzer
+		if err := controllers.RecordFingerprints(cc.DB, userID, r); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to record fingerprints of user %d: %v", userID, err)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]int{
-			"likes":    likes,
-			"dislikes": dislikes,
+			"likes":    tally.Likes,
+			"dislikes": tally.Dislikes,
 		})
 	}
 }
@@ -77,7 +89,7 @@ func GetUserCommentVotesHandler(cc *controllers.CommentVotesController) http.Han
 		`
 		rows, err := cc.DB.Query(query, userID)
 		if err != nil {
-			logger.Error("Failed to fetch user comment votes: %v", err)
+			logger.ErrorContext(r.Context(), "Failed to fetch user comment votes: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -91,7 +103,7 @@ func GetUserCommentVotesHandler(cc *controllers.CommentVotesController) http.Han
 			var commentID int
 			var voteType string
 			if err := rows.Scan(&commentID, &voteType); err != nil {
-				logger.Error("Error scanning vote row: %v", err)
+				logger.ErrorContext(r.Context(), "Error scanning vote row: %v", err)
 				continue
 			}
 			userVotes[strconv.Itoa(commentID)] = voteType
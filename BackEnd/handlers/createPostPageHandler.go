@@ -19,7 +19,7 @@ func CreatePostPageHandler(w http.ResponseWriter, r *http.Request) {
 
 	sessioToken, err := controllers.GetSessionToken(r)
 	if err != nil {
-		logger.Error("Error getting session token: %s", err)
+		logger.ErrorContext(r.Context(), "Error getting session token: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -27,7 +27,7 @@ func CreatePostPageHandler(w http.ResponseWriter, r *http.Request) {
 	// Generate CSRF token
 	csrfToken, err := controllers.GenerateCSRFToken(database.GloabalDB, sessioToken)
 	if err != nil {
-		logger.Error("Error generating CSRF token: %s", err)
+		logger.ErrorContext(r.Context(), "Error generating CSRF token: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -47,14 +47,14 @@ func CreatePostPageHandler(w http.ResponseWriter, r *http.Request) {
 		"./FrontEnd/templates/post.html",
 	)
 	if err != nil {
-		logger.Error("An error Occurred While rendering Template %v", err)
+		logger.ErrorContext(r.Context(), "An error Occurred While rendering Template %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	err = tmpl.Execute(w, data)
 	if err != nil {
-		logger.Error("An error Occurred While rendering Template %v", err)
+		logger.ErrorContext(r.Context(), "An error Occurred While rendering Template %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
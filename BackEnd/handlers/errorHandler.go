@@ -1,9 +1,13 @@
 package handlers
 
 import (
-	"fmt"
+	"encoding/json"
 	"html/template"
 	"net/http"
+	"strings"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
 )
 
 // ErrorPageData holds the data for the error page template
@@ -17,7 +21,7 @@ type ErrorPageData struct {
 func ServeErrorPage(w http.ResponseWriter, errorCode int, errorTitle, errorMessage string) {
 	tmpl, err := template.ParseFiles("FrontEnd/templates/errorPage.html")
 	if err != nil {
-		fmt.Println(err)
+		logger.Error("Failed to parse the error page: %v", err)
 		return
 	}
 
@@ -32,7 +36,67 @@ func ServeErrorPage(w http.ResponseWriter, errorCode int, errorTitle, errorMessa
 
 	// Execute template without writing status
 	if err := tmpl.Execute(w, data); err != nil {
-		fmt.Println(err)
+		logger.Error("Failed to render the error page: %v", err)
+		return
+	}
+}
+
+// WriteError answers the request with err, rendering the error page for browser navigation and
+// application/problem+json for scripts and API clients. Errors that are not application errors are
+// logged and answered as internal errors
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := apperror.From(err)
+	if appErr.Err != nil {
+		logger.ErrorContext(r.Context(), "Request failed - method: %s, path: %s, error: %v", r.Method, r.URL.Path, appErr)
+	}
+	status := appErr.Status()
+
+	if WantsHTML(r) {
+		title, message := http.StatusText(status), appErr.Detail
+		switch status {
+		case http.StatusNotFound:
+			title = "Page Not Found"
+			if message == "" {
+				message = "Oops! The page you're looking for doesn't exist."
+			}
+		case http.StatusMethodNotAllowed:
+			if message == "" {
+				message = "Oops! The Method Used is Not Allowed"
+			}
+		case http.StatusInternalServerError:
+			if message == "" {
+				message = "Something went wrong."
+			}
+		default:
+			if message == "" {
+				message = "An unexpected error occurred."
+			}
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(status)
+		ServeErrorPage(w, status, title, message)
 		return
 	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(appErr.Problem(r.URL.Path))
+}
+
+// WantsHTML reports whether a request prefers an HTML page to JSON, as browser navigation does.
+// API requests and scripts, which fetch without asking for HTML, get JSON
+func WantsHTML(r *http.Request) bool {
+	if strings.HasPrefix(r.URL.Path, "/api/") || r.Header.Get("X-Requested-With") == "XMLHttpRequest" {
+		return false
+	}
+	// The first media type the client names decides
+	for _, mediaRange := range strings.Split(r.Header.Get("Accept"), ",") {
+		switch strings.TrimSpace(strings.Split(mediaRange, ";")[0]) {
+		case "text/html", "application/xhtml+xml":
+			return true
+		case "application/json", "application/problem+json":
+			return false
+		}
+	}
+	return false
 }
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		header      map[string]string
+		err         error
+		status      int
+		contentType string
+		problem     map[string]interface{}
+	}{
+		{
+			name:        "browser navigation gets the error page",
+			path:        "/posts/9",
+			header:      map[string]string{"Accept": "text/html,application/xhtml+xml,*/*;q=0.8"},
+			err:         apperror.NotFound("Post not found"),
+			status:      http.StatusNotFound,
+			contentType: "text/html",
+		},
+		{
+			name:        "fetch gets a problem",
+			path:        "/posts/9",
+			header:      map[string]string{"Accept": "*/*"},
+			err:         apperror.NotFound("Post not found"),
+			status:      http.StatusNotFound,
+			contentType: "application/problem+json",
+			problem: map[string]interface{}{
+				"type": "urn:forum:problem:not-found", "title": "Not Found", "status": float64(404),
+				"detail": "Post not found", "instance": "/posts/9",
+			},
+		},
+		{
+			name:        "XHR gets a problem even when accepting HTML",
+			path:        "/login",
+			header:      map[string]string{"Accept": "text/html", "X-Requested-With": "XMLHttpRequest"},
+			err:         apperror.Unauthorized("Invalid credentials"),
+			status:      http.StatusUnauthorized,
+			contentType: "application/problem+json",
+			problem: map[string]interface{}{
+				"type": "urn:forum:problem:unauthorized", "title": "Unauthorized", "status": float64(401),
+				"detail": "Invalid credentials", "instance": "/login",
+			},
+		},
+		{
+			name:        "API requests always get a problem",
+			path:        "/api/v1/posts",
+			header:      map[string]string{"Accept": "text/html"},
+			err:         apperror.Validation("Title is required", map[string]string{"title": "Title is required"}),
+			status:      http.StatusUnprocessableEntity,
+			contentType: "application/problem+json",
+			problem: map[string]interface{}{
+				"type": "urn:forum:problem:validation", "title": "Unprocessable Entity", "status": float64(422),
+				"detail": "Title is required", "instance": "/api/v1/posts",
+				"errors": map[string]interface{}{"title": "Title is required"},
+			},
+		},
+		{
+			name:        "extensions are members of the problem",
+			path:        "/vote",
+			err:         apperror.Forbidden("Not enough reputation").With("required", 15),
+			status:      http.StatusForbidden,
+			contentType: "application/problem+json",
+			problem: map[string]interface{}{
+				"type": "urn:forum:problem:forbidden", "title": "Forbidden", "status": float64(403),
+				"detail": "Not enough reputation", "instance": "/vote", "required": float64(15),
+			},
+		},
+		{
+			name:        "other errors do not leak their cause",
+			path:        "/messages",
+			err:         errors.New("database is locked"),
+			status:      http.StatusInternalServerError,
+			contentType: "application/problem+json",
+			problem: map[string]interface{}{
+				"type": "urn:forum:problem:internal", "title": "Internal Server Error", "status": float64(500),
+				"detail": "Something went wrong.", "instance": "/messages",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			WriteError(rec, req, tt.err)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if contentType := rec.Header().Get("Content-Type"); contentType != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", contentType, tt.contentType)
+			}
+			if tt.problem == nil {
+				return
+			}
+
+			var problem map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("invalid problem %q: %v", rec.Body.String(), err)
+			}
+			if len(problem) != len(tt.problem) {
+				t.Errorf("problem = %v, want %v", problem, tt.problem)
+			}
+			for key, want := range tt.problem {
+				got, _ := json.Marshal(problem[key])
+				wanted, _ := json.Marshal(want)
+				if string(got) != string(wanted) {
+					t.Errorf("%s = %s, want %s", key, got, wanted)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/storage"
+)
+
+// shuttingDown is set once the server starts shutting down, failing readiness so load balancers
+// stop sending requests while the ones in flight finish
+var shuttingDown atomic.Bool
+
+// BeginShutdown makes the readiness check fail from now on
+func BeginShutdown() {
+	shuttingDown.Store(true)
+}
+
+// CheckResult is the outcome of one readiness check
+type CheckResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"durationMs"`
+}
+
+// Readiness is the body of a readiness response
+type Readiness struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// HealthHandler reports that the process is alive and serving requests
+func HealthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(map[string]string{
+			"status": "ok",
+		})
+	}
+}
+
+// ReadinessHandler reports whether the server can take requests: the database answers within
+// timeout and has every migration applied, upload storage is writable and the server is not
+// shutting down. It answers 503 when any check fails
+func ReadinessHandler(db *sql.DB, timeout time.Duration) http.HandlerFunc {
+	checks := map[string]func(ctx context.Context) error{
+		"shutdown": func(ctx context.Context) error {
+			if shuttingDown.Load() {
+				return errors.New("server is shutting down")
+			}
+			return nil
+		},
+		"database": func(ctx context.Context) error {
+			return db.PingContext(ctx)
+		},
+		"migrations": func(ctx context.Context) error {
+			pending, err := database.PendingMigrations(ctx, db)
+			if err != nil {
+				return err
+			}
+			if pending > 0 {
+				return fmt.Errorf("%d migrations pending", pending)
+			}
+			return nil
+		},
+		"storage": func(ctx context.Context) error {
+			return storage.CheckWritable(ctx, storage.Default)
+		},
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		readiness := Readiness{Status: "ok", Checks: make(map[string]CheckResult, len(checks))}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for name, check := range checks {
+			wg.Add(1)
+			go func(name string, check func(ctx context.Context) error) {
+				defer wg.Done()
+				start := time.Now()
+				err := runCheck(ctx, check)
+				result := CheckResult{Status: "ok", DurationMS: time.Since(start).Milliseconds()}
+				if err != nil {
+					result.Status, result.Error = "failing", err.Error()
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				readiness.Checks[name] = result
+				if err != nil {
+					readiness.Status = "unavailable"
+				}
+			}(name, check)
+		}
+		wg.Wait()
+
+		status := http.StatusOK
+		if readiness.Status != "ok" {
+			status = http.StatusServiceUnavailable
+			logger.WarningContext(r.Context(), "Readiness check failed: %+v", readiness.Checks)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(readiness)
+	}
+}
+
+// runCheck runs check, giving up when ctx ends even if the check does not watch ctx
+func runCheck(ctx context.Context, check func(ctx context.Context) error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/storage"
+)
+
+func TestReadiness(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	defaultStorage := storage.Default
+	defer func() {
+		storage.Default = defaultStorage
+		shuttingDown.Store(false)
+	}()
+	storage.Default = storage.NewLocalStorage(t.TempDir(), []byte("key"))
+	handler := ReadinessHandler(db, time.Second)
+
+	probe := func() (int, Readiness) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var readiness Readiness
+		if err := json.Unmarshal(rec.Body.Bytes(), &readiness); err != nil {
+			t.Fatalf("invalid readiness %q: %v", rec.Body.String(), err)
+		}
+		return rec.Code, readiness
+	}
+
+	status, readiness := probe()
+	if status != http.StatusOK || readiness.Status != "ok" {
+		t.Fatalf("ready server answered %d %+v", status, readiness)
+	}
+	for _, name := range []string{"database", "migrations", "storage", "shutdown"} {
+		if readiness.Checks[name].Status != "ok" {
+			t.Errorf("check %s = %+v, want ok", name, readiness.Checks[name])
+		}
+	}
+
+	// Storage that cannot be written fails only its own check
+	storage.Default = nil
+	status, readiness = probe()
+	if status != http.StatusServiceUnavailable || readiness.Checks["storage"].Status != "failing" || readiness.Checks["database"].Status != "ok" {
+		t.Errorf("without storage answered %d %+v", status, readiness)
+	}
+	storage.Default = storage.NewLocalStorage(t.TempDir(), []byte("key"))
+
+	// Readiness fails as soon as shutdown begins
+	BeginShutdown()
+	status, readiness = probe()
+	if status != http.StatusServiceUnavailable || readiness.Checks["shutdown"].Error != "server is shutting down" {
+		t.Errorf("shutting down server answered %d %+v", status, readiness)
+	}
+
+	// Liveness does not depend on any of it
+	rec := httptest.NewRecorder()
+	HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "{\"status\":\"ok\"}\n" {
+		t.Errorf("/healthz answered %d %q", rec.Code, rec.Body.String())
+	}
+}
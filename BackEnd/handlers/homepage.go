@@ -10,6 +10,7 @@ import (
 	"github.com/Raymond9734/forum.git/BackEnd/controllers"
 	"github.com/Raymond9734/forum.git/BackEnd/logger"
 	"github.com/Raymond9734/forum.git/BackEnd/models"
+	"github.com/Raymond9734/forum.git/BackEnd/ranking"
 )
 
 type HomePageHandler struct {
@@ -28,24 +29,53 @@ func (h *HomePageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if loggedIn {
 		sessionToken, err := controllers.GetSessionToken(r)
 		if err != nil {
-			logger.Error("Error getting session token: %s", err)
+			logger.ErrorContext(r.Context(), "Error getting session token: %s", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 		// Generate CSRF token for the session
 		csrfToken, err = controllers.GenerateCSRFToken(h.db, sessionToken)
 		if err != nil {
-			logger.Error("Error generating CSRF token: %V", err)
+			logger.ErrorContext(r.Context(), "Error generating CSRF token: %V", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 	}
 	// Create a PostController instance using the handler's db
 	postController := controllers.NewPostController(h.db)
-	// Fetch posts from the database using the controller
-	posts, err := postController.GetAllPosts()
+	// Fetch posts from the database using the controller, in the requested order
+	sort := ranking.ParseSort(r.URL.Query().Get("sort"))
+	period := ranking.ParsePeriod(r.URL.Query().Get("t"))
+	// The category page lists only the posts filed under it; the sort links keep it
+	category := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("category")))
+	posts, err := postController.GetRankedPosts(sort, period, category)
 	if err != nil {
-		logger.Error("Failed to fetch Posts %v", err)
+		logger.ErrorContext(r.Context(), "Failed to fetch Posts %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Hide posts by users the viewer blocked or muted
+	hidden, err := controllers.HiddenAuthors(h.db, userID)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to fetch block list for user %d: %v", userID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	posts = controllers.FilterHiddenPosts(posts, hidden)
+
+	// The unanswered filter keeps Q&A posts that have no accepted answer yet
+	filter := r.URL.Query().Get("filter")
+	if filter == controllers.FilterUnanswered {
+		posts = controllers.UnansweredPosts(posts)
+	} else {
+		filter = ""
+	}
+
+	// Look up which posts the viewer has saved
+	bookmarked, err := controllers.NewBookmarkController(h.db).BookmarkedIDs(userID, models.BookmarkPost)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to fetch bookmarks for user %d: %v", userID, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -54,11 +84,12 @@ func (h *HomePageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	commentController := controllers.NewCommentController(h.db)
 	for i := range posts {
 		posts[i].IsAuthor = loggedIn && posts[i].UserID == userID
+		posts[i].IsBookmarked = bookmarked[posts[i].ID]
 
 		// Fetch total comment count including replies
 		commentCount, err := commentController.GetCommentCountByPostID(posts[i].ID)
 		if err != nil {
-			logger.Error("Failed to fetch comment count for post %d: %v", posts[i].ID, err)
+			logger.ErrorContext(r.Context(), "Failed to fetch comment count for post %d: %v", posts[i].ID, err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -71,8 +102,9 @@ func (h *HomePageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		"formatTime": func(t time.Time) string {
 			return t.Format("Jan 02, 2006 at 15:04")
 		},
-		"split": strings.Split,
-		"trim":  strings.TrimSpace,
+		"split":    strings.Split,
+		"trim":     strings.TrimSpace,
+		"mentions": LinkMentions,
 	}
 
 	// Create template with function map
@@ -89,11 +121,23 @@ func (h *HomePageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		CSRFToken       string
 		Posts           []models.Post
 		UserID          int
+		Sort            string
+		Period          string
+		Sorts           []string
+		Periods         []string
+		Filter          string
+		Category        string
 	}{
 		IsAuthenticated: loggedIn,
 		CSRFToken:       csrfToken,
 		Posts:           posts,
 		UserID:          userID,
+		Sort:            sort,
+		Period:          period,
+		Sorts:           ranking.Sorts,
+		Periods:         ranking.Periods,
+		Filter:          filter,
+		Category:        category,
 	}
 	// Execute template with data
 	err = tmpl.ExecuteTemplate(w, "layout.html", data)
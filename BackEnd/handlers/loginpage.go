@@ -17,14 +17,14 @@ func LoginPageHandler(w http.ResponseWriter, r *http.Request) {
 
 	tmp, err := template.ParseFiles("FrontEnd/templates/login.html")
 	if err != nil {
-		logger.Error("Failed to parse login template: %v", err)
+		logger.ErrorContext(r.Context(), "Failed to parse login template: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	logger.Debug("Executing login template")
+	logger.DebugContext(r.Context(), "Executing login template")
 	if err := tmp.Execute(w, nil); err != nil {
-		logger.Error("Failed to execute login template: %v", err)
+		logger.ErrorContext(r.Context(), "Failed to execute login template: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+// profilePostLimit is how many recent posts a profile page lists
+const profilePostLimit = 20
+
+// profileLedgerLimit is how many recent reputation changes users see on their own profile
+const profileLedgerLimit = 20
+
+// LinkMentions HTML-escapes content and turns every @username into a link to the user's profile.
+// Templates use it as the "mentions" function to print post and comment content
+func LinkMentions(content string) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range controllers.FindMentions(content) {
+		b.WriteString(template.HTMLEscapeString(content[last:span[0]]))
+		name := content[span[0]+1 : span[1]]
+		b.WriteString(`<a href="/profile?username=`)
+		b.WriteString(url.QueryEscape(name))
+		b.WriteString(`" class="mention">@`)
+		b.WriteString(name)
+		b.WriteString(`</a>`)
+		last = span[1]
+	}
+	b.WriteString(template.HTMLEscapeString(content[last:]))
+	return b.String()
+}
+
+// SuggestUsernamesHandler returns the usernames starting with the prefix query parameter
+// for the @mention autocomplete
+func SuggestUsernamesHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loggedIn, _ := isLoggedIn(db, r)
+		if !loggedIn {
+			WriteError(w, r, apperror.Unauthorized("Must be logged in to look up users"))
+			return
+		}
+
+		names, err := controllers.SuggestUsernames(db, r.URL.Query().Get("prefix"), models.MaxUsernameSuggestions)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to suggest usernames: %v", err)
+			WriteError(w, r, apperror.Internal("Failed to look up users"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]string{
+			"usernames": names,
+		})
+	}
+}
+
+// ProfilePageHandler renders the profile page of the user named by the username query parameter
+func ProfilePageHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loggedIn, userID := isLoggedIn(db, r)
+		if !loggedIn {
+			http.Redirect(w, r, "/login_Page", http.StatusSeeOther)
+			return
+		}
+
+		profile, err := controllers.GetUserProfile(db, r.URL.Query().Get("username"), profilePostLimit)
+		if errors.Is(err, controllers.ErrUserNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch profile: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// Users see the reputation changes behind their own score
+		isSelf := profile.ID == userID
+		var ledger []models.ReputationEntry
+		if isSelf {
+			ledger, err = controllers.GetReputationLedger(db, userID, profileLedgerLimit)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "Failed to fetch reputation ledger: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		sessionToken, err := controllers.GetSessionToken(r)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Error getting session token: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		csrfToken, err := controllers.GenerateCSRFToken(db, sessionToken)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Error generating CSRF token: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		funcMap := template.FuncMap{
+			"formatTime": func(t time.Time) string {
+				return t.Format("Jan 02, 2006 at 15:04")
+			},
+		}
+		tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles(
+			"./FrontEnd/templates/layout.html",
+			"./FrontEnd/templates/profile.html",
+		)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "An error occured while rendering template %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		data := struct {
+			IsAuthenticated bool
+			CSRFToken       string
+			Profile         models.UserProfile
+			IsSelf          bool
+			UserID          int
+			Privileges      []models.UnlockedPrivilege
+			Ledger          []models.ReputationEntry
+		}{
+			IsAuthenticated: loggedIn,
+			CSRFToken:       csrfToken,
+			Profile:         profile,
+			IsSelf:          isSelf,
+			UserID:          userID,
+			Privileges:      controllers.UserPrivileges(profile.Reputation),
+			Ledger:          ledger,
+		}
+		if err := tmpl.ExecuteTemplate(w, "layout.html", data); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to render profile: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+}
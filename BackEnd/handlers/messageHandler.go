@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+// writeMessageError maps messaging errors to application errors
+func writeMessageError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	switch {
+	case errors.Is(err, controllers.ErrConversationNotFound), errors.Is(err, controllers.ErrUserNotFound):
+		WriteError(w, r, apperror.NotFound(err.Error()))
+	case errors.Is(err, controllers.ErrRecipientBlocked):
+		WriteError(w, r, apperror.Forbidden(err.Error()))
+	case errors.Is(err, controllers.ErrNoRecipients), errors.Is(err, controllers.ErrTooManyRecipients),
+		errors.Is(err, controllers.ErrEmptyMessage), errors.Is(err, controllers.ErrMessageTooLong),
+		errors.Is(err, controllers.ErrSubjectTooLong):
+		WriteError(w, r, apperror.BadRequest(err.Error()))
+	default:
+		WriteError(w, r, apperror.Internal(fallback).Wrap(err))
+	}
+}
+
+// writeMessageUnauthorized rejects messaging requests from logged-out users
+func writeMessageUnauthorized(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, apperror.Unauthorized("Must be logged in to use private messages"))
+}
+
+// StartConversationHandler sends a first message to one or more users.
+// It expects the form fields to (comma separated usernames), an optional subject and content.
+func StartConversationHandler(mc *controllers.MessageController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loggedIn, userID := isLoggedIn(mc.DB, r)
+		if !loggedIn {
+			writeMessageUnauthorized(w, r)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to parse form data: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conversationID, err := mc.StartConversation(userID, strings.Split(r.FormValue("to"), ","), r.FormValue("subject"), r.FormValue("content"))
+		if err != nil {
+			writeMessageError(w, r, err, "Failed to send message")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]int{
+			"conversationID": conversationID,
+		})
+	}
+}
+
+// SendMessageHandler replies in an existing conversation.
+// It expects the form fields conversation_id and content, and accepts a multipart form with an
+// attachment file that only the members of the conversation can fetch.
+func SendMessageHandler(mc *controllers.MessageController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loggedIn, userID := isLoggedIn(mc.DB, r)
+		if !loggedIn {
+			writeMessageUnauthorized(w, r)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, controllers.MaxUploadRequestSize)
+		if err := r.ParseMultipartForm(controllers.MaxUploadRequestSize); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			logger.ErrorContext(r.Context(), "Failed to parse form data: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conversationID, err := strconv.Atoi(r.FormValue("conversation_id"))
+		if err != nil {
+			WriteError(w, r, apperror.BadRequest("Invalid conversation ID"))
+			return
+		}
+
+		// Plain form posts carry no attachment
+		var attachment string
+		if r.MultipartForm != nil {
+			attachment, err = controllers.UploadPrivateFile(mc.DB, r, "attachment", userID)
+			var quotaErr *controllers.QuotaExceededError
+			if errors.As(err, &quotaErr) {
+				writeQuotaExceeded(w, r, quotaErr)
+				return
+			}
+			if err != nil {
+				WriteError(w, r, apperror.Internal("Failed to save file"))
+				return
+			}
+		}
+
+		messageID, err := mc.SendMessage(conversationID, userID, r.FormValue("content"), attachment)
+		if err != nil {
+			writeMessageError(w, r, err, "Failed to send message")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]int{
+			"messageID": messageID,
+		})
+	}
+}
+
+// ConversationsHandler returns the logged-in user's conversations as JSON
+func ConversationsHandler(mc *controllers.MessageController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loggedIn, userID := isLoggedIn(mc.DB, r)
+		if !loggedIn {
+			writeMessageUnauthorized(w, r)
+			return
+		}
+
+		conversations, err := mc.GetConversations(userID)
+		if err != nil {
+			writeMessageError(w, r, err, "Failed to fetch conversations")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(conversations)
+	}
+}
+
+// MessageHistoryHandler returns a page of a conversation's messages as JSON.
+// The query parameter id selects the conversation and before pages back through older messages.
+func MessageHistoryHandler(mc *controllers.MessageController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loggedIn, userID := isLoggedIn(mc.DB, r)
+		if !loggedIn {
+			writeMessageUnauthorized(w, r)
+			return
+		}
+
+		conversationID, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			WriteError(w, r, apperror.BadRequest("Invalid conversation ID"))
+			return
+		}
+		before, _ := strconv.Atoi(r.URL.Query().Get("before"))
+
+		messages, err := mc.GetMessages(conversationID, userID, before)
+		if err != nil {
+			writeMessageError(w, r, err, "Failed to fetch messages")
+			return
+		}
+		if before == 0 {
+			if err := mc.MarkRead(conversationID, userID); err != nil {
+				logger.ErrorContext(r.Context(), "Failed to mark conversation %d read: %v", conversationID, err)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(messages)
+	}
+}
+
+// UnreadMessagesHandler returns the logged-in user's number of unread messages as JSON
+func UnreadMessagesHandler(mc *controllers.MessageController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loggedIn, userID := isLoggedIn(mc.DB, r)
+		if !loggedIn {
+			writeMessageUnauthorized(w, r)
+			return
+		}
+
+		unread, err := mc.UnreadCount(userID)
+		if err != nil {
+			writeMessageError(w, r, err, "Failed to count unread messages")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{
+			"unread": unread,
+		})
+	}
+}
+
+// MessagesPageHandler renders the logged-in user's inbox; ?to= prefills the recipient of a new message
+func MessagesPageHandler(mc *controllers.MessageController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+
+		loggedIn, userID := isLoggedIn(mc.DB, r)
+		if !loggedIn {
+			http.Redirect(w, r, "/login_Page", http.StatusSeeOther)
+			return
+		}
+		csrfToken, ok := pageCSRFToken(w, r, mc)
+		if !ok {
+			return
+		}
+
+		conversations, err := mc.GetConversations(userID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch conversations for user %d: %v", userID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		data := struct {
+			IsAuthenticated bool
+			CSRFToken       string
+			Conversations   []models.Conversation
+			To              string
+			UserID          int
+		}{
+			IsAuthenticated: loggedIn,
+			CSRFToken:       csrfToken,
+			Conversations:   conversations,
+			To:              r.URL.Query().Get("to"),
+			UserID:          userID,
+		}
+		renderMessagesTemplate(w, r, "messages.html", data)
+	}
+}
+
+// ConversationPageHandler renders one of the logged-in user's conversations and marks it read
+func ConversationPageHandler(mc *controllers.MessageController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+
+		loggedIn, userID := isLoggedIn(mc.DB, r)
+		if !loggedIn {
+			http.Redirect(w, r, "/login_Page", http.StatusSeeOther)
+			return
+		}
+		conversationID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		csrfToken, ok := pageCSRFToken(w, r, mc)
+		if !ok {
+			return
+		}
+
+		conversation, err := mc.GetConversation(conversationID, userID)
+		if errors.Is(err, controllers.ErrConversationNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch conversation %d: %v", conversationID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		messages, err := mc.GetMessages(conversationID, userID, 0)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch messages of conversation %d: %v", conversationID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if err := mc.MarkRead(conversationID, userID); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to mark conversation %d read: %v", conversationID, err)
+		}
+
+		data := struct {
+			IsAuthenticated bool
+			CSRFToken       string
+			Conversation    models.Conversation
+			Messages        []models.Message
+			HasOlder        bool
+			UserID          int
+		}{
+			IsAuthenticated: loggedIn,
+			CSRFToken:       csrfToken,
+			Conversation:    conversation,
+			Messages:        messages,
+			HasOlder:        len(messages) == models.MessagesPageSize,
+			UserID:          userID,
+		}
+		renderMessagesTemplate(w, r, "conversation.html", data)
+	}
+}
+
+// pageCSRFToken generates the CSRF token embedded in the messaging pages
+func pageCSRFToken(w http.ResponseWriter, r *http.Request, mc *controllers.MessageController) (string, bool) {
+	sessionToken, err := controllers.GetSessionToken(r)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Error getting session token: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return "", false
+	}
+	csrfToken, err := controllers.GenerateCSRFToken(mc.DB, sessionToken)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Error generating CSRF token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return "", false
+	}
+	return csrfToken, true
+}
+
+// renderMessagesTemplate renders one of the messaging pages inside the layout
+func renderMessagesTemplate(w http.ResponseWriter, r *http.Request, page string, data interface{}) {
+	funcMap := template.FuncMap{
+		"formatTime": func(t time.Time) string {
+			return t.Format("Jan 02, 2006 at 15:04")
+		},
+	}
+	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles(
+		"./FrontEnd/templates/layout.html",
+		"./FrontEnd/templates/"+page,
+	)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "An error occured while rendering template %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err := tmpl.ExecuteTemplate(w, "layout.html", data); err != nil {
+		logger.ErrorContext(r.Context(), "Failed to render %s: %v", page, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
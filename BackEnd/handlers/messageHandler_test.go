@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+	"github.com/Raymond9734/forum.git/BackEnd/storage"
+)
+
+func TestMessageAttachments(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	defaultStorage := storage.Default
+	defer func() { storage.Default = defaultStorage }()
+	storage.Default = storage.NewLocalStorage(t.TempDir(), []byte("key"))
+
+	ac := controllers.NewAuthController(db)
+	for _, name := range []string{"alice", "bob", "eve"} {
+		if _, err := ac.RegisterUser(name+"@example.com", name, "password123"); err != nil {
+			t.Fatalf("RegisterUser() error = %v", err)
+		}
+	}
+	for userID, session := range map[int]string{1: "alice-session", 3: "eve-session"} {
+		if err := controllers.AddSession(db, session, userID, time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("AddSession() error = %v", err)
+		}
+	}
+	mc := controllers.NewMessageController(db)
+	conversationID, err := mc.StartConversation(1, []string{"bob"}, "", "hi")
+	if err != nil {
+		t.Fatalf("StartConversation() error = %v", err)
+	}
+
+	send := func(contentType string, body io.Reader) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/messages/send", body)
+		req.Header.Set("Content-Type", contentType)
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: "alice-session"})
+		rec := httptest.NewRecorder()
+		SendMessageHandler(mc).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// A plain form reply still works without an attachment
+	form := url.Values{"conversation_id": {strconv.Itoa(conversationID)}, "content": {"see attached"}}
+	if status := send("application/x-www-form-urlencoded", strings.NewReader(form.Encode())); status != http.StatusCreated {
+		t.Fatalf("plain reply answered %d, want %d", status, http.StatusCreated)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("conversation_id", strconv.Itoa(conversationID))
+	part, _ := mw.CreateFormFile("attachment", "notes.txt")
+	part.Write([]byte("private notes"))
+	mw.Close()
+	if status := send(mw.FormDataContentType(), &body); status != http.StatusCreated {
+		t.Fatalf("reply with an attachment answered %d, want %d", status, http.StatusCreated)
+	}
+
+	history := func(session string) (int, []models.Message) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/messages/history?id="+strconv.Itoa(conversationID), nil)
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: session})
+		rec := httptest.NewRecorder()
+		MessageHistoryHandler(mc).ServeHTTP(rec, req)
+		var messages []models.Message
+		json.Unmarshal(rec.Body.Bytes(), &messages)
+		return rec.Code, messages
+	}
+	if status, _ := history("eve-session"); status != http.StatusNotFound {
+		t.Errorf("history for a non-member answered %d, want %d", status, http.StatusNotFound)
+	}
+	status, messages := history("alice-session")
+	if status != http.StatusOK || len(messages) != 3 || messages[1].AttachmentURL != "" {
+		t.Fatalf("history answered %d %+v", status, messages)
+	}
+	signed := messages[2].AttachmentURL
+	if !strings.HasPrefix(signed, storage.PublicPath+storage.PrivatePrefix) {
+		t.Fatalf("attachment URL = %q, want a private upload", signed)
+	}
+
+	// The attachment is only served through its signed URL
+	fetch := func(target string) (int, string) {
+		rec := httptest.NewRecorder()
+		UploadsHandler(storage.Default).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Code, rec.Body.String()
+	}
+	if status, content := fetch(signed); status != http.StatusOK || content != "private notes" {
+		t.Errorf("GET signed attachment URL = %d %q", status, content)
+	}
+	path := strings.Split(signed, "?")[0]
+	if status, _ := fetch(path); status != http.StatusForbidden {
+		t.Errorf("GET unsigned attachment URL = %d, want %d", status, http.StatusForbidden)
+	}
+
+	// The message keeps the file from being collected
+	var refs int
+	if err := db.QueryRow(`SELECT ref_count FROM files WHERE storage_key = ?`, storage.KeyFromPath(path)).Scan(&refs); err != nil || refs != 1 {
+		t.Errorf("ref_count of the attachment = %d, %v, want 1", refs, err)
+	}
+}
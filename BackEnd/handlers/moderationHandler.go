@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+// ThreadStateHandler lets moderators pin, lock and archive threads.
+// It expects the form fields post_id and action, plus an optional category when pinning.
+func ThreadStateHandler(pc *controllers.PostController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, userID := isLoggedIn(pc.DB, r)
+
+		if err := r.ParseForm(); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to parse form data: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		postID, err := strconv.Atoi(r.FormValue("post_id"))
+		if err != nil {
+			WriteError(w, r, apperror.BadRequest("Invalid post ID"))
+			return
+		}
+
+		action := r.FormValue("action")
+		switch action {
+		case "pin":
+			err = pc.SetPinned(postID, true, r.FormValue("category"))
+		case "unpin":
+			err = pc.SetPinned(postID, false, "")
+		case "lock":
+			err = pc.SetLocked(postID, true)
+		case "unlock":
+			err = pc.SetLocked(postID, false)
+		case "archive":
+			err = pc.SetArchived(postID, true)
+		case "unarchive":
+			err = pc.SetArchived(postID, false)
+		default:
+			WriteError(w, r, apperror.BadRequest("Unknown action"))
+			return
+		}
+
+		if errors.Is(err, controllers.ErrPostNotFound) {
+			WriteError(w, r, apperror.NotFound("Post not found"))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to %s post %d: %v", action, postID, err)
+			WriteError(w, r, apperror.BadRequest(err.Error()))
+			return
+		}
+
+		logger.InfoContext(r.Context(), "Moderator %d applied %s to post %d", userID, action, postID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Thread updated",
+		})
+	}
+}
+
+// VoteFlagsHandler reports the votes the analyzer flagged as possible manipulation.
+// It accepts an optional status (open by default, dismissed or nullified) and limit.
+func VoteFlagsHandler(fc *controllers.VoteFlagController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := r.URL.Query().Get("status")
+		switch status {
+		case "":
+			status = models.FlagOpen
+		case models.FlagOpen, models.FlagDismissed, models.FlagNullified:
+		default:
+			WriteError(w, r, apperror.BadRequest("Unknown status"))
+			return
+		}
+		limit := 50
+		if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 200 {
+			limit = l
+		}
+
+		flags, err := fc.GetFlags(status, limit)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to build vote flag report: %v", err)
+			WriteError(w, r, apperror.Internal("Failed to build vote flag report"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(flags)
+	}
+}
+
+// ResolveVoteFlagHandler lets moderators dismiss a vote flag or nullify the flagged votes.
+// It expects the form fields id and action ("dismiss" or "nullify").
+func ResolveVoteFlagHandler(fc *controllers.VoteFlagController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, userID := isLoggedIn(fc.DB, r)
+
+		if err := r.ParseForm(); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to parse form data: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		flagID, err := strconv.Atoi(r.FormValue("id"))
+		if err != nil {
+			WriteError(w, r, apperror.BadRequest("Invalid flag ID"))
+			return
+		}
+
+		action := r.FormValue("action")
+		removed := 0
+		switch action {
+		case "dismiss":
+			err = fc.Dismiss(flagID)
+		case "nullify":
+			removed, err = fc.Nullify(flagID)
+		default:
+			WriteError(w, r, apperror.BadRequest("Unknown action"))
+			return
+		}
+
+		if errors.Is(err, controllers.ErrVoteFlagNotFound) {
+			WriteError(w, r, apperror.NotFound("Vote flag not found"))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to %s vote flag %d: %v", action, flagID, err)
+			WriteError(w, r, apperror.Internal("Failed to resolve vote flag"))
+			return
+		}
+
+		logger.InfoContext(r.Context(), "Moderator %d applied %s to vote flag %d", userID, action, flagID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{
+			"removedVotes": removed,
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+var (
+	openAPIOnce sync.Once
+	openAPISpec map[string]interface{}
+)
+
+// OpenAPISpec returns the OpenAPI 3 document of the JSON API, generated from its routes and resource types
+func OpenAPISpec() map[string]interface{} {
+	openAPIOnce.Do(func() {
+		openAPISpec = buildOpenAPISpec(apiRoutes())
+	})
+	return openAPISpec
+}
+
+func buildOpenAPISpec(routes []apiRoute) map[string]interface{} {
+	schemas := make(map[string]interface{})
+	schemaRef(reflect.TypeOf(apperror.Problem{}), schemas)
+
+	paths := make(map[string]interface{})
+	for _, route := range routes {
+		operation := map[string]interface{}{
+			"summary":     route.summary,
+			"operationId": operationID(route),
+			"responses":   operationResponses(route, schemas),
+		}
+
+		var parameters []interface{}
+		for _, segment := range strings.Split(route.path, "/") {
+			if strings.HasPrefix(segment, "{") {
+				name := strings.Trim(segment, "{}")
+				schema := map[string]interface{}{"type": "string"}
+				if name == "id" {
+					schema = map[string]interface{}{"type": "integer"}
+				}
+				parameters = append(parameters, map[string]interface{}{
+					"name": name, "in": "path", "required": true, "schema": schema,
+				})
+			}
+		}
+		for _, param := range route.query {
+			schema := map[string]interface{}{"type": "string"}
+			if param.integer {
+				schema["type"] = "integer"
+			}
+			if len(param.enum) > 0 {
+				schema["enum"] = param.enum
+			}
+			parameters = append(parameters, map[string]interface{}{
+				"name": param.name, "in": "query", "description": param.description, "schema": schema,
+			})
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		if route.request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": schemaRef(reflect.TypeOf(route.request), schemas),
+					},
+				},
+			}
+		}
+		if route.auth {
+			security := []interface{}{map[string]interface{}{"session": []string{}}}
+			if route.method != http.MethodGet {
+				security = []interface{}{map[string]interface{}{"session": []string{}, "csrf": []string{}}}
+			}
+			operation["security"] = security
+		}
+
+		item, ok := paths[route.path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[route.path] = item
+		}
+		item[strings.ToLower(route.method)] = operation
+	}
+
+	paths["/openapi.json"] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"summary":     "This document",
+			"operationId": "getOpenAPI",
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "The OpenAPI document",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{"schema": map[string]interface{}{"type": "object"}},
+					},
+				},
+			},
+		},
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Forum API",
+			"version": "1.0.0",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": APIPrefix}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"session": map[string]interface{}{"type": "apiKey", "in": "cookie", "name": "session_token"},
+				"csrf":    map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-CSRF-Token"},
+			},
+		},
+	}
+}
+
+// operationID names an operation after its method and path, e.g. listPostsComments for GET /posts/{id}/comments
+func operationID(route apiRoute) string {
+	var name strings.Builder
+	for _, segment := range strings.Split(route.path, "/") {
+		if segment == "" || strings.HasPrefix(segment, "{") {
+			continue
+		}
+		name.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+	switch {
+	case route.method == http.MethodPost:
+		return "create" + name.String()
+	case route.list:
+		return "list" + name.String()
+	default:
+		return "get" + name.String()
+	}
+}
+
+func operationResponses(route apiRoute, schemas map[string]interface{}) map[string]interface{} {
+	data := schemaRef(reflect.TypeOf(route.response), schemas)
+	envelope := map[string]interface{}{
+		"type":                 "object",
+		"required":             []string{"data"},
+		"additionalProperties": false,
+		"properties":           map[string]interface{}{"data": data},
+	}
+	if route.list {
+		envelope["required"] = []string{"data", "pagination"}
+		envelope["properties"] = map[string]interface{}{
+			"data":       map[string]interface{}{"type": "array", "items": data},
+			"pagination": schemaRef(reflect.TypeOf(models.APIPagination{}), schemas),
+		}
+	}
+
+	responses := map[string]interface{}{
+		"200": map[string]interface{}{
+			"description": route.summary,
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": envelope}},
+		},
+	}
+	errorResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content": map[string]interface{}{
+				"application/problem+json": map[string]interface{}{
+					"schema": map[string]interface{}{"$ref": "#/components/schemas/Problem"},
+				},
+			},
+		}
+	}
+	if len(route.query) > 0 || route.request != nil || strings.Contains(route.path, "{id}") {
+		responses["400"] = errorResponse("The request is invalid")
+	}
+	if route.auth {
+		responses["401"] = errorResponse("Not logged in")
+	}
+	if route.auth && route.method != http.MethodGet {
+		responses["403"] = errorResponse("The CSRF token is invalid or the user may not do this")
+	}
+	if strings.Contains(route.path, "{") || route.request != nil {
+		responses["404"] = errorResponse("The resource does not exist")
+	}
+	responses["default"] = errorResponse("Unexpected error")
+	return responses
+}
+
+var jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// schemaRef returns the schema of a type, registering structs as components named after the
+// type without its API prefix
+func schemaRef(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Ptr:
+		schema := schemaRef(t.Elem(), schemas)
+		if _, isRef := schema["$ref"]; isRef {
+			return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case t.Kind() == reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": schemaRef(t.Elem(), schemas)}
+	case t.Kind() == reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaRef(t.Elem(), schemas)}
+	case t.Kind() == reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case t.Kind() == reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case t.Kind() != reflect.Struct:
+		return map[string]interface{}{}
+	}
+
+	name := strings.TrimPrefix(t.Name(), "API")
+	if _, ok := schemas[name]; !ok {
+		// Register the name first so recursive types end in a reference
+		schemas[name] = nil
+		properties := make(map[string]interface{})
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := strings.Split(field.Tag.Get("json"), ",")
+			if tag[0] == "-" || !field.IsExported() {
+				continue
+			}
+			jsonName := tag[0]
+			if jsonName == "" {
+				jsonName = field.Name
+			}
+			properties[jsonName] = schemaRef(field.Type, schemas)
+			if !containsString(tag[1:], "omitempty") {
+				required = append(required, jsonName)
+			}
+		}
+		schemas[name] = map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+			// Types marshalling themselves may add members, as problems do with their extensions
+			"additionalProperties": t.Implements(jsonMarshaler),
+		}
+	}
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+// parsePollForm builds the optional poll of a post creation form; it returns nil when no options were given
+func parsePollForm(r *http.Request) (*models.Poll, error) {
+	var options []models.PollOption
+	for _, text := range r.Form["poll-option"] {
+		if text != "" {
+			options = append(options, models.PollOption{Text: text})
+		}
+	}
+	if len(options) == 0 {
+		return nil, nil
+	}
+
+	poll := &models.Poll{
+		Question:       r.FormValue("poll-question"),
+		MultipleChoice: r.FormValue("poll-multiple") == "true" || r.FormValue("poll-multiple") == "on",
+		Options:        options,
+	}
+	if closesAt := r.FormValue("poll-closes-at"); closesAt != "" {
+		t, err := time.Parse(time.RFC3339, closesAt)
+		if err != nil {
+			return nil, errors.New("invalid poll closing time")
+		}
+		poll.ClosesAt = &t
+	}
+	if poll.Question == "" {
+		poll.Question = r.FormValue("title")
+	}
+
+	if err := controllers.ValidatePoll(poll); err != nil {
+		return nil, err
+	}
+	return poll, nil
+}
+
+// GetPollHandler returns the live tallies of a post's poll
+func GetPollHandler(pc *controllers.PollController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		postID, err := strconv.Atoi(r.URL.Query().Get("post_id"))
+		if err != nil {
+			WriteError(w, r, apperror.BadRequest("Invalid post ID"))
+			return
+		}
+
+		_, userID := isLoggedIn(pc.DB, r)
+		poll, err := pc.GetPollByPostID(postID, userID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch poll for post %d: %v", postID, err)
+			WriteError(w, r, apperror.Internal("Failed to fetch poll"))
+			return
+		}
+		if poll == nil {
+			WriteError(w, r, apperror.NotFound("Post has no poll"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(poll)
+	}
+}
+
+// PollVoteHandler records the logged-in user's ballot
+func PollVoteHandler(pc *controllers.PollController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loggedIn, userID := isLoggedIn(pc.DB, r)
+		if !loggedIn {
+			WriteError(w, r, apperror.Unauthorized("Must be logged in to vote in a poll"))
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to parse form data: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		postID, err := strconv.Atoi(r.FormValue("post_id"))
+		if err != nil {
+			WriteError(w, r, apperror.BadRequest("Invalid post ID"))
+			return
+		}
+
+		var optionIDs []int
+		for _, value := range r.Form["option_id"] {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				WriteError(w, r, apperror.BadRequest("Invalid option ID"))
+				return
+			}
+			optionIDs = append(optionIDs, id)
+		}
+
+		poll, err := pc.Vote(postID, userID, optionIDs)
+		if err != nil {
+			appErr := apperror.Internal("Failed to record vote")
+			switch {
+			case errors.Is(err, controllers.ErrPollNotFound):
+				appErr = apperror.NotFound(err.Error())
+			case errors.Is(err, controllers.ErrPollClosed), errors.Is(err, controllers.ErrAlreadyVoted):
+				appErr = apperror.Conflict(err.Error())
+			case errors.Is(err, controllers.ErrInvalidPollChoice):
+				appErr = apperror.BadRequest(err.Error())
+			default:
+				logger.ErrorContext(r.Context(), "Failed to record poll vote for post %d: %v", postID, err)
+			}
+			WriteError(w, r, appErr)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(poll)
+	}
+}
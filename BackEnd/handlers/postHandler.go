@@ -3,12 +3,15 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
 	"github.com/Raymond9734/forum.git/BackEnd/controllers"
 	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/metrics"
 	"github.com/Raymond9734/forum.git/BackEnd/models"
 )
 
@@ -17,28 +20,21 @@ func CreatePostHandler(pc *controllers.PostController) http.HandlerFunc {
 		// Check if the user is logged in
 		loggedIn, userID := isLoggedIn(pc.DB, r)
 		if !loggedIn {
-			logger.Warning("Unauthorized attempt to create post - remote_addr: %s, method: %s, path: %s, user_id: %d",
+			logger.WarningContext(r.Context(), "Unauthorized attempt to create post - remote_addr: %s, method: %s, path: %s, user_id: %d",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 				userID,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "Must be logged in to create post",
-			})
+			WriteError(w, r, apperror.Unauthorized("Must be logged in to create post"))
 			return
 		}
-		// Parse the multipart form
-		err := r.ParseMultipartForm(10 << 20) // 10 MB limit
+		// Parse the multipart form, rejecting bodies over the upload limit
+		r.Body = http.MaxBytesReader(w, r.Body, controllers.MaxUploadRequestSize)
+		err := r.ParseMultipartForm(controllers.MaxUploadRequestSize)
 		if err != nil {
-			logger.Error("Failed to parse multipart form: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Failed to parse form data",
-			})
+			logger.ErrorContext(r.Context(), "Failed to parse multipart form: %v", err)
+			WriteError(w, r, apperror.BadRequest("Failed to parse form data"))
 			return
 		}
 
@@ -49,41 +45,64 @@ func CreatePostHandler(pc *controllers.PostController) http.HandlerFunc {
 
 		// Validate required fields
 		if title == "" || categories == "" {
-			logger.Warning("Invalid post creation request: missing or empty required fields - remote_addr: %s, method: %s, path: %s",
+			logger.WarningContext(r.Context(), "Invalid post creation request: missing or empty required fields - remote_addr: %s, method: %s, path: %s",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "Title and categories are required",
-			})
+			fields := make(map[string]string)
+			if title == "" {
+				fields["title"] = "Title is required"
+			}
+			if categories == "" {
+				fields["category"] = "Choose at least one category"
+			}
+			WriteError(w, r, apperror.Validation("Title and categories are required", fields))
+			return
+		}
+
+		// Parse the optional poll
+		poll, err := parsePollForm(r)
+		if err != nil {
+			logger.WarningContext(r.Context(), "Invalid poll in post creation request: %v - remote_addr: %s", err, r.RemoteAddr)
+			WriteError(w, r, apperror.BadRequest(err.Error()))
 			return
 		}
 
+		// Posting images is a privilege unlocked by reputation
+		if _, _, err := r.FormFile("post-file"); err == nil {
+			err = controllers.CheckPrivilege(pc.DB, userID, models.PrivilegePostImages)
+			var repErr *controllers.ReputationRequiredError
+			if errors.As(err, &repErr) {
+				writeReputationRequired(w, r, repErr)
+				return
+			}
+			if err != nil {
+				logger.ErrorContext(r.Context(), "Failed to check image privilege of user %d: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
 		// Handle file upload
-		filePath, err := controllers.UploadFile(r, "post-file", userID)
+		filePath, err := controllers.UploadFile(pc.DB, r, "post-file", userID)
+		var quotaErr *controllers.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			writeQuotaExceeded(w, r, quotaErr)
+			return
+		}
 		if err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Failed to save file",
-			})
+			WriteError(w, r, apperror.Internal("Failed to save file"))
 			return
 		}
 
-		if content == "" && filePath == "" {
-			logger.Warning("Invalid post creation request: missing content and image  fields  at least one is required - remote_addr: %s, method: %s, path: %s",
+		if content == "" && filePath == "" && poll == nil {
+			logger.WarningContext(r.Context(), "Invalid post creation request: missing content and image  fields  at least one is required - remote_addr: %s, method: %s, path: %s",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "Missing content and image  fields  at least one is required",
-			})
+			WriteError(w, r, apperror.BadRequest("Missing content and image  fields  at least one is required"))
 			return
 		}
 
@@ -101,19 +120,27 @@ func CreatePostHandler(pc *controllers.PostController) http.HandlerFunc {
 				String: filePath,
 				Valid:  filePath != "",
 			},
+			Poll: poll,
 		}
 
 		// Insert the post into the database
 		postID, err := pc.InsertPost(createPost)
 		if err != nil {
-			logger.Error("Failed to insert post: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Failed to create post",
-			})
+			logger.ErrorContext(r.Context(), "Failed to insert post: %v", err)
+			WriteError(w, r, apperror.Internal("Failed to create post"))
 			return
 		}
+		metrics.PostsCreated.Inc()
+
+		// Subscribe the author and fan the new post out to category subscribers;
+		// the post is already stored, so a failure here does not fail the request
+		createPost.ID = postID
+		if _, err := controllers.NewSubscriptionController(pc.DB).PostCreated(createPost); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fan out post %d to subscribers: %v", postID, err)
+		}
+		if _, err := controllers.NewMentionController(pc.DB).SaveMentions(models.MentionSourcePost, postID, postID, userID, content); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to record mentions in post %d: %v", postID, err)
+		}
 
 		// Return the created post ID in the response
 		w.Header().Set("Content-Type", "application/json")
@@ -130,36 +157,29 @@ func UpdatePostHandler(pc *controllers.PostController) http.HandlerFunc {
 		// Check if the user is logged in
 		loggedIn, userID := isLoggedIn(pc.DB, r)
 		if !loggedIn {
-			logger.Warning("Unauthorized attempt to update post - remote_addr: %s, method: %s, path: %s, user_id: %d",
+			logger.WarningContext(r.Context(), "Unauthorized attempt to update post - remote_addr: %s, method: %s, path: %s, user_id: %d",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 				userID,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "Must be logged in to update post",
-			})
+			WriteError(w, r, apperror.Unauthorized("Must be logged in to update post"))
 			return
 		}
 
-		// Parse the multipart form
-		err := r.ParseMultipartForm(10 << 20) // 10 MB limit
+		// Parse the multipart form, rejecting bodies over the upload limit
+		r.Body = http.MaxBytesReader(w, r.Body, controllers.MaxUploadRequestSize)
+		err := r.ParseMultipartForm(controllers.MaxUploadRequestSize)
 		if err != nil {
-			logger.Error("Failed to parse multipart form: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Failed to parse form data",
-			})
+			logger.ErrorContext(r.Context(), "Failed to parse multipart form: %v", err)
+			WriteError(w, r, apperror.BadRequest("Failed to parse form data"))
 			return
 		}
 
 		// Extract post ID from URL
-		postID := r.URL.Query().Get("id")
+		postID := r.PathValue("id")
 		if postID == "" {
-			logger.Warning("Post Id Is empty")
+			logger.WarningContext(r.Context(), "Post Id Is empty")
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
@@ -171,54 +191,58 @@ func UpdatePostHandler(pc *controllers.PostController) http.HandlerFunc {
 
 		// Validate required fields
 		if postID == "" || title == "" || categories == "" {
-			logger.Error("Invalid post update request: missing or empty required fields - remote_addr: %s, method: %s, path: %s",
+			logger.ErrorContext(r.Context(), "Invalid post update request: missing or empty required fields - remote_addr: %s, method: %s, path: %s",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "Post ID, title, and categories are required",
-			})
+			WriteError(w, r, apperror.BadRequest("Post ID, title, and categories are required"))
 			return
 		}
 
 		// Convert postID to int
 		postIDInt, err := strconv.Atoi(postID)
 		if err != nil {
-			logger.Error("Invalid post ID: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Invalid post ID",
-			})
+			logger.ErrorContext(r.Context(), "Invalid post ID: %v", err)
+			WriteError(w, r, apperror.BadRequest("Invalid post ID"))
 			return
 		}
 
+		// Posting images is a privilege unlocked by reputation
+		if _, _, err := r.FormFile("post-file"); err == nil {
+			err = controllers.CheckPrivilege(pc.DB, userID, models.PrivilegePostImages)
+			var repErr *controllers.ReputationRequiredError
+			if errors.As(err, &repErr) {
+				writeReputationRequired(w, r, repErr)
+				return
+			}
+			if err != nil {
+				logger.ErrorContext(r.Context(), "Failed to check image privilege of user %d: %v", userID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
 		// Handle file upload (if a new file is provided)
-		filePath, err := controllers.UploadFile(r, "post-file", userID)
+		filePath, err := controllers.UploadFile(pc.DB, r, "post-file", userID)
+		var quotaErr *controllers.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			writeQuotaExceeded(w, r, quotaErr)
+			return
+		}
 		if err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Failed to save file",
-			})
+			WriteError(w, r, apperror.Internal("Failed to save file"))
 			return
 		}
 
 		// Ensure at least one of content or file is provided
 		if content == "" && filePath == "" {
-			logger.Warning("Invalid post update request: missing content and image fields - at least one is required - remote_addr: %s, method: %s, path: %s",
+			logger.WarningContext(r.Context(), "Invalid post update request: missing content and image fields - at least one is required - remote_addr: %s, method: %s, path: %s",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "Missing content and image fields - at least one is required",
-			})
+			WriteError(w, r, apperror.BadRequest("Missing content and image fields - at least one is required"))
 			return
 		}
 
@@ -243,15 +267,16 @@ func UpdatePostHandler(pc *controllers.PostController) http.HandlerFunc {
 		// Update the post in the database
 		err = pc.UpdatePost(updatePost)
 		if err != nil {
-			logger.Error("Failed to update post: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Failed to update post",
-			})
+			logger.ErrorContext(r.Context(), "Failed to update post: %v", err)
+			WriteError(w, r, apperror.Internal("Failed to update post"))
 			return
 		}
 
+		// Only users newly mentioned by the edit are alerted
+		if _, err := controllers.NewMentionController(pc.DB).SaveMentions(models.MentionSourcePost, postIDInt, postIDInt, userID, content); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to record mentions in post %d: %v", postIDInt, err)
+		}
+
 		// Return success response
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -267,89 +292,65 @@ func DeletePostHandler(pc *controllers.PostController) http.HandlerFunc {
 		// Check if the user is logged in
 		loggedIn, userID := isLoggedIn(pc.DB, r)
 		if !loggedIn {
-			logger.Warning("Unauthorized attempt to delete post - remote_addr: %s, method: %s, path: %s, user_id: %d",
+			logger.WarningContext(r.Context(), "Unauthorized attempt to delete post - remote_addr: %s, method: %s, path: %s, user_id: %d",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 				userID,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "Must be logged in to delete post",
-			})
+			WriteError(w, r, apperror.Unauthorized("Must be logged in to delete post"))
 			return
 		}
 
-		// Extract the post ID from the query parameters
-		postIDStr := r.URL.Query().Get("id")
+		// Extract the post ID from the URL
+		postIDStr := r.PathValue("id")
 		if postIDStr == "" {
-			logger.Warning("Missing post ID in delete request - remote_addr: %s, method: %s, path: %s",
+			logger.WarningContext(r.Context(), "Missing post ID in delete request - remote_addr: %s, method: %s, path: %s",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Post ID is required",
-			})
+			WriteError(w, r, apperror.BadRequest("Post ID is required"))
 			return
 		}
 
 		// Convert post ID to an integer
 		postID, err := strconv.Atoi(postIDStr)
 		if err != nil {
-			logger.Error("Invalid post ID: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Invalid post ID",
-			})
+			logger.ErrorContext(r.Context(), "Invalid post ID: %v", err)
+			WriteError(w, r, apperror.BadRequest("Invalid post ID"))
 			return
 		}
 
 		// Verify that the user is the author of the Post
 		isAuthor, err := pc.IsPostAuthor(postID, userID)
 		if err != nil {
-			logger.Error("Failed to verify Post author: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Failed to verify Post author",
-			})
+			logger.ErrorContext(r.Context(), "Failed to verify Post author: %v", err)
+			WriteError(w, r, apperror.Internal("Failed to verify Post author"))
 			return
 		}
 
 		if !isAuthor {
-			logger.Warning("Unauthorized attempt to delete Post - remote_addr: %s, method: %s, path: %s, user_id: %d",
+			logger.WarningContext(r.Context(), "Unauthorized attempt to delete Post - remote_addr: %s, method: %s, path: %s, user_id: %d",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 				userID,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusForbidden)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "You are not authorized to delete this Post",
-			})
+			WriteError(w, r, apperror.Forbidden("You are not authorized to delete this Post"))
 			return
 		}
 
-		logger.Info("User %d is authorized to delete Post %d", userID, postID)
+		logger.InfoContext(r.Context(), "User %d is authorized to delete Post %d", userID, postID)
 		// Call the controller to delete the post
 		err = pc.DeletePost(postID, userID)
 		if err != nil {
-			logger.Error("Failed to delete post: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Failed to delete post",
-			})
+			logger.ErrorContext(r.Context(), "Failed to delete post: %v", err)
+			WriteError(w, r, apperror.Internal("Failed to delete post"))
 			return
 		}
 
-		logger.Info("Post %d deleted successfully by user %d", postID, userID)
+		logger.InfoContext(r.Context(), "Post %d deleted successfully by user %d", postID, userID)
 		// Return success response
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -358,3 +359,17 @@ func DeletePostHandler(pc *controllers.PostController) http.HandlerFunc {
 		})
 	}
 }
+
+// writeReputationRequired tells the user how much reputation the privilege they tried to use needs
+func writeReputationRequired(w http.ResponseWriter, r *http.Request, repErr *controllers.ReputationRequiredError) {
+	WriteError(w, r, apperror.Forbidden(repErr.Error()).
+		With("reputation", repErr.Reputation).
+		With("required", repErr.Required))
+}
+
+// writeQuotaExceeded reports an upload rejected by the user's storage quota
+func writeQuotaExceeded(w http.ResponseWriter, r *http.Request, quotaErr *controllers.QuotaExceededError) {
+	WriteError(w, r, apperror.TooLarge(quotaErr.Error()).
+		With("bytesUsed", quotaErr.Used).
+		With("quota", quotaErr.Quota))
+}
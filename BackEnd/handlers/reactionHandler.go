@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+// ReactHandler toggles the logged-in user's reaction on a post or comment.
+// It expects the form fields type ("post" or "comment"), id and reaction.
+func ReactHandler(rc *controllers.ReactionController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loggedIn, userID := isLoggedIn(rc.DB, r)
+		if !loggedIn {
+			WriteError(w, r, apperror.Unauthorized("Must be logged in to react"))
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to parse form data: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		targetID, err := strconv.Atoi(r.FormValue("id"))
+		targetType := r.FormValue("type")
+		if err != nil || (targetType != models.ReactionPost && targetType != models.ReactionComment) {
+			WriteError(w, r, apperror.BadRequest("Invalid reaction target"))
+			return
+		}
+
+		reactions, err := rc.React(userID, targetType, targetID, r.FormValue("reaction"))
+		if err != nil {
+			appErr := apperror.Internal("Failed to update reaction")
+			switch {
+			case errors.Is(err, controllers.ErrInvalidReaction):
+				appErr = apperror.BadRequest(err.Error())
+			case errors.Is(err, controllers.ErrReactionTargetNotFound):
+				appErr = apperror.NotFound(err.Error())
+			default:
+				logger.ErrorContext(r.Context(), "Failed to react to %s %d: %v", targetType, targetID, err)
+			}
+			WriteError(w, r, appErr)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"reactions": reactions,
+		})
+	}
+}
+
+// ReactionsHandler returns the reaction counts of a post or comment and who reacted, newest first.
+// It expects the query parameters type and id, and an optional reaction to list only its users.
+func ReactionsHandler(rc *controllers.ReactionController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		targetID, err := strconv.Atoi(query.Get("id"))
+		targetType := query.Get("type")
+		reaction := query.Get("reaction")
+		if err != nil || (targetType != models.ReactionPost && targetType != models.ReactionComment) ||
+			(reaction != "" && !controllers.IsReaction(reaction)) {
+			WriteError(w, r, apperror.BadRequest("Invalid reaction target"))
+			return
+		}
+
+		_, userID := isLoggedIn(rc.DB, r)
+		counts, err := rc.ReactionCounts(targetType, []int{targetID}, userID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch reactions of %s %d: %v", targetType, targetID, err)
+			WriteError(w, r, apperror.Internal("Failed to fetch reactions"))
+			return
+		}
+		reactors, err := rc.GetReactors(targetType, targetID, reaction)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch reactors of %s %d: %v", targetType, targetID, err)
+			WriteError(w, r, apperror.Internal("Failed to fetch reactions"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"reactions": counts[targetID],
+			"reactors":  reactors,
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+// SubscribeHandler sets the logged-in user's watch level for a post or a category.
+// It expects the form fields post_id or category, and level; an empty level unsubscribes.
+func SubscribeHandler(sc *controllers.SubscriptionController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loggedIn, userID := isLoggedIn(sc.DB, r)
+		if !loggedIn {
+			WriteError(w, r, apperror.Unauthorized("Must be logged in to subscribe"))
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to parse form data: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var postID int
+		if value := r.FormValue("post_id"); value != "" {
+			var err error
+			if postID, err = strconv.Atoi(value); err != nil {
+				WriteError(w, r, apperror.BadRequest("Invalid post ID"))
+				return
+			}
+		}
+		category := r.FormValue("category")
+		level := r.FormValue("level")
+
+		var err error
+		if level == "" {
+			err = sc.Unsubscribe(userID, postID, category)
+		} else {
+			err = sc.Subscribe(userID, postID, category, level)
+		}
+
+		if err != nil {
+			appErr := apperror.Internal("Failed to update subscription")
+			switch {
+			case errors.Is(err, controllers.ErrInvalidWatchLevel), errors.Is(err, controllers.ErrInvalidSubscription):
+				appErr = apperror.BadRequest(err.Error())
+			case errors.Is(err, controllers.ErrSubscriptionPostMissing), errors.Is(err, controllers.ErrSubscriptionNotFound):
+				appErr = apperror.NotFound(err.Error())
+			default:
+				logger.ErrorContext(r.Context(), "Failed to update subscription for user %d: %v", userID, err)
+			}
+			WriteError(w, r, appErr)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"level": level,
+		})
+	}
+}
+
+// SubscriptionsPageHandler renders the logged-in user's thread and category subscriptions
+func SubscriptionsPageHandler(sc *controllers.SubscriptionController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+
+		loggedIn, userID := isLoggedIn(sc.DB, r)
+		if !loggedIn {
+			http.Redirect(w, r, "/login_Page", http.StatusSeeOther)
+			return
+		}
+
+		sessionToken, err := controllers.GetSessionToken(r)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Error getting session token: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		csrfToken, err := controllers.GenerateCSRFToken(sc.DB, sessionToken)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Error generating CSRF token: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		subscriptions, err := sc.GetSubscriptions(userID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch subscriptions for user %d: %v", userID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		funcMap := template.FuncMap{
+			"formatTime": func(t time.Time) string {
+				return t.Format("Jan 02, 2006 at 15:04")
+			},
+		}
+		tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles(
+			"./FrontEnd/templates/layout.html",
+			"./FrontEnd/templates/subscriptions.html",
+		)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "An error occured while rendering template %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		data := struct {
+			IsAuthenticated bool
+			CSRFToken       string
+			Subscriptions   []models.Subscription
+			WatchLevels     []string
+			UserID          int
+		}{
+			IsAuthenticated: loggedIn,
+			CSRFToken:       csrfToken,
+			Subscriptions:   subscriptions,
+			WatchLevels:     models.WatchLevels,
+			UserID:          userID,
+		}
+		if err := tmpl.ExecuteTemplate(w, "layout.html", data); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to render subscriptions page: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+}
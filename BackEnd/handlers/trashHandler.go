@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+// TrashPageHandler lists the posts and comments the logged-in user can still restore
+func TrashPageHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+
+		loggedIn, userID := isLoggedIn(db, r)
+		if !loggedIn {
+			http.Redirect(w, r, "/login_Page", http.StatusSeeOther)
+			return
+		}
+
+		sessionToken, err := controllers.GetSessionToken(r)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Error getting session token: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		csrfToken, err := controllers.GenerateCSRFToken(db, sessionToken)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Error generating CSRF token: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		items, err := controllers.GetTrash(db, userID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch trash for user %d: %v", userID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		funcMap := template.FuncMap{
+			"formatTime": func(t time.Time) string {
+				return t.Format("Jan 02, 2006 at 15:04")
+			},
+		}
+		tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFiles(
+			"./FrontEnd/templates/layout.html",
+			"./FrontEnd/templates/trash.html",
+		)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "An error occured while rendering template %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		data := struct {
+			IsAuthenticated bool
+			CSRFToken       string
+			Items           []models.TrashItem
+			RetentionDays   int
+			UserID          int
+		}{
+			IsAuthenticated: loggedIn,
+			CSRFToken:       csrfToken,
+			Items:           items,
+			RetentionDays:   int(controllers.TrashRetention().Hours() / 24),
+			UserID:          userID,
+		}
+		if err := tmpl.ExecuteTemplate(w, "layout.html", data); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to render trash page: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+}
+
+// RestoreTrashHandler restores a post or comment from the logged-in user's trash.
+// It expects the form fields type ("post" or "comment") and id.
+func RestoreTrashHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loggedIn, userID := isLoggedIn(db, r)
+		if !loggedIn {
+			WriteError(w, r, apperror.Unauthorized("Must be logged in to restore content"))
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to parse form data: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		id, err := strconv.Atoi(r.FormValue("id"))
+		itemType := r.FormValue("type")
+		if err != nil || (itemType != models.TrashPost && itemType != models.TrashComment) {
+			WriteError(w, r, apperror.BadRequest("Invalid trash item"))
+			return
+		}
+
+		err = controllers.RestoreFromTrash(db, itemType, id, userID)
+		if errors.Is(err, controllers.ErrNotInTrash) {
+			WriteError(w, r, apperror.NotFound(err.Error()))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to restore %s %d: %v", itemType, id, err)
+			WriteError(w, r, apperror.Internal("Failed to restore item"))
+			return
+		}
+
+		logger.InfoContext(r.Context(), "User %d restored %s %d from trash", userID, itemType, id)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Restored successfully",
+		})
+	}
+}
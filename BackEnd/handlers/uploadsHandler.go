@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/storage"
+)
+
+// UploadsHandler serves stored uploads from the configured storage backend
+func UploadsHandler(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := storage.KeyFromPath(r.URL.Path)
+		if key == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// Private objects are only reachable through a valid signed URL
+		if storage.IsPrivate(key) {
+			verifier, ok := store.(storage.Verifier)
+			query := r.URL.Query()
+			if !ok || !verifier.Verify(key, query.Get("expires"), query.Get("signature")) {
+				logger.WarningContext(r.Context(), "Rejected unsigned request for private upload - remote_addr: %s, key: %s", r.RemoteAddr, key)
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		} else if url := store.URL(key); url != storage.PathFor(key) {
+			// The backend serves public objects itself
+			http.Redirect(w, r, url, http.StatusFound)
+			return
+		}
+
+		object, err := store.Get(r.Context(), key)
+		if errors.Is(err, storage.ErrNotExist) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to read upload %s: %v", key, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer object.Close()
+
+		if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+
+		// Local files support range requests and conditional headers
+		if seeker, ok := object.(io.ReadSeeker); ok {
+			http.ServeContent(w, r, key, time.Time{}, seeker)
+			return
+		}
+		if _, err := io.Copy(w, object); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to stream upload %s: %v", key, err)
+		}
+	}
+}
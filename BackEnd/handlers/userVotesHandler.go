@@ -2,14 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
 	"github.com/Raymond9734/forum.git/BackEnd/controllers"
 	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/metrics"
 	"github.com/Raymond9734/forum.git/BackEnd/models"
 )
 
@@ -18,29 +21,21 @@ func CreateUserVoteHandler(lc *controllers.LikesController) http.HandlerFunc {
 		// Check if the user is logged in
 		loggedIn, userID := isLoggedIn(lc.DB, r)
 		if !loggedIn {
-			logger.Warning("Unauthorized attempt to create like - remote_addr: %s, method: %s, path: %s, user_id: %d",
+			logger.WarningContext(r.Context(), "Unauthorized attempt to create like - remote_addr: %s, method: %s, path: %s, user_id: %d",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 				userID,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "Must be logged in to like a post",
-			})
+			WriteError(w, r, apperror.Unauthorized("Must be logged in to like a post"))
 			return
 		}
 
 		// Parse the form data
 		err := r.ParseForm()
 		if err != nil {
-			logger.Error("Failed to parse form data: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Failed to parse form data",
-			})
+			logger.ErrorContext(r.Context(), "Failed to parse form data: %v", err)
+			WriteError(w, r, apperror.BadRequest("Failed to parse form data"))
 			return
 		}
 
@@ -50,77 +45,65 @@ func CreateUserVoteHandler(lc *controllers.LikesController) http.HandlerFunc {
 
 		// Validate required fields
 		if postIDStr == "" || userVote == "" {
-			logger.Warning("Invalid like creation request: missing or empty required fields - remote_addr: %s, method: %s, path: %s",
+			logger.WarningContext(r.Context(), "Invalid like creation request: missing or empty required fields - remote_addr: %s, method: %s, path: %s",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "Post ID and vote are required",
-			})
+			WriteError(w, r, apperror.BadRequest("Post ID and vote are required"))
 			return
 		}
 
 		// Convert post_id to an integer
 		postID, err := strconv.Atoi(postIDStr)
 		if err != nil {
-			logger.Error("Invalid post_id: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "Invalid post ID format",
-			})
+			logger.ErrorContext(r.Context(), "Invalid post_id: %v", err)
+			WriteError(w, r, apperror.BadRequest("Invalid post ID format"))
 			return
 		}
 
 		// Validate the vote value
 		if userVote != "like" && userVote != "dislike" {
-			logger.Warning("Invalid vote value: %s - remote_addr: %s, method: %s, path: %s",
+			logger.WarningContext(r.Context(), "Invalid vote value: %s - remote_addr: %s, method: %s, path: %s",
 				userVote,
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "Vote must be either 'like' or 'dislike'",
-			})
+			WriteError(w, r, apperror.BadRequest("Vote must be either 'like' or 'dislike'"))
 			return
 		}
 
-		// Handle the vote
-		err = lc.HandleVote(postID, userID, userVote)
-		if err != nil {
-			logger.Error("Failed to handle vote: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": err.Error(),
-			})
+		// Handle the vote; the counts it returns are the ones committed with it
+		tally, err := lc.HandleVote(postID, userID, userVote)
+		var repErr *controllers.ReputationRequiredError
+		if errors.As(err, &repErr) {
+			writeReputationRequired(w, r, repErr)
+			return
+		}
+		if errors.Is(err, controllers.ErrVoteTargetNotFound) {
+			WriteError(w, r, apperror.NotFound("Post not found"))
 			return
 		}
-
-		// After updating the post votes, fetch the updated likes count
-		likesCount, dislikesCount, err := lc.GetPostVotes(postID)
 		if err != nil {
-			logger.Error("Failed to fetch updated votes for post %d: %v", postID, err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Failed to fetch updated votes",
-			})
+			logger.ErrorContext(r.Context(), "Failed to handle vote: %v", err)
+			WriteError(w, r, apperror.Internal("Failed to save vote"))
 			return
 		}
 
+		metrics.ObserveVote("post", userVote, tally.UserVote)
+
+		// Remember where the vote came from for the vote manipulation analyzer
+		if err := controllers.RecordFingerprints(lc.DB, userID, r); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to record fingerprints of user %d: %v", userID, err)
+		}
+
 		// Return the updated likes and dislikes count
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(map[string]int{
-			"likes":    likesCount,
-			"dislikes": dislikesCount,
+			"likes":    tally.Likes,
+			"dislikes": tally.Dislikes,
 		})
 	}
 }
@@ -130,28 +113,20 @@ func GetUserVotesHandler(lc *controllers.LikesController) http.HandlerFunc {
 		// Check if the user is logged in
 		loggedIn, userID := isLoggedIn(lc.DB, r)
 		if !loggedIn {
-			logger.Warning("Unauthorized attempt to GetUserVotesHandler - remote_addr: %s, method: %s, path: %s, user_id: %d",
+			logger.WarningContext(r.Context(), "Unauthorized attempt to GetUserVotesHandler - remote_addr: %s, method: %s, path: %s, user_id: %d",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 				userID,
 			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{
-				"message": "Must be logged in to GetUserVotes",
-			})
+			WriteError(w, r, apperror.Unauthorized("Must be logged in to GetUserVotes"))
 			return
 		}
 
 		userVote, err := lc.GetUserVotes(userID)
 		if err != nil {
-			logger.Error("Failed to get user votes: %v", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Failed to get user votes",
-			})
+			logger.ErrorContext(r.Context(), "Failed to get user votes: %v", err)
+			WriteError(w, r, apperror.Internal("Failed to get user votes"))
 			return
 		}
 
@@ -171,14 +146,14 @@ func GetUserPostLikesHandler(lc *controllers.LikesController) http.HandlerFunc {
 		if loggedIn {
 			sessionToken, err := controllers.GetSessionToken(r)
 			if err != nil {
-				logger.Error("Error getting session token: %s", err)
+				logger.ErrorContext(r.Context(), "Error getting session token: %s", err)
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
 			// Generate CSRF token for the session
 			csrfToken, err = controllers.GenerateCSRFToken(lc.DB, sessionToken)
 			if err != nil {
-				logger.Error("Error generating CSRF token: %V", err)
+				logger.ErrorContext(r.Context(), "Error generating CSRF token: %V", err)
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
@@ -190,15 +165,23 @@ func GetUserPostLikesHandler(lc *controllers.LikesController) http.HandlerFunc {
 			return
 		}
 
+		bookmarked, err := controllers.NewBookmarkController(lc.DB).BookmarkedIDs(userID, models.BookmarkPost)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch bookmarks for user %d: %v", userID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
 		// Add IsAuthor field to each post and fetch comment count
 		commentController := controllers.NewCommentController(lc.DB)
 		for i := range userPosts {
 			userPosts[i].IsAuthor = loggedIn && userPosts[i].UserID == userID
+			userPosts[i].IsBookmarked = bookmarked[userPosts[i].ID]
 
 			// Fetch total comment count including replies
 			commentCount, err := commentController.GetCommentCountByPostID(userPosts[i].ID)
 			if err != nil {
-				logger.Error("Failed to fetch comment count for post %d: %v", userPosts[i].ID, err)
+				logger.ErrorContext(r.Context(), "Failed to fetch comment count for post %d: %v", userPosts[i].ID, err)
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
@@ -211,8 +194,9 @@ func GetUserPostLikesHandler(lc *controllers.LikesController) http.HandlerFunc {
 			"formatTime": func(t time.Time) string {
 				return t.Format("Jan 02, 2006 at 15:04")
 			},
-			"split": strings.Split,
-			"trim":  strings.TrimSpace,
+			"split":    strings.Split,
+			"trim":     strings.TrimSpace,
+			"mentions": LinkMentions,
 		}
 
 		// Create template with function map
@@ -221,7 +205,7 @@ func GetUserPostLikesHandler(lc *controllers.LikesController) http.HandlerFunc {
 			"./FrontEnd/templates/homepage.html",
 		)
 		if err != nil {
-			logger.Error("An Error Occured while Rendering template %v", err)
+			logger.ErrorContext(r.Context(), "An Error Occured while Rendering template %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -232,6 +216,7 @@ func GetUserPostLikesHandler(lc *controllers.LikesController) http.HandlerFunc {
 			CSRFToken       string
 			Posts           []models.Post
 			UserID          int
+			Sorts           []string // liked posts are not rankable, so no sort bar
 		}{
 			IsAuthenticated: loggedIn,
 			CSRFToken:       csrfToken,
@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"text/template"
 	"time"
 
@@ -24,7 +27,7 @@ func (h *ViewPostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html")
 
 	// Extract post ID from URL
-	postID := r.URL.Query().Get("id")
+	postID := r.PathValue("id")
 	if postID == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		return
@@ -38,7 +41,7 @@ func (h *ViewPostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if loggedIn {
 		sessionToken, err := controllers.GetSessionToken(r)
 		if err != nil {
-			logger.Error("Error getting session token: %s", err)
+			logger.ErrorContext(r.Context(), "Error getting session token: %s", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -51,7 +54,7 @@ func (h *ViewPostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Fetch the post from the database
 	post, err := postController.GetPostByID(postID)
 	if err != nil {
-		logger.Error("Failed to fetch Posts %v", err)
+		logger.ErrorContext(r.Context(), "Failed to fetch Posts %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -59,11 +62,38 @@ func (h *ViewPostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Determine if the logged-in user is the post author
 	isAuthor := loggedIn && userID == post.UserID
 
-	// Create CommentController and fetch comments
+	// Create CommentController and fetch a page of comments in the requested order; a comment
+	// parameter continues the thread from that comment instead of the top
 	commentController := controllers.NewCommentController(h.db)
-	comments, err := commentController.GetCommentsByPostID(postID)
+	commentSort := controllers.ParseCommentSort(r.URL.Query().Get("sort"))
+	var comments []models.Comment
+	var hasMoreComments bool
+	var nextOffset, threadRootID, threadParentID int
+	if commentParam := r.URL.Query().Get("comment"); commentParam != "" {
+		threadRootID, err = strconv.Atoi(commentParam)
+		if err != nil || threadRootID <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		comments, err = commentController.GetCommentThread(post.ID, threadRootID, commentSort)
+		if errors.Is(err, controllers.ErrCommentNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err == nil && comments[0].ParentID.Valid {
+			threadParentID = int(comments[0].ParentID.Int64)
+		}
+	} else {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 1 {
+			page = 1
+		}
+		var commentPage models.CommentPage
+		commentPage, err = commentController.GetCommentTreePage(post.ID, userID, commentSort, (page-1)*models.CommentsPageSize)
+		comments, hasMoreComments, nextOffset = commentPage.Comments, commentPage.HasMore, commentPage.NextOffset
+	}
 	if err != nil {
-		logger.Error("Failed to fetch comments: %v", err)
+		logger.ErrorContext(r.Context(), "Failed to fetch comments: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -71,17 +101,98 @@ func (h *ViewPostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Get total comment count
 	commentCount, err := commentController.GetCommentCountByPostID(post.ID)
 	if err != nil {
-		logger.Error("Failed to fetch comment count: %v", err)
+		logger.ErrorContext(r.Context(), "Failed to fetch comment count: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 	post.CommentCount = commentCount
 
+	// Fetch the optional poll as seen by the viewer
+	post.Poll, err = controllers.NewPollController(h.db).GetPollByPostID(post.ID, userID)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to fetch poll: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Collapse comments by users the viewer blocked or muted
+	hidden, err := controllers.HiddenAuthors(h.db, userID)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to fetch block list: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	comments = controllers.HideComments(comments, hidden)
+
+	// Pin the accepted answer of a Q&A post above the other comments
+	controllers.MarkAcceptedAnswer(comments, post.AcceptedCommentID)
+	if post.AcceptedCommentID != 0 {
+		accepted, err := commentController.GetAcceptedAnswer(post.ID, post.AcceptedCommentID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to fetch accepted answer: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if answer := controllers.HideComments([]models.Comment{*accepted}, hidden); len(answer) == 1 {
+			post.AcceptedAnswer = &answer[0]
+		}
+	}
+
+	// Mark the post and comments the viewer has saved
+	bookmarkController := controllers.NewBookmarkController(h.db)
+	savedPosts, err := bookmarkController.BookmarkedIDs(userID, models.BookmarkPost)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to fetch bookmarks: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	post.IsBookmarked = savedPosts[post.ID]
+	savedComments, err := bookmarkController.BookmarkedIDs(userID, models.BookmarkComment)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to fetch bookmarks: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	controllers.MarkBookmarkedComments(comments, savedComments)
+	if post.AcceptedAnswer != nil {
+		post.AcceptedAnswer.IsBookmarked = savedComments[post.AcceptedAnswer.ID]
+	}
+
+	// Count the reactions on the post and comments, marking the viewer's
+	reactionController := controllers.NewReactionController(h.db)
+	postReactions, err := reactionController.ReactionCounts(models.ReactionPost, []int{post.ID}, userID)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to fetch reactions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	post.Reactions = postReactions[post.ID]
+	err = reactionController.AddCommentReactions(comments, userID)
+	if err == nil && post.AcceptedAnswer != nil {
+		answer := []models.Comment{*post.AcceptedAnswer}
+		err = reactionController.AddCommentReactions(answer, userID)
+		post.AcceptedAnswer = &answer[0]
+	}
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to fetch reactions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Fetch the viewer's watch level for the thread
+	watchLevel, err := controllers.NewSubscriptionController(h.db).GetWatchLevel(userID, post.ID)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to fetch watch level: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	// Create template function map
 	funcMap := template.FuncMap{
 		"formatTime": func(t time.Time) string {
 			return t.Format("Jan 02, 2006 at 15:04")
 		},
+		"mentions": LinkMentions,
 		"dict": func(values ...interface{}) (map[string]interface{}, error) {
 			if len(values)%2 != 0 {
 				return nil, fmt.Errorf("invalid dict call")
@@ -96,6 +207,8 @@ func (h *ViewPostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			}
 			return dict, nil
 		},
+		"split": strings.Split,
+		"trim":  strings.TrimSpace,
 	}
 
 	// Create template with function map
@@ -104,7 +217,7 @@ func (h *ViewPostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		"./FrontEnd/templates/viewPost.html",
 	)
 	if err != nil {
-		logger.Error("An error occured while rendering template %v", err)
+		logger.ErrorContext(r.Context(), "An error occured while rendering template %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -112,25 +225,43 @@ func (h *ViewPostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	data := struct {
 		IsAuthenticated bool
 		IsAuthor        bool
+		IsModerator     bool
 		CSRFToken       string
 		Post            models.Post
 		Comments        []models.Comment
 		UserID          int
 		MaxDepth        int
 		CommentCounts   []models.Post
+		WatchLevel      string
+		// ThreadRootID is the comment the thread continues from, ThreadParentID its parent
+		ThreadRootID   int
+		ThreadParentID int
+		CommentSort    string
+		CommentSorts   []string
+		// HasMoreComments reports whether top level comments from NextOffset on are left to load
+		HasMoreComments bool
+		NextOffset      int
 	}{
 		IsAuthenticated: loggedIn,
 		IsAuthor:        isAuthor,
+		IsModerator:     loggedIn && controllers.IsModerator(h.db, userID),
 		CSRFToken:       csrfToken,
 		Post:            post,
 		Comments:        comments,
 		UserID:          userID,
-		MaxDepth:        3,
+		MaxDepth:        models.ThreadDisplayDepth,
+		WatchLevel:      watchLevel,
+		ThreadRootID:    threadRootID,
+		ThreadParentID:  threadParentID,
+		CommentSort:     commentSort,
+		CommentSorts:    models.CommentSorts,
+		HasMoreComments: hasMoreComments,
+		NextOffset:      nextOffset,
 	}
 
 	err = tmpl.ExecuteTemplate(w, "layout.html", data)
 	if err != nil {
-		logger.Error("An error occured while rendering template %v", err)
+		logger.ErrorContext(r.Context(), "An error occured while rendering template %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
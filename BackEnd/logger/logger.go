@@ -1,66 +1,221 @@
 package logger
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Options configures where logs are written and when log files are rotated. Zero values take the defaults
+type Options struct {
+	// Dir holds the current log file, forum.log, and its rotated predecessors (default: logs)
+	Dir string
+	// Level is the least severe level written: debug, info, warning or error (default: info)
+	Level string
+	// MaxSize rotates the log file once it would grow past this many bytes (default: 100MB)
+	MaxSize int64
+	// RotateEvery rotates the log file once it has been written to for this long (default: 24h)
+	RotateEvery time.Duration
+	// MaxBackups is how many rotated files are kept (default: 14)
+	MaxBackups int
+	// MaxAge removes rotated files older than this; zero keeps them until MaxBackups is reached
+	MaxAge time.Duration
+}
+
 var (
-	infoLogger    *log.Logger
-	errorLogger   *log.Logger
-	warningLogger *log.Logger
-	debugLogger   *log.Logger
+	level   = new(slog.LevelVar)
+	current atomic.Pointer[slog.Logger]
+	// mu guards output, the log file Init opened
+	mu     sync.Mutex
+	output io.Closer
 )
 
-func Init() error {
-	logDir := "logs"
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return fmt.Errorf("failed to create log directory: %v", err)
+func init() {
+	// Until Init is called, logs go to standard error
+	use(newLogger(os.Stderr))
+}
+
+// use makes l the logger of this package and of the standard log and log/slog packages, so lines
+// logged through them are written as JSON to the same file, at the same level
+func use(l *slog.Logger) {
+	current.Store(l)
+	slog.SetDefault(l)
+}
+
+// Init writes JSON logs to the rotated log file described by opts
+func Init(opts Options) error {
+	if opts.Dir == "" {
+		opts.Dir = "logs"
+	}
+	if opts.Level == "" {
+		opts.Level = "info"
+	}
+	if opts.MaxSize == 0 {
+		opts.MaxSize = 100 << 20
+	}
+	if opts.RotateEvery == 0 {
+		opts.RotateEvery = 24 * time.Hour
+	}
+	if opts.MaxBackups == 0 {
+		opts.MaxBackups = 14
+	}
+	if err := SetLevel(opts.Level); err != nil {
+		return err
 	}
 
-	logFile := filepath.Join(logDir, fmt.Sprintf("%s.log", time.Now().Format("2006-01-02")))
-	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	file, err := openRotatingFile(opts)
 	if err != nil {
-		return fmt.Errorf("failed to open log file: %v", err)
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	use(newLogger(file))
+	if output != nil {
+		output.Close()
 	}
+	output = file
+	return nil
+}
+
+func newLogger(w io.Writer) *slog.Logger {
+	return slog.New(contextHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{
+		AddSource: true,
+		Level:     level,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			// Only the file name of the source is worth its space on every line
+			if source, ok := attr.Value.Any().(*slog.Source); ok && len(groups) == 0 {
+				source.File = filepath.Base(source.File)
+			}
+			return attr
+		},
+	})})
+}
 
-	// Initialize loggers with different prefixes
-	infoLogger = log.New(file, "INFO: ", log.Ldate|log.Ltime)
-	errorLogger = log.New(file, "ERROR: ", log.Ldate|log.Ltime)
-	warningLogger = log.New(file, "WARNING: ", log.Ldate|log.Ltime)
-	debugLogger = log.New(file, "DEBUG: ", log.Ldate|log.Ltime)
+// Close closes the log file. Later logs go to standard error
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if output == nil {
+		return nil
+	}
+	use(newLogger(os.Stderr))
+	err := output.Close()
+	output = nil
+	return err
+}
 
+// SetLevel changes the least severe level written while the application runs
+func SetLevel(name string) error {
+	var l slog.Level
+	if strings.EqualFold(name, "warning") {
+		name = "warn"
+	}
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("unknown log level %q", name)
+	}
+	level.Set(l)
 	return nil
 }
 
-func getFileInfo() string {
-	_, file, line, ok := runtime.Caller(2)
-	if !ok {
-		return "unknown:0"
+// Level is the name of the least severe level written
+func Level() string {
+	if level.Level() == slog.LevelWarn {
+		return "warning"
 	}
-	return fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	return strings.ToLower(level.Level().String())
+}
+
+// Logger returns the logger for structured logging. Pass the request context to its Context
+// methods so the line carries the request ID
+func Logger() *slog.Logger {
+	return current.Load()
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx whose log lines carry the request ID id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID of ctx, or "" if it has none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler adds the request ID of the context to every line
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// write logs the formatted message with the caller of the exported logging function as its source
+func write(ctx context.Context, l slog.Level, format string, v ...interface{}) {
+	log := current.Load()
+	if !log.Enabled(ctx, l) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+	record := slog.NewRecord(time.Now(), l, fmt.Sprintf(format, v...), pcs[0])
+	log.Handler().Handle(ctx, record)
 }
 
 func Info(format string, v ...interface{}) {
-	msg := fmt.Sprintf(format, v...)
-	infoLogger.Printf("[%s] %s", getFileInfo(), msg)
+	write(context.Background(), slog.LevelInfo, format, v...)
 }
 
 func Error(format string, v ...interface{}) {
-	msg := fmt.Sprintf(format, v...)
-	errorLogger.Printf("[%s] %s", getFileInfo(), msg)
+	write(context.Background(), slog.LevelError, format, v...)
 }
 
 func Warning(format string, v ...interface{}) {
-	msg := fmt.Sprintf(format, v...)
-	warningLogger.Printf("[%s] %s", getFileInfo(), msg)
+	write(context.Background(), slog.LevelWarn, format, v...)
 }
 
 func Debug(format string, v ...interface{}) {
-	msg := fmt.Sprintf(format, v...)
-	debugLogger.Printf("[%s] %s", getFileInfo(), msg)
+	write(context.Background(), slog.LevelDebug, format, v...)
+}
+
+// InfoContext logs like Info, adding the request ID of ctx
+func InfoContext(ctx context.Context, format string, v ...interface{}) {
+	write(ctx, slog.LevelInfo, format, v...)
+}
+
+// ErrorContext logs like Error, adding the request ID of ctx
+func ErrorContext(ctx context.Context, format string, v ...interface{}) {
+	write(ctx, slog.LevelError, format, v...)
+}
+
+// WarningContext logs like Warning, adding the request ID of ctx
+func WarningContext(ctx context.Context, format string, v ...interface{}) {
+	write(ctx, slog.LevelWarn, format, v...)
+}
+
+// DebugContext logs like Debug, adding the request ID of ctx
+func DebugContext(ctx context.Context, format string, v ...interface{}) {
+	write(ctx, slog.LevelDebug, format, v...)
 }
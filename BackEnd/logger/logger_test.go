@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	rf, err := openRotatingFile(Options{Dir: dir, MaxSize: 10, RotateEvery: time.Hour, MaxBackups: 2, MaxAge: 3 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	rf.now = func() time.Time { return now }
+	rf.opened = now
+
+	write := func(line string) {
+		t.Helper()
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups := func() []string {
+		t.Helper()
+		names, _ := filepath.Glob(filepath.Join(dir, "forum-*.log"))
+		for i := range names {
+			names[i] = filepath.Base(names[i])
+		}
+		return names
+	}
+
+	// A write that fits stays in the file, one that does not starts a new file
+	write("12345")
+	write("6789")
+	if got := backups(); len(got) != 0 {
+		t.Fatalf("rotated too early: %v", got)
+	}
+	now = now.Add(time.Second)
+	write("abcdef")
+	if got := backups(); len(got) != 1 || got[0] != "forum-2026-03-01T12-00-01.000.log" {
+		t.Fatalf("backups after outgrowing the size = %v", got)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "forum.log")); string(content) != "abcdef" {
+		t.Errorf("current file = %q, want the line that did not fit", content)
+	}
+
+	// The file is rotated once it has been written to for RotateEvery
+	now = now.Add(time.Hour)
+	write("x")
+	if got := backups(); len(got) != 2 {
+		t.Fatalf("backups after an hour = %v", got)
+	}
+
+	// Only MaxBackups rotated files are kept, the oldest go first
+	now = now.Add(time.Hour)
+	write("y")
+	if got := backups(); len(got) != 2 || got[0] != "forum-2026-03-01T13-00-01.000.log" {
+		t.Fatalf("backups beyond MaxBackups = %v", got)
+	}
+
+	// Rotated files older than MaxAge are removed even below MaxBackups
+	rf.opts.MaxBackups = 10
+	now = now.Add(3*time.Hour + 30*time.Minute)
+	write("z")
+	if got := backups(); len(got) != 1 || got[0] != "forum-2026-03-01T17-30-01.000.log" {
+		t.Fatalf("backups after MaxAge = %v", got)
+	}
+}
+
+func TestRequestIDAndLevel(t *testing.T) {
+	dir := t.TempDir()
+	if err := Init(Options{Dir: dir, Level: "warning"}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetLevel("info")
+	defer Close()
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	InfoContext(ctx, "not written at level %s", Level())
+	WarningContext(ctx, "written with %d attribute", 1)
+	if err := SetLevel("debug"); err != nil {
+		t.Fatal(err)
+	}
+	Debug("written after the level changed")
+	if err := SetLevel("loud"); err == nil {
+		t.Error("SetLevel accepted an unknown level")
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "forum.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("wrote %d lines, want 2: %s", len(lines), content)
+	}
+
+	var first struct {
+		Level     string `json:"level"`
+		Msg       string `json:"msg"`
+		RequestID string `json:"request_id"`
+		Source    struct {
+			File string `json:"file"`
+		} `json:"source"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("line is not JSON: %s", lines[0])
+	}
+	if first.Level != "WARN" || first.Msg != "written with 1 attribute" || first.RequestID != "req-1" {
+		t.Errorf("first line = %+v", first)
+	}
+	if first.Source.File != "logger_test.go" {
+		t.Errorf("source = %q, want the caller of the logger", first.Source.File)
+	}
+	if strings.Contains(lines[1], "request_id") {
+		t.Errorf("line without a request context has a request ID: %s", lines[1])
+	}
+}
+
+func TestStandardLogBridge(t *testing.T) {
+	dir := t.TempDir()
+	if err := Init(Options{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+
+	log.Printf("from the %s package", "log")
+	content, err := os.ReadFile(filepath.Join(dir, "forum.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line struct {
+		Level string `json:"level"`
+		Msg   string `json:"msg"`
+	}
+	if err := json.Unmarshal(content, &line); err != nil {
+		t.Fatalf("line of the log package is not JSON: %s", content)
+	}
+	if line.Level != "INFO" || line.Msg != "from the log package" {
+		t.Errorf("line = %+v", line)
+	}
+}
@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	logName = "forum"
+	// backupLayout timestamps rotated files so their names sort by age
+	backupLayout = "2006-01-02T15-04-05.000"
+)
+
+// rotatingFile writes to Dir/forum.log, renaming it to forum-<time>.log and starting a new one when
+// it would grow past MaxSize or has been written to for RotateEvery. Rotated files beyond MaxBackups
+// or older than MaxAge are removed
+type rotatingFile struct {
+	mu     sync.Mutex
+	opts   Options
+	file   *os.File
+	size   int64
+	opened time.Time
+	now    func() time.Time
+}
+
+func openRotatingFile(opts Options) (*rotatingFile, error) {
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %v", err)
+	}
+	rf := &rotatingFile{opts: opts, now: time.Now}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) path() string {
+	return filepath.Join(rf.opts.Dir, logName+".log")
+}
+
+// open opens the log file, continuing one left by an earlier run
+func (rf *rotatingFile) open() error {
+	file, err := os.OpenFile(rf.path(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+	rf.file, rf.size, rf.opened = file, info.Size(), rf.now()
+	if info.Size() > 0 {
+		// A file left by an earlier run is as old as its last write, not this run
+		rf.opened = info.ModTime()
+	}
+	return nil
+}
+
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	tooBig := rf.opts.MaxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.opts.MaxSize
+	tooOld := rf.opts.RotateEvery > 0 && rf.now().Sub(rf.opened) >= rf.opts.RotateEvery
+	if tooBig || tooOld {
+		if err := rf.rotate(); err != nil {
+			// Keep logging to the current file rather than losing lines
+			fmt.Fprintf(os.Stderr, "failed to rotate log file: %v\n", err)
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (rf *rotatingFile) rotate() error {
+	backup := filepath.Join(rf.opts.Dir, fmt.Sprintf("%s-%s.log", logName, rf.now().UTC().Format(backupLayout)))
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	renameErr := os.Rename(rf.path(), backup)
+	if err := rf.open(); err != nil {
+		return err
+	}
+	rf.opened = rf.now()
+	if renameErr != nil {
+		return renameErr
+	}
+	return rf.prune()
+}
+
+// prune removes the rotated files retention no longer keeps
+func (rf *rotatingFile) prune() error {
+	backups, err := filepath.Glob(filepath.Join(rf.opts.Dir, logName+"-*.log"))
+	if err != nil {
+		return err
+	}
+	// Newest first
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+
+	for i, backup := range backups {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(backup), logName+"-"), ".log")
+		rotated, err := time.Parse(backupLayout, stamp)
+		if err != nil {
+			// Not a file rotation created
+			continue
+		}
+		expired := rf.opts.MaxAge > 0 && rf.now().Sub(rotated) > rf.opts.MaxAge
+		if i >= rf.opts.MaxBackups || expired {
+			if err := os.Remove(backup); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// The metrics of the forum
+var (
+	HTTPRequests = NewCounter("forum_http_requests_total",
+		"HTTP requests answered, by method, route pattern and status code.", "method", "route", "code")
+	HTTPRequestDuration = NewHistogram("forum_http_request_duration_seconds",
+		"Time taken to answer HTTP requests, by method and route pattern.", DefaultBuckets, "method", "route")
+	RateLimitRejections = NewCounter("forum_rate_limit_rejections_total",
+		"Requests rejected by the rate limiter.")
+	CSRFFailures = NewCounter("forum_csrf_failures_total",
+		"Requests rejected for a missing or invalid CSRF token.")
+
+	PostsCreated = NewCounter("forum_posts_created_total",
+		"Posts created.")
+	CommentsCreated = NewCounter("forum_comments_created_total",
+		"Comments and replies created.")
+	Votes = NewCounter("forum_votes_total",
+		"Likes and dislikes cast or removed, by what was voted on, the vote and whether it was cast or removed.",
+		"target", "vote", "action")
+	Registrations = NewCounter("forum_registrations_total",
+		"Users registered.")
+)
+
+// RegisterDBStats reports the connection pool statistics of db
+func RegisterDBStats(db *sql.DB) {
+	stat := func(read func(sql.DBStats) float64) func() float64 {
+		return func() float64 { return read(db.Stats()) }
+	}
+	NewGaugeFunc("forum_db_max_open_connections", "Maximum number of open connections to the database.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	NewGaugeFunc("forum_db_open_connections", "Established connections to the database, in use or idle.",
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	NewGaugeFunc("forum_db_in_use_connections", "Connections to the database currently in use.",
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	NewGaugeFunc("forum_db_idle_connections", "Idle connections to the database.",
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	NewCounterFunc("forum_db_wait_count_total", "Connections waited for because the pool was exhausted.",
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	NewCounterFunc("forum_db_wait_duration_seconds_total", "Time spent waiting for a connection.",
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	NewCounterFunc("forum_db_closed_max_idle_total", "Connections closed because of the maximum idle connections.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }))
+	NewCounterFunc("forum_db_closed_max_lifetime_total", "Connections closed because of their maximum lifetime.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+}
+
+// ObserveRequest records an answered HTTP request
+func ObserveRequest(method, route string, code int, took time.Duration) {
+	HTTPRequests.Inc(method, route, strconv.Itoa(code))
+	HTTPRequestDuration.Observe(took.Seconds(), method, route)
+}
+
+// ObserveVote records a toggled vote; current is the user's vote afterwards, empty when voting
+// the same way again removed it
+func ObserveVote(target, vote, current string) {
+	action := "cast"
+	if current == "" {
+		action = "removed"
+	}
+	Votes.Inc(target, vote, action)
+}
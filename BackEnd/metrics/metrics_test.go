@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExposition(t *testing.T) {
+	requests := NewCounter("test_requests_total", "Requests.\nBy route.", "route", "code")
+	requests.Inc("/posts/{id}", "200")
+	requests.Add(2, "/posts/{id}", "200")
+	requests.Inc(`/say "hi"`, "404")
+	idle := NewCounter("test_idle_total", "Never counted.")
+	latency := NewHistogram("test_latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	latency.Observe(0.05, "/")
+	latency.Observe(0.1, "/")
+	latency.Observe(3, "/")
+	NewGaugeFunc("test_open", "Open things.", func() float64 { return 4 })
+
+	if got := requests.Value("/posts/{id}", "200"); got != 3 {
+		t.Errorf("Value = %v, want 3", got)
+	}
+	if got := idle.Value(); got != 0 {
+		t.Errorf("Value of an unused counter = %v, want 0", got)
+	}
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+
+	want := []string{
+		"# HELP test_idle_total Never counted.\n# TYPE test_idle_total counter\ntest_idle_total 0\n",
+		"# HELP test_latency_seconds Latency.\n# TYPE test_latency_seconds histogram\n" +
+			`test_latency_seconds_bucket{route="/",le="0.1"} 2` + "\n" +
+			`test_latency_seconds_bucket{route="/",le="1"} 2` + "\n" +
+			`test_latency_seconds_bucket{route="/",le="+Inf"} 3` + "\n" +
+			`test_latency_seconds_sum{route="/"} 3.15` + "\n" +
+			`test_latency_seconds_count{route="/"} 3` + "\n",
+		"# HELP test_open Open things.\n# TYPE test_open gauge\ntest_open 4\n",
+		"# HELP test_requests_total Requests.\\nBy route.\n# TYPE test_requests_total counter\n" +
+			`test_requests_total{route="/posts/{id}",code="200"} 3` + "\n" +
+			`test_requests_total{route="/say \"hi\"",code="404"} 1` + "\n",
+	}
+	body := rec.Body.String()
+	last := -1
+	for _, family := range want {
+		at := strings.Index(body, family)
+		if at < 0 {
+			t.Errorf("exposition lacks\n%s\ngot\n%s", family, body)
+			continue
+		}
+		if at < last {
+			t.Errorf("metrics are not sorted by name:\n%s", body)
+		}
+		last = at
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	NewCounter("test_twice_total", "Registered twice.")
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice did not panic")
+		}
+	}()
+	NewCounter("test_twice_total", "Registered twice.")
+}
+
+func TestObserveVote(t *testing.T) {
+	ObserveVote("post", "like", "like")
+	ObserveVote("post", "like", "")
+	ObserveVote("post", "dislike", "dislike")
+	if cast, removed := Votes.Value("post", "like", "cast"), Votes.Value("post", "like", "removed"); cast != 1 || removed != 1 {
+		t.Errorf("likes cast = %v, removed = %v, want 1 each", cast, removed)
+	}
+	if changed := Votes.Value("post", "dislike", "cast"); changed != 1 {
+		t.Errorf("dislikes cast = %v, want 1", changed)
+	}
+}
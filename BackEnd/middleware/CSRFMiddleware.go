@@ -2,11 +2,13 @@ package middleware
 
 import (
 	"database/sql"
-	"encoding/json"
 	"net/http"
 
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
 	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
 	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/metrics"
 )
 
 // VerifyCSRFMiddleware is a middleware function to verify CSRF tokens
@@ -21,16 +23,13 @@ func VerifyCSRFMiddleware(db *sql.DB) func(http.Handler) http.Handler {
 
 			// Verify the CSRF token
 			if !controllers.VerifyCSRFToken(db, r) {
-				logger.Warning("Invalid CSRF token in request - remote_addr: %s, method: %s, path: %s",
+				logger.WarningContext(r.Context(), "Invalid CSRF token in request - remote_addr: %s, method: %s, path: %s",
 					r.RemoteAddr,
 					r.Method,
 					r.URL.Path,
 				)
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusForbidden)
-				json.NewEncoder(w).Encode(map[string]string{
-					"error": "Invalid CSRF token",
-				})
+				metrics.CSRFFailures.Inc()
+				handlers.WriteError(w, r, apperror.Forbidden("Invalid CSRF token"))
 				return
 			}
 
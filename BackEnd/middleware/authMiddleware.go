@@ -3,8 +3,10 @@ package middleware
 import (
 	"net/http"
 
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
 	"github.com/Raymond9734/forum.git/BackEnd/controllers"
 	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
 	"github.com/Raymond9734/forum.git/BackEnd/logger"
 )
 
@@ -14,23 +16,23 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		// Check if the user is authenticated
 		sessionCookie, err := r.Cookie("session_token")
 		if err != nil || sessionCookie == nil || sessionCookie.Value == "" {
-			logger.Warning("Unauthorized attempt  nil sessionCookie - remote_addr: %s, method: %s, path: %s",
+			logger.WarningContext(r.Context(), "Unauthorized attempt  nil sessionCookie - remote_addr: %s, method: %s, path: %s",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 			)
-			http.Redirect(w, r, "/login_Page", http.StatusSeeOther)
+			redirectToLogin(w, r)
 			return
 		}
 
 		// Validating the session token
 		if _, valid := controllers.IsValidSession(database.GloabalDB, sessionCookie.Value); !valid {
-			logger.Warning("Unauthorized attempt  Invalid Session - remote_addr: %s, method: %s, path: %s",
+			logger.WarningContext(r.Context(), "Unauthorized attempt  Invalid Session - remote_addr: %s, method: %s, path: %s",
 				r.RemoteAddr,
 				r.Method,
 				r.URL.Path,
 			)
-			http.Redirect(w, r, "/login_Page", http.StatusSeeOther)
+			redirectToLogin(w, r)
 			return
 		}
 
@@ -39,6 +41,16 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// redirectToLogin sends pages to the login page. Scripts and API clients cannot follow the
+// redirect usefully, so they get a 401 problem instead
+func redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	if !handlers.WantsHTML(r) {
+		handlers.WriteError(w, r, apperror.Unauthorized("Must be logged in"))
+		return
+	}
+	http.Redirect(w, r, "/login_Page", http.StatusSeeOther)
+}
+
 // Middleware chain to apply multiple middleware functions
 func ApplyMiddleware(handler http.Handler, middlewares ...func(http.Handler) http.Handler) http.Handler {
 	for _, middleware := range middlewares {
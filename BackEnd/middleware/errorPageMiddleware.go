@@ -3,33 +3,49 @@ package middleware
 import (
 	"net/http"
 
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
 	"github.com/Raymond9734/forum.git/BackEnd/handlers"
 )
 
+// responseWriter holds back an error status until the handler writes a body, so ErrorHandler can
+// still answer errors the handler gave no body
 type responseWriter struct {
 	http.ResponseWriter
-	status int
+	status    int
+	flushed   bool
+	wroteBody bool
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
+	if rw.status != 0 {
+		return
+	}
 	rw.status = code
-	rw.ResponseWriter.WriteHeader(code)
+	if code < 400 {
+		rw.flushed = true
+		rw.ResponseWriter.WriteHeader(code)
+	}
 }
 
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.WriteHeader(http.StatusOK)
+	if !rw.flushed {
+		rw.flushed = true
+		rw.ResponseWriter.WriteHeader(rw.status)
+	}
+	rw.wroteBody = true
+	return rw.ResponseWriter.Write(b)
+}
+
+// ErrorHandler answers error statuses written without a body with the error page or problem+json,
+// whichever the request asks for. Error responses that have a body are left alone
 func ErrorHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		rw := &responseWriter{ResponseWriter: w}
 		next.ServeHTTP(rw, r)
 
-		if rw.status >= 400 {
-			switch rw.status {
-			case http.StatusNotFound:
-				handlers.ServeErrorPage(w, rw.status, "Page Not Found", "Oops! The page you're looking for doesn't exist.")
-			case http.StatusInternalServerError:
-				handlers.ServeErrorPage(w, rw.status, "Internal Server Error", "Something went wrong.")
-			default:
-				handlers.ServeErrorPage(w, rw.status, "Error", "An unexpected error occurred.")
-			}
+		if rw.status >= 400 && !rw.wroteBody {
+			handlers.WriteError(w, r, apperror.FromStatus(rw.status, ""))
 		}
 	})
 }
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/metrics"
+)
+
+// InstrumentRoute counts the requests of a route and how long they take. route is the pattern the
+// route was registered with, so the metrics have one series per route rather than per URL
+func InstrumentRoute(route string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r)
+			metrics.ObserveRequest(r.Method, route, sw.status, time.Since(start))
+		})
+	}
+}
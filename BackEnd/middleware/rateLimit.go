@@ -4,6 +4,10 @@ import (
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
+	"github.com/Raymond9734/forum.git/BackEnd/metrics"
 )
 
 type visitor struct {
@@ -66,7 +70,8 @@ func (rl *RateLimiter) RateLimit(next http.Handler) http.Handler {
 
 		if v.count > rl.rate {
 			rl.mu.Unlock()
-			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			metrics.RateLimitRejections.Inc()
+			handlers.WriteError(w, r, apperror.RateLimited("Rate limit exceeded"))
 			return
 		}
 		rl.mu.Unlock()
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+)
+
+// RequireRole is a middleware factory that only lets users holding one of the given roles through
+func RequireRole(db *sql.DB, roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sessionCookie, err := r.Cookie("session_token")
+			if err == nil {
+				if userID, valid := controllers.IsValidSession(db, sessionCookie.Value); valid && controllers.HasRole(db, userID, roles...) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			logger.WarningContext(r.Context(), "Forbidden attempt to access restricted route - remote_addr: %s, method: %s, path: %s",
+				r.RemoteAddr,
+				r.Method,
+				r.URL.Path,
+			)
+			handlers.WriteError(w, r, apperror.Forbidden("You are not allowed to access this resource"))
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package models
+
+import "time"
+
+// The resources of the versioned JSON API. The OpenAPI document served with the API is generated
+// from these types, so their JSON tags are the contract; a field tagged omitempty is optional
+
+// APIPagination describes the page of a list response
+type APIPagination struct {
+	Page    int  `json:"page"`
+	Limit   int  `json:"limit"`
+	HasMore bool `json:"hasMore"`
+}
+
+type APIPost struct {
+	ID         int      `json:"id"`
+	Title      string   `json:"title"`
+	Content    string   `json:"content"`
+	AuthorID   int      `json:"authorId"`
+	Author     string   `json:"author"`
+	Categories []string `json:"categories"`
+	// ImageURL is null for posts without an image
+	ImageURL     *string `json:"imageUrl"`
+	Likes        int     `json:"likes"`
+	Dislikes     int     `json:"dislikes"`
+	CommentCount int     `json:"commentCount"`
+	IsPinned     bool    `json:"isPinned"`
+	IsLocked     bool    `json:"isLocked"`
+	IsArchived   bool    `json:"isArchived"`
+	IsQuestion   bool    `json:"isQuestion"`
+	// AcceptedAnswerID is null unless the post is a question with an accepted answer
+	AcceptedAnswerID *int      `json:"acceptedAnswerId"`
+	CreatedAt        time.Time `json:"createdAt"`
+}
+
+type APIComment struct {
+	ID     int `json:"id"`
+	PostID int `json:"postId"`
+	// ParentID is null for top level comments
+	ParentID *int `json:"parentId"`
+	// AuthorID is 0 and Author a placeholder for deleted comments and hidden authors
+	AuthorID   int       `json:"authorId"`
+	Author     string    `json:"author"`
+	Content    string    `json:"content"`
+	Likes      int       `json:"likes"`
+	Dislikes   int       `json:"dislikes"`
+	ReplyCount int       `json:"replyCount"`
+	IsDeleted  bool      `json:"isDeleted"`
+	IsAccepted bool      `json:"isAccepted"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type APICategory struct {
+	Name      string `json:"name"`
+	PostCount int    `json:"postCount"`
+	// IsQuestion reports whether posts in the category are Q&A posts that can accept an answer
+	IsQuestion bool `json:"isQuestion"`
+}
+
+type APIUser struct {
+	ID         int    `json:"id"`
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+	Reputation int    `json:"reputation"`
+}
+
+// APIVote is a vote the viewer cast
+type APIVote struct {
+	TargetType string `json:"targetType"`
+	TargetID   int    `json:"targetId"`
+	Vote       string `json:"vote"`
+}
+
+// APIVoteRequest casts a vote; casting the same vote again removes it
+type APIVoteRequest struct {
+	TargetType string `json:"targetType"`
+	TargetID   int    `json:"targetId"`
+	Vote       string `json:"vote"`
+}
+
+// APIVoteResult is the outcome of a vote; Vote is empty once the vote was removed
+type APIVoteResult struct {
+	TargetType string `json:"targetType"`
+	TargetID   int    `json:"targetId"`
+	Likes      int    `json:"likes"`
+	Dislikes   int    `json:"dislikes"`
+	Vote       string `json:"vote"`
+}
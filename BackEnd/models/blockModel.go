@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Kinds of user block list entries
+const (
+	// BlockKindBlock hides the user's content and stops them replying to or messaging the blocker
+	BlockKindBlock = "block"
+	// BlockKindMute only hides the user's content
+	BlockKindMute = "mute"
+)
+
+// BlockedUser is an entry of a user's block list
+type BlockedUser struct {
+	UserID    int       `json:"userId"`
+	Username  string    `json:"username"`
+	Kind      string    `json:"kind"`
+	CreatedAt time.Time `json:"createdAt"`
+}
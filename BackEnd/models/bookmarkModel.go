@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Bookmark target types
+const (
+	BookmarkPost    = "post"
+	BookmarkComment = "comment"
+)
+
+const (
+	// BookmarkPageSize is the number of bookmarks shown per page of /saved
+	BookmarkPageSize = 20
+	// BookmarkFolderMaxChars limits the length of a folder name
+	BookmarkFolderMaxChars = 50
+)
+
+// Bookmark is a post or comment a user saved to read later
+type Bookmark struct {
+	ID        int       `json:"id"`
+	Type      string    `json:"type"`
+	TargetID  int       `json:"targetId"`
+	PostID    int       `json:"postId"`
+	Title     string    `json:"title"`
+	Author    string    `json:"author"`
+	Excerpt   string    `json:"excerpt"`
+	Folder    string    `json:"folder"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// BookmarkPage is one page of a user's bookmarks
+type BookmarkPage struct {
+	Bookmarks  []Bookmark `json:"bookmarks"`
+	Folder     string     `json:"folder"`
+	Folders    []string   `json:"folders"`
+	Page       int        `json:"page"`
+	TotalPages int        `json:"totalPages"`
+}
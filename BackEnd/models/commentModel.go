@@ -5,6 +5,50 @@ import (
 	"time"
 )
 
+// DeletedPlaceholder replaces the author and content of deleted comments
+const DeletedPlaceholder = "[deleted]"
+
+// HiddenPlaceholder replaces the author and content of comments by users the viewer blocked or muted
+const HiddenPlaceholder = "[hidden]"
+
+// Comment nesting limits
+const (
+	// MaxCommentDepth caps how deeply replies can nest
+	MaxCommentDepth = 100
+	// ThreadDisplayDepth is how many levels of a thread are rendered before "continue this thread"
+	ThreadDisplayDepth = 6
+	// MaxQuoteChars caps the excerpt a quote-reply embeds
+	MaxQuoteChars = 300
+)
+
+// Comment paging
+const (
+	// CommentsPageSize is how many top level comments a page of a post shows
+	CommentsPageSize = 20
+	// RepliesPreview is how many replies of each comment a page shows before "show more replies"
+	RepliesPreview = 3
+	// RepliesPageSize is how many replies each "show more replies" loads
+	RepliesPageSize = 10
+)
+
+// Comment orders
+const (
+	CommentSortBest = "best"
+	CommentSortNew  = "new"
+	CommentSortOld  = "old"
+)
+
+// CommentSorts lists the comment orders in the order they are shown to users
+var CommentSorts = []string{CommentSortBest, CommentSortNew, CommentSortOld}
+
+// QuotedComment is the excerpt of the parent comment embedded in a quote-reply
+type QuotedComment struct {
+	ID      int    `json:"id"`
+	UserID  int    `json:"userId"`
+	Author  string `json:"author"`
+	Excerpt string `json:"excerpt"`
+}
+
 // Comment represents a comment on a post
 type Comment struct {
 	ID        int
@@ -19,9 +63,38 @@ type Comment struct {
 	Timestamp time.Time
 	Replies   []Comment `json:"replies,omitempty"`
 	Depth     int       `json:"depth"`
+	// IsDeleted marks a "[deleted]" placeholder kept so that its replies stay in the tree
+	IsDeleted bool `json:"isDeleted"`
+	// IsBookmarked reports whether the viewer saved the comment
+	IsBookmarked bool `json:"isBookmarked"`
+	// IsHidden marks a "[hidden]" placeholder for a comment by a user the viewer blocked or muted
+	IsHidden bool `json:"isHidden"`
+	// Quote is set on quote-replies
+	Quote *QuotedComment `json:"quote,omitempty"`
+	// ReplyCount counts the direct replies to show, loaded or not
+	ReplyCount int `json:"replyCount"`
+	// MoreReplies counts the direct replies that are not loaded in Replies
+	MoreReplies int `json:"moreReplies"`
+	// ContinueThread is set on comments on the last level shown; their replies open as a new page
+	ContinueThread bool `json:"continueThread"`
+	// IsAccepted marks the accepted answer of a Q&A post
+	IsAccepted bool `json:"isAccepted"`
+	// AuthorReputation is the reputation of the author, shown next to their name
+	AuthorReputation int `json:"authorReputation"`
+	// Reactions counts every configured reaction on the comment, in the configured order
+	Reactions []ReactionCount `json:"reactions"`
+}
+
+// CommentPage is one page of the replies to a comment, or of the top level comments of a post
+type CommentPage struct {
+	Comments   []Comment `json:"comments"`
+	HasMore    bool      `json:"hasMore"`
+	NextOffset int       `json:"nextOffset"`
 }
 
 type CommentRequest struct {
 	Content  string `json:"content"`
 	ParentID int    `json:"parentId,omitempty"`
+	// Quote is an excerpt of the parent comment to embed in the reply
+	Quote string `json:"quote,omitempty"`
 }
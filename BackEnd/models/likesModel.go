@@ -1,7 +1,33 @@
 package models
 
+// Vote values
+const (
+	VoteLike    = "like"
+	VoteDislike = "dislike"
+)
+
+// Kinds of things that can be voted on
+const (
+	VoteTargetPost    = "post"
+	VoteTargetComment = "comment"
+)
+
 type Likes struct {
 	PostId   int
 	UserId   int
 	UserVote string
 }
+
+// VoteTally is the outcome of a vote: the target's new counts and the voter's vote, empty once removed
+type VoteTally struct {
+	Likes    int    `json:"likes"`
+	Dislikes int    `json:"dislikes"`
+	UserVote string `json:"userVote"`
+}
+
+// UserVote is a vote a user cast on a post or comment
+type UserVote struct {
+	TargetType string
+	TargetID   int
+	Vote       string
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Kinds of content a mention can appear in
+const (
+	MentionSourcePost    = "post"
+	MentionSourceComment = "comment"
+)
+
+// MaxUsernameSuggestions caps how many usernames the mention autocomplete returns
+const MaxUsernameSuggestions = 10
+
+// UserProfile is the public view of a user shown on their profile page
+type UserProfile struct {
+	ID         int
+	Username   string
+	Role       string
+	Reputation int
+	Posts      []Post
+}
+
+// Mention records that a user was @mentioned in a post or comment
+type Mention struct {
+	SourceType string    `json:"sourceType"`
+	SourceID   int       `json:"sourceId"`
+	PostID     int       `json:"postId"`
+	UserID     int       `json:"userId"`
+	AuthorID   int       `json:"authorId"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
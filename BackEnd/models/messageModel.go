@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+const (
+	// MaxConversationMembers caps group conversations, the sender included
+	MaxConversationMembers = 8
+	// MaxMessageChars limits the length of a single message
+	MaxMessageChars = 2000
+	// MaxSubjectChars limits the length of a conversation subject
+	MaxSubjectChars = 100
+	// MessagesPageSize is the number of messages loaded at a time
+	MessagesPageSize = 50
+)
+
+// ConversationMember is a participant of a conversation
+type ConversationMember struct {
+	UserID   int    `json:"userId"`
+	Username string `json:"username"`
+}
+
+// Conversation is a private one-to-one or group thread as seen by one of its members
+type Conversation struct {
+	ID          int                  `json:"id"`
+	Subject     string               `json:"subject"`
+	Members     []ConversationMember `json:"members"`
+	LastMessage string               `json:"lastMessage"`
+	UpdatedAt   time.Time            `json:"updatedAt"`
+	Unread      int                  `json:"unread"`
+}
+
+// Message is a single private message; AttachmentURL is a signed URL of its private attachment
+// that is only valid for a limited time
+type Message struct {
+	ID             int       `json:"id"`
+	ConversationID int       `json:"conversationId"`
+	SenderID       int       `json:"senderId"`
+	Sender         string    `json:"sender"`
+	Content        string    `json:"content"`
+	AttachmentURL  string    `json:"attachmentUrl,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Poll limits
+const (
+	PollMinOptions     = 2
+	PollMaxOptions     = 10
+	PollMaxOptionChars = 200
+)
+
+// Poll is an optional vote attached to a post
+type Poll struct {
+	ID             int          `json:"id"`
+	PostID         int          `json:"postId"`
+	Question       string       `json:"question"`
+	MultipleChoice bool         `json:"multipleChoice"`
+	ClosesAt       *time.Time   `json:"closesAt,omitempty"`
+	Options        []PollOption `json:"options"`
+	TotalVoters    int          `json:"totalVoters"`
+	HasVoted       bool         `json:"hasVoted"`
+	IsClosed       bool         `json:"isClosed"`
+	// ResultsVisible is false until the viewer has voted or the poll has closed;
+	// vote counts are zeroed while it is false
+	ResultsVisible bool `json:"resultsVisible"`
+}
+
+// PollOption is one of the choices of a poll
+type PollOption struct {
+	ID      int    `json:"id"`
+	Text    string `json:"text"`
+	Votes   int    `json:"votes"`
+	Percent int    `json:"percent"`
+	Chosen  bool   `json:"chosen"`
+}
@@ -23,6 +23,22 @@ type Post struct {
 	Timestamp time.Time
 	Comments  []Comment
 	CommentCount int
+	Poll      *Poll
+	// Thread state set by moderators; an empty PinnedCategory pins the post globally
+	IsPinned       bool
+	PinnedCategory string
+	IsLocked       bool
+	IsArchived     bool
+	// IsBookmarked reports whether the viewer saved the post
+	IsBookmarked bool
+	// IsQuestion marks posts in a Q&A category; they are solved once AcceptedCommentID is set
+	IsQuestion        bool
+	AcceptedCommentID int
+	AcceptedAnswer    *Comment
+	// AuthorReputation is the reputation of the author, shown next to their name
+	AuthorReputation int
+	// Reactions counts every configured reaction on the post, in the configured order
+	Reactions []ReactionCount
 }
 
 type PostRequest struct {
@@ -30,3 +46,9 @@ type PostRequest struct {
 	Content    string `json:"content"`
 	Categories string `json:"category"`
 }
+
+// Category is a post category with the number of posts filed under it
+type Category struct {
+	Name      string
+	PostCount int
+}
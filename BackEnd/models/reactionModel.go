@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Reaction target types
+const (
+	ReactionPost    = "post"
+	ReactionComment = "comment"
+)
+
+// ReactorsPageSize caps how many users the "who reacted" listing returns
+const ReactorsPageSize = 100
+
+// DefaultReactions are the reactions offered when FORUM_REACTIONS is not set
+var DefaultReactions = []string{"👍", "❤️", "😂", "🎉", "😮"}
+
+// ReactionCount is how many users reacted to a post or comment with one reaction, and whether
+// the viewer is one of them
+type ReactionCount struct {
+	Reaction string `json:"reaction"`
+	Count    int    `json:"count"`
+	Reacted  bool   `json:"reacted"`
+}
+
+// Reactor is a user who reacted to a post or comment
+type Reactor struct {
+	UserID    int       `json:"userId"`
+	Username  string    `json:"username"`
+	Reaction  string    `json:"reaction"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Reasons recorded in the reputation ledger
+const (
+	// ReputationVote is the reputation a vote gave the author of the post or comment
+	ReputationVote = "vote"
+	// ReputationUnvote reverses the reputation of a vote that was removed or changed
+	ReputationUnvote = "unvote"
+)
+
+// ReputationWeight is the reputation a vote on a post or comment gives its author, configured
+// through the environment variable Env
+type ReputationWeight struct {
+	TargetType string
+	Vote       string
+	Env        string
+	Default    int
+}
+
+// ReputationWeights lists the weight of every kind of vote
+var ReputationWeights = []ReputationWeight{
+	{VoteTargetPost, VoteLike, "FORUM_REP_POST_LIKE", 10},
+	{VoteTargetPost, VoteDislike, "FORUM_REP_POST_DISLIKE", -2},
+	{VoteTargetComment, VoteLike, "FORUM_REP_COMMENT_LIKE", 5},
+	{VoteTargetComment, VoteDislike, "FORUM_REP_COMMENT_DISLIKE", -1},
+}
+
+// Privilege is an action unlocked once a user's reputation reaches the threshold configured
+// through the environment variable Env; moderators and admins always have it
+type Privilege struct {
+	Name        string
+	Description string
+	Env         string
+	Default     int
+}
+
+// Downvoting needs no reputation by default, so that existing users can keep disliking
+var (
+	PrivilegePostImages = Privilege{"post_images", "post images", "FORUM_REP_POST_IMAGES", 10}
+	PrivilegeDownvote   = Privilege{"downvote", "downvote", "FORUM_REP_DOWNVOTE", 0}
+)
+
+// Privileges lists the privileges in the order they are shown to users
+var Privileges = []Privilege{PrivilegePostImages, PrivilegeDownvote}
+
+// UnlockedPrivilege is a privilege with its threshold, as shown on a profile
+type UnlockedPrivilege struct {
+	Description string
+	Required    int
+	Unlocked    bool
+}
+
+// ReputationEntry is one change in the reputation ledger
+type ReputationEntry struct {
+	ID         int
+	UserID     int
+	VoterID    int
+	TargetType string
+	TargetID   int
+	PostID     int
+	Vote       string
+	Reason     string
+	Delta      int
+	CreatedAt  time.Time
+}
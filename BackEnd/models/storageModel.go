@@ -0,0 +1,11 @@
+package models
+
+// StorageUsage summarizes the uploads charged to a user
+type StorageUsage struct {
+	UserID    int    `json:"userId"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	FileCount int    `json:"fileCount"`
+	BytesUsed int64  `json:"bytesUsed"`
+	Quota     int64  `json:"quota"` // 0 means unlimited
+}
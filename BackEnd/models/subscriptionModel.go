@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Watch levels of a subscription
+const (
+	// WatchAll delivers every new post or comment in the thread or category
+	WatchAll = "all"
+	// WatchReplies delivers only replies to the subscriber's own posts and comments
+	WatchReplies = "replies"
+	// WatchMuted delivers nothing, even where another subscription would
+	WatchMuted = "muted"
+)
+
+// WatchLevels lists the levels in the order they are offered to users
+var WatchLevels = []string{WatchAll, WatchReplies, WatchMuted}
+
+// Subscription follows a post or, when PostID is 0, a category
+type Subscription struct {
+	ID        int       `json:"id"`
+	PostID    int       `json:"postId,omitempty"`
+	PostTitle string    `json:"postTitle,omitempty"`
+	Category  string    `json:"category,omitempty"`
+	Level     string    `json:"level"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SubscriptionEvent is an event fanned out to one subscriber
+type SubscriptionEvent struct {
+	ID        int       `json:"id"`
+	Type      string    `json:"type"`
+	ActorID   int       `json:"actorId"`
+	PostID    int       `json:"postId"`
+	CommentID int       `json:"commentId,omitempty"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+}
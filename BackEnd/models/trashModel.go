@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Trash item types
+const (
+	TrashPost    = "post"
+	TrashComment = "comment"
+)
+
+// TrashItem is a post or comment deleted by its author that can still be restored
+type TrashItem struct {
+	Type      string    `json:"type"`
+	ID        int       `json:"id"`
+	PostID    int       `json:"postId"`
+	Title     string    `json:"title"`
+	Excerpt   string    `json:"excerpt"`
+	DeletedAt time.Time `json:"deletedAt"`
+	PurgeAt   time.Time `json:"purgeAt"`
+}
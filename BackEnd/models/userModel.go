@@ -1,10 +1,18 @@
 package models
 
+// User roles, from least to most privileged
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
 	ID       int
 	Email    string
 	Username string
 	Password string
+	Role     string
 }
 type RegisterRequest struct {
 	Email    string `json:"email"`
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Kinds of suspicious voting the analyzer flags
+const (
+	// FlagSharedFingerprint marks a voter sharing an IP address or device with the author, or with
+	// another account voting for the author
+	FlagSharedFingerprint = "shared_fingerprint"
+	// FlagNewAccountBurst marks a voter among many new accounts voting for an author within a short window
+	FlagNewAccountBurst = "new_account_burst"
+	// FlagReciprocalVoting marks two users who keep liking each other's posts and comments
+	FlagReciprocalVoting = "reciprocal_voting"
+)
+
+// Vote flag statuses
+const (
+	FlagOpen      = "open"
+	FlagDismissed = "dismissed"
+	FlagNullified = "nullified"
+)
+
+// Kinds of fingerprints recorded for users; only hashes are stored
+const (
+	FingerprintIP     = "ip"
+	FingerprintDevice = "device"
+)
+
+// VoteFlag reports the votes one user cast for another as suspected manipulation
+type VoteFlag struct {
+	ID       int    `json:"id"`
+	Kind     string `json:"kind"`
+	VoterID  int    `json:"voterId"`
+	Voter    string `json:"voter"`
+	AuthorID int    `json:"authorId"`
+	Author   string `json:"author"`
+	// Votes counts the likes the voter gave the author's posts and comments when last analyzed
+	Votes     int       `json:"votes"`
+	Detail    string    `json:"detail"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,119 @@
+// Package ranking implements the feed orderings offered on the homepage.
+//
+// Scores that depend only on a post's votes and age (hot, controversial) are stored on
+// the post and recomputed whenever its votes change, so listing a feed is a plain
+// indexed ORDER BY rather than a per-request computation.
+package ranking
+
+import (
+	"math"
+	"time"
+)
+
+// Sort orders
+const (
+	SortHot           = "hot"
+	SortNew           = "new"
+	SortTop           = "top"
+	SortControversial = "controversial"
+	SortComments      = "comments"
+)
+
+// Periods for SortTop
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+	PeriodYear  = "year"
+	PeriodAll   = "all"
+)
+
+// DefaultSort is used when no valid sort is requested
+const DefaultSort = SortNew
+
+// Sorts lists the available orders in the order they are shown to users
+var Sorts = []string{SortHot, SortNew, SortTop, SortControversial, SortComments}
+
+// Periods lists the available SortTop periods
+var Periods = []string{PeriodDay, PeriodWeek, PeriodMonth, PeriodYear, PeriodAll}
+
+// epoch anchors hot scores; only differences between scores matter
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// hotHalfLife is the age difference that outweighs a tenfold difference in net votes
+const hotHalfLife = 12.5 * float64(time.Hour/time.Second)
+
+// Hot ranks a post by the order of magnitude of its net votes plus a bonus for recency,
+// so newer posts need fewer votes to outrank older ones
+func Hot(likes, dislikes int, created time.Time) float64 {
+	score := float64(likes - dislikes)
+	order := math.Log10(math.Max(math.Abs(score), 1))
+	sign := 0.0
+	if score > 0 {
+		sign = 1
+	} else if score < 0 {
+		sign = -1
+	}
+	seconds := created.Sub(epoch).Seconds()
+	return sign*order + seconds/hotHalfLife
+}
+
+// Controversy is high for posts with many votes split evenly between likes and dislikes
+func Controversy(likes, dislikes int) float64 {
+	if likes <= 0 || dislikes <= 0 {
+		return 0
+	}
+	magnitude := float64(likes + dislikes)
+	balance := float64(min(likes, dislikes)) / float64(max(likes, dislikes))
+	return math.Pow(magnitude, balance)
+}
+
+// ParseSort returns sort if it is a known order, DefaultSort otherwise
+func ParseSort(sort string) string {
+	for _, s := range Sorts {
+		if s == sort {
+			return s
+		}
+	}
+	return DefaultSort
+}
+
+// ParsePeriod returns period if it is a known SortTop period, PeriodAll otherwise
+func ParsePeriod(period string) string {
+	for _, p := range Periods {
+		if p == period {
+			return p
+		}
+	}
+	return PeriodAll
+}
+
+// Since returns the start of period relative to now; the zero time for PeriodAll
+func Since(period string, now time.Time) time.Time {
+	switch period {
+	case PeriodDay:
+		return now.AddDate(0, 0, -1)
+	case PeriodWeek:
+		return now.AddDate(0, 0, -7)
+	case PeriodMonth:
+		return now.AddDate(0, -1, 0)
+	case PeriodYear:
+		return now.AddDate(-1, 0, 0)
+	}
+	return time.Time{}
+}
+
+// OrderBy returns the SQL ordering of the posts table for sort
+func OrderBy(sort string) string {
+	switch sort {
+	case SortHot:
+		return "hot_score DESC, timestamp DESC"
+	case SortTop:
+		return "(likes - dislikes) DESC, likes DESC, timestamp DESC"
+	case SortControversial:
+		return "controversy_score DESC, timestamp DESC"
+	case SortComments:
+		return "comment_count DESC, timestamp DESC"
+	}
+	return "timestamp DESC"
+}
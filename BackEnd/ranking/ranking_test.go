@@ -0,0 +1,58 @@
+package ranking
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHot(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		higher float64
+		lower  float64
+	}{
+		{"more votes rank higher at the same age", Hot(100, 0, now), Hot(10, 0, now)},
+		{"newer posts rank higher with the same votes", Hot(10, 0, now), Hot(10, 0, now.Add(-time.Hour))},
+		{"a day of age outweighs a tenfold vote lead", Hot(10, 0, now), Hot(100, 0, now.Add(-24*time.Hour))},
+		{"disliked posts sink", Hot(0, 0, now), Hot(0, 10, now)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.higher <= tt.lower {
+				t.Errorf("expected %v > %v", tt.higher, tt.lower)
+			}
+		})
+	}
+}
+
+func TestControversy(t *testing.T) {
+	if got := Controversy(50, 0); got != 0 {
+		t.Errorf("Controversy(50, 0) = %v, want 0 for one-sided votes", got)
+	}
+	if got := Controversy(10, 10); got != 20 {
+		t.Errorf("Controversy(10, 10) = %v, want 20", got)
+	}
+	if Controversy(30, 10) >= Controversy(20, 20) {
+		t.Errorf("an uneven split should be less controversial than an even one with as many votes")
+	}
+	if Controversy(10, 10) >= Controversy(100, 100) {
+		t.Errorf("more votes should be more controversial at the same balance")
+	}
+}
+
+func TestParseSortAndPeriod(t *testing.T) {
+	if got := ParseSort("controversial"); got != SortControversial {
+		t.Errorf("ParseSort(controversial) = %q", got)
+	}
+	if got := ParseSort("bogus"); got != DefaultSort {
+		t.Errorf("ParseSort(bogus) = %q, want %q", got, DefaultSort)
+	}
+	if got := ParsePeriod(""); got != PeriodAll {
+		t.Errorf("ParsePeriod(\"\") = %q, want %q", got, PeriodAll)
+	}
+	now := time.Now()
+	if !Since(PeriodAll, now).IsZero() || !Since(PeriodWeek, now).Equal(now.AddDate(0, 0, -7)) {
+		t.Errorf("Since() returned unexpected bounds")
+	}
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"database/sql"
+
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+func AdminRoutes(rt *Router, db *sql.DB) {
+	admins := staff(rt, db, models.RoleAdmin)
+	admins.Handle("GET /admin/storage", handlers.StorageReportHandler(db))
+	admins.Handle("GET /admin/log-level", handlers.LogLevelHandler())
+	admins.Handle("PUT /admin/log-level", handlers.LogLevelHandler())
+}
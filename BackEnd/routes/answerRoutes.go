@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
+	"github.com/Raymond9734/forum.git/BackEnd/middleware"
+)
+
+func AnswerRoutes(rt *Router, db *sql.DB) {
+	PostController := controllers.NewPostController(db)
+
+	answerLimiter := middleware.NewRateLimiter(30, time.Minute) // 30 accepted answer changes per minute
+
+	members(rt, db).Handle("POST /answer/accept", handlers.AcceptAnswerHandler(PostController), answerLimiter.RateLimit)
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
+	"github.com/Raymond9734/forum.git/BackEnd/middleware"
+)
+
+// APIRoutes serves the versioned JSON API. It answers authentication, CSRF and routing errors
+// itself with JSON error responses, so it skips the middleware that would reply with pages
+func APIRoutes(rt *Router, db *sql.DB) {
+	limiter := middleware.NewRateLimiter(120, time.Minute) // 120 API requests per minute
+
+	api := rt.Group(middleware.SetCSPHeaders, middleware.CORSMiddleware)
+	api.Handle(handlers.APIPrefix+"/", handlers.APIHandler(db), limiter.RateLimit)
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"database/sql"
+
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
+)
+
+func BlockRoutes(rt *Router, db *sql.DB) {
+	authed := members(rt, db)
+	authed.Handle("POST /block", handlers.BlockUserHandler(db))
+	authed.Handle("GET /blocked", handlers.BlockedUsersPageHandler(db))
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
+	"github.com/Raymond9734/forum.git/BackEnd/middleware"
+)
+
+func BookmarkRoutes(rt *Router, db *sql.DB) {
+	BookmarkController := controllers.NewBookmarkController(db)
+
+	bookmarkLimiter := middleware.NewRateLimiter(60, time.Minute) // 60 bookmark changes per minute
+
+	authed := members(rt, db)
+	authed.Handle("POST /bookmark", handlers.ToggleBookmarkHandler(BookmarkController), bookmarkLimiter.RateLimit)
+	authed.Handle("POST /bookmark/folder", handlers.MoveBookmarkHandler(BookmarkController), bookmarkLimiter.RateLimit)
+	authed.Handle("GET /saved", handlers.SavedPageHandler(BookmarkController))
+}
@@ -2,7 +2,6 @@ package routes
 
 import (
 	"database/sql"
-	"net/http"
 	"time"
 
 	"github.com/Raymond9734/forum.git/BackEnd/controllers"
@@ -10,39 +9,24 @@ import (
 	"github.com/Raymond9734/forum.git/BackEnd/middleware"
 )
 
-func CommentRoute(db *sql.DB) {
+func CommentRoute(rt *Router, db *sql.DB) {
 	commentController := controllers.NewCommentController(db)
 
 	// Rate limit for comments
 	commentLimiter := middleware.NewRateLimiter(10, time.Minute) // 10 comments per minute
+	pageLimiter := middleware.NewRateLimiter(120, time.Minute)   // 120 comment pages per minute
 
-	http.Handle("/comment/", middleware.ApplyMiddleware(
-		handlers.CommentHandler(commentController),
-		middleware.SetCSPHeaders,
-		middleware.AuthMiddleware,
-		middleware.CORSMiddleware,
-		commentLimiter.RateLimit,
-		middleware.ErrorHandler,
-		middleware.VerifyCSRFMiddleware(db),
-	))
+	authed := members(rt, db)
+	authed.Handle("POST /posts/{id}/comments", handlers.CommentHandler(commentController), commentLimiter.RateLimit)
+	authed.Handle("PUT /comments/{id}", handlers.UpdateCommentHandler(commentController), commentLimiter.RateLimit)
+	// Edits were posted to /updateComment, so clients redirected from there still update with POST
+	authed.Handle("POST /comments/{id}", handlers.UpdateCommentHandler(commentController), commentLimiter.RateLimit)
+	authed.Handle("DELETE /comments/{id}", handlers.DeleteCommentHandler(commentController), commentLimiter.RateLimit)
 
-	http.Handle("/deleteComment", middleware.ApplyMiddleware(
-		handlers.DeleteCommentHandler(commentController),
-		middleware.SetCSPHeaders,
-		middleware.AuthMiddleware,
-		middleware.CORSMiddleware,
-		commentLimiter.RateLimit,
-		middleware.ErrorHandler,
-		middleware.VerifyCSRFMiddleware(db),
-	))
+	public(rt).Handle("GET /comments", handlers.CommentPageHandler(commentController), pageLimiter.RateLimit)
 
-	http.Handle("/updateComment", middleware.ApplyMiddleware(
-		handlers.UpdateCommentHandler(commentController),
-		middleware.SetCSPHeaders,
-		middleware.AuthMiddleware,
-		middleware.CORSMiddleware,
-		commentLimiter.RateLimit,
-		middleware.ErrorHandler,
-		middleware.VerifyCSRFMiddleware(db),
-	))
+	// Comments used to be created at /comment/{post id} and changed at /updateComment?id= and /deleteComment?id=
+	rt.Redirect("POST /comment/{id}", "/posts/{id}/comments")
+	rt.Redirect("POST /updateComment", "/comments/{id}")
+	rt.Redirect("DELETE /deleteComment", "/comments/{id}")
 }
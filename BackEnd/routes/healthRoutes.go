@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
+)
+
+// HealthRoutes serves the probes of load balancers and orchestrators. They skip the middleware of
+// pages so probing stays cheap; readiness checks give up after timeout
+func HealthRoutes(rt *Router, db *sql.DB, timeout time.Duration) {
+	rt.Handle("GET /healthz", handlers.HealthHandler())
+	rt.Handle("GET /readyz", handlers.ReadinessHandler(db, timeout))
+}
@@ -2,7 +2,6 @@ package routes
 
 import (
 	"database/sql"
-	"net/http"
 	"time"
 
 	"github.com/Raymond9734/forum.git/BackEnd/controllers"
@@ -10,7 +9,7 @@ import (
 	"github.com/Raymond9734/forum.git/BackEnd/middleware"
 )
 
-func LikesRoutes(db *sql.DB) {
+func LikesRoutes(rt *Router, db *sql.DB) {
 	// Create controllers
 	LikesController := controllers.NewLikesController(db)
 	CommentVotesController := controllers.NewCommentVotesController(db)
@@ -18,49 +17,14 @@ func LikesRoutes(db *sql.DB) {
 	// Rate limiter for likes
 	likesLimiter := middleware.NewRateLimiter(30, time.Minute) // 30 likes per minute
 
-	// Post vote routes
-	http.Handle("/likePost", middleware.ApplyMiddleware(
-		handlers.CreateUserVoteHandler(LikesController),
-		middleware.SetCSPHeaders,
-		middleware.AuthMiddleware,
-		middleware.CORSMiddleware,
-		likesLimiter.RateLimit,
-		middleware.ErrorHandler,
-		middleware.VerifyCSRFMiddleware(db),
-		middleware.ValidatePathAndMethod("/likePost", http.MethodPost),
-	))
+	authed := members(rt, db)
 
-	http.Handle("/getUserVotes", middleware.ApplyMiddleware(
-		handlers.GetUserVotesHandler(LikesController),
-		middleware.SetCSPHeaders,
-		middleware.AuthMiddleware,
-		middleware.CORSMiddleware,
-		middleware.ErrorHandler,
-		middleware.VerifyCSRFMiddleware(db),
-		middleware.ValidatePathAndMethod("/getUserVotes", http.MethodGet),
-	))
+	// Post vote routes
+	authed.Handle("POST /likePost", handlers.CreateUserVoteHandler(LikesController), likesLimiter.RateLimit)
+	authed.Handle("GET /getUserVotes", handlers.GetUserVotesHandler(LikesController))
+	authed.Handle("GET /getUserLikePosts", handlers.GetUserPostLikesHandler(LikesController), likesLimiter.RateLimit)
 
 	// Comment vote routes
-	http.Handle("/commentVote", middleware.ApplyMiddleware(
-		handlers.CreateCommentVoteHandler(CommentVotesController),
-		middleware.AuthMiddleware,
-		middleware.ErrorHandler,
-		middleware.VerifyCSRFMiddleware(db),
-		middleware.ValidatePathAndMethod("/commentVote", http.MethodPost),
-	))
-
-	http.Handle("/getUserCommentVotes", middleware.ApplyMiddleware(
-		handlers.GetUserCommentVotesHandler(CommentVotesController),
-		middleware.AuthMiddleware,
-		middleware.ErrorHandler,
-		middleware.ValidatePathAndMethod("/getUserCommentVotes", http.MethodGet),
-	))
-
-	http.Handle("/getUserLikePosts", middleware.ApplyMiddleware(
-		handlers.GetUserPostLikesHandler(LikesController),
-		middleware.AuthMiddleware,
-		middleware.ErrorHandler,
-		likesLimiter.RateLimit,
-		middleware.ValidatePathAndMethod("/getUserLikePosts", http.MethodGet),
-	))
+	authed.Handle("POST /commentVote", handlers.CreateCommentVoteHandler(CommentVotesController))
+	authed.Handle("GET /getUserCommentVotes", handlers.GetUserCommentVotesHandler(CommentVotesController))
 }
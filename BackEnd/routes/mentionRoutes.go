@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
+	"github.com/Raymond9734/forum.git/BackEnd/middleware"
+)
+
+func MentionRoutes(rt *Router, db *sql.DB) {
+	limiter := middleware.NewRateLimiter(120, time.Minute) // 120 lookups per minute
+
+	authed := members(rt, db)
+	authed.Handle("GET /api/users/suggest", handlers.SuggestUsernamesHandler(db), limiter.RateLimit)
+	authed.Handle("GET /profile", handlers.ProfilePageHandler(db))
+}
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
+	"github.com/Raymond9734/forum.git/BackEnd/middleware"
+)
+
+func MessageRoutes(rt *Router, db *sql.DB) {
+	MessageController := controllers.NewMessageController(db)
+
+	// Rate limit for sending private messages
+	sendLimiter := middleware.NewRateLimiter(20, time.Minute) // 20 messages per minute
+
+	// Less strict limit for reading
+	readLimiter := middleware.NewRateLimiter(120, time.Minute) // 120 reads per minute
+
+	authed := members(rt, db)
+	authed.Handle("GET /messages", handlers.MessagesPageHandler(MessageController))
+	authed.Handle("GET /messages/{id}", handlers.ConversationPageHandler(MessageController))
+	authed.Handle("GET /messages/list", handlers.ConversationsHandler(MessageController), readLimiter.RateLimit)
+	authed.Handle("GET /messages/history", handlers.MessageHistoryHandler(MessageController), readLimiter.RateLimit)
+	authed.Handle("GET /messages/unread", handlers.UnreadMessagesHandler(MessageController), readLimiter.RateLimit)
+	authed.Handle("POST /messages/new", handlers.StartConversationHandler(MessageController), sendLimiter.RateLimit)
+	authed.Handle("POST /messages/send", handlers.SendMessageHandler(MessageController), sendLimiter.RateLimit)
+
+	// Conversations used to be viewed at /messages/view?id=
+	rt.Redirect("GET /messages/view", "/messages/{id}")
+}
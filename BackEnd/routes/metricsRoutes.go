@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"database/sql"
+
+	"github.com/Raymond9734/forum.git/BackEnd/metrics"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+// MetricsRoutes serves the Prometheus metrics to admins. Scrapers without a session use the
+// separate listener of FORUM_METRICS_ADDR instead
+func MetricsRoutes(rt *Router, db *sql.DB) {
+	staff(rt, db, models.RoleAdmin).Handle("GET /metrics", metrics.Handler())
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"database/sql"
+
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
+	"github.com/Raymond9734/forum.git/BackEnd/models"
+)
+
+func ModerationRoutes(rt *Router, db *sql.DB) {
+	PostController := controllers.NewPostController(db)
+	VoteFlagController := controllers.NewVoteFlagController(db)
+
+	moderators := staff(rt, db, models.RoleModerator, models.RoleAdmin)
+	moderators.Handle("POST /moderation/thread", handlers.ThreadStateHandler(PostController))
+	moderators.Handle("GET /moderation/votes", handlers.VoteFlagsHandler(VoteFlagController))
+	moderators.Handle("POST /moderation/votes/resolve", handlers.ResolveVoteFlagHandler(VoteFlagController))
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
+	"github.com/Raymond9734/forum.git/BackEnd/middleware"
+)
+
+func PollRoutes(rt *Router, db *sql.DB) {
+	PollController := controllers.NewPollController(db)
+
+	// Tallies are polled by open pages, votes are rare
+	tallyLimiter := middleware.NewRateLimiter(60, time.Minute) // 60 requests per minute
+	voteLimiter := middleware.NewRateLimiter(30, time.Minute)  // 30 votes per minute
+
+	public(rt).Handle("GET /poll", handlers.GetPollHandler(PollController), tallyLimiter.RateLimit)
+	members(rt, db).Handle("POST /pollVote", handlers.PollVoteHandler(PollController), voteLimiter.RateLimit)
+}
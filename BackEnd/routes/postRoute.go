@@ -10,7 +10,7 @@ import (
 	"github.com/Raymond9734/forum.git/BackEnd/middleware"
 )
 
-func PostRoutes(db *sql.DB) {
+func PostRoutes(rt *Router, db *sql.DB) {
 	PostController := controllers.NewPostController(db)
 
 	// Rate limit for post creation
@@ -19,55 +19,16 @@ func PostRoutes(db *sql.DB) {
 	// Less strict limit for viewing
 	viewLimiter := middleware.NewRateLimiter(60, time.Minute) // 60 views per minute
 
-	http.Handle("/viewPost", middleware.ApplyMiddleware(
-		handlers.NewViewPostHandler(db),
-		middleware.SetCSPHeaders,
-		middleware.CORSMiddleware,
-		viewLimiter.RateLimit,
-		middleware.ErrorHandler,
-		middleware.ValidatePathAndMethod("/viewPost", http.MethodGet),
-	))
+	public(rt).Handle("GET /posts/{id}", handlers.NewViewPostHandler(db), viewLimiter.RateLimit)
 
-	http.Handle("/create-post", middleware.ApplyMiddleware(
-		http.HandlerFunc(handlers.CreatePostPageHandler),
-		middleware.SetCSPHeaders,
-		middleware.AuthMiddleware,
-		middleware.CORSMiddleware,
-		postLimiter.RateLimit,
-		middleware.ErrorHandler,
-		middleware.ValidatePathAndMethod("/create-post", http.MethodGet),
-	))
+	authed := members(rt, db)
+	authed.Handle("GET /create-post", http.HandlerFunc(handlers.CreatePostPageHandler), postLimiter.RateLimit)
+	authed.Handle("POST /createPost", handlers.CreatePostHandler(PostController), postLimiter.RateLimit)
+	authed.Handle("PUT /posts/{id}", handlers.UpdatePostHandler(PostController), postLimiter.RateLimit)
+	authed.Handle("DELETE /posts/{id}", handlers.DeletePostHandler(PostController), postLimiter.RateLimit)
 
-	http.Handle("/createPost", middleware.ApplyMiddleware(
-		handlers.CreatePostHandler(PostController),
-		middleware.SetCSPHeaders,
-		middleware.AuthMiddleware,
-		middleware.CORSMiddleware,
-		postLimiter.RateLimit,
-		middleware.ErrorHandler,
-		middleware.VerifyCSRFMiddleware(db),
-		middleware.ValidatePathAndMethod("/createPost", http.MethodPost),
-	))
-
-	http.Handle("/updatePost", middleware.ApplyMiddleware(
-		handlers.UpdatePostHandler(PostController),
-		middleware.SetCSPHeaders,
-		middleware.AuthMiddleware,
-		middleware.CORSMiddleware,
-		postLimiter.RateLimit,
-		middleware.ErrorHandler,
-		middleware.VerifyCSRFMiddleware(db),
-		middleware.ValidatePathAndMethod("/updatePost", http.MethodPut),
-	))
-
-	http.Handle("/deletePost", middleware.ApplyMiddleware(
-		handlers.DeletePostHandler(PostController),
-		middleware.SetCSPHeaders,
-		middleware.AuthMiddleware,
-		middleware.CORSMiddleware,
-		postLimiter.RateLimit,
-		middleware.ErrorHandler,
-		middleware.VerifyCSRFMiddleware(db),
-		middleware.ValidatePathAndMethod("/deletePost", http.MethodDelete),
-	))
+	// Posts used to be viewed at /viewPost?id= and changed at /updatePost?id= and /deletePost?id=
+	rt.Redirect("GET /viewPost", "/posts/{id}")
+	rt.Redirect("PUT /updatePost", "/posts/{id}")
+	rt.Redirect("DELETE /deletePost", "/posts/{id}")
 }
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
+	"github.com/Raymond9734/forum.git/BackEnd/middleware"
+)
+
+func ReactionRoutes(rt *Router, db *sql.DB) {
+	ReactionController := controllers.NewReactionController(db)
+
+	reactLimiter := middleware.NewRateLimiter(30, time.Minute)      // 30 reactions per minute
+	reactionsLimiter := middleware.NewRateLimiter(120, time.Minute) // 120 reaction lookups per minute
+
+	members(rt, db).Handle("POST /react", handlers.ReactHandler(ReactionController), reactLimiter.RateLimit)
+	public(rt).Handle("GET /reactions", handlers.ReactionsHandler(ReactionController), reactionsLimiter.RateLimit)
+}
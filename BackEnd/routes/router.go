@@ -0,0 +1,138 @@
+package routes
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Raymond9734/forum.git/BackEnd/apperror"
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
+	"github.com/Raymond9734/forum.git/BackEnd/middleware"
+)
+
+// Router registers routes on a ServeMux with method and wildcard patterns such as "GET /posts/{id}",
+// wrapping each in the middleware of the group it is registered in
+type Router struct {
+	mux        *http.ServeMux
+	middleware []func(http.Handler) http.Handler
+}
+
+func NewRouter() *Router {
+	return &Router{mux: http.NewServeMux()}
+}
+
+// Group returns a router registering on the same mux whose routes are wrapped in middlewares as
+// well as in the router's own. As with ApplyMiddleware, the first middleware listed runs last
+func (rt *Router) Group(middlewares ...func(http.Handler) http.Handler) *Router {
+	group := &Router{mux: rt.mux}
+	group.middleware = append(group.middleware, middlewares...)
+	group.middleware = append(group.middleware, rt.middleware...)
+	return group
+}
+
+// Handle registers handler for pattern, wrapped in middlewares specific to the route and then in
+// the group's middleware. The requests of the route are counted in the metrics under its path pattern
+func (rt *Router) Handle(pattern string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) {
+	chain := append(append([]func(http.Handler) http.Handler{}, middlewares...), rt.middleware...)
+	chain = append(chain, middleware.InstrumentRoute(routePath(pattern)))
+	rt.mux.Handle(pattern, middleware.ApplyMiddleware(handler, chain...))
+}
+
+// routePath is the path of a pattern such as "GET /posts/{id}"
+func routePath(pattern string) string {
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return strings.TrimSpace(path)
+	}
+	return pattern
+}
+
+// Redirect permanently redirects a legacy URL to the route that replaced it, so bookmarks and old
+// links keep working. A "{id}" in target is filled from the id wildcard of pattern or else from the
+// id query parameter; the other query parameters are kept. Requests other than GET are redirected
+// with 308 so they are repeated with the same method and body
+func (rt *Router) Redirect(pattern, target string) {
+	rt.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		id := r.PathValue("id")
+		if id == "" {
+			id = query.Get("id")
+			query.Del("id")
+		}
+		if strings.Contains(target, "{id}") && id == "" {
+			handlers.WriteError(w, r, apperror.NotFound(""))
+			return
+		}
+
+		location := strings.ReplaceAll(target, "{id}", url.PathEscape(id))
+		if encoded := query.Encode(); encoded != "" {
+			location += "?" + encoded
+		}
+		status := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			status = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, location, status)
+	}))
+}
+
+// ServeHTTP dispatches the request to its route. Requests no route matches get an error, with
+// the Allow header listing the methods the path does accept
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler, pattern := rt.mux.Handler(r)
+	if pattern != "" {
+		rt.mux.ServeHTTP(w, r)
+		return
+	}
+
+	// ServeMux answers unmatched requests in plain text; keep only its status and Allow header and
+	// answer like any other error
+	unmatched := &unmatchedWriter{header: make(http.Header), status: http.StatusOK}
+	handler.ServeHTTP(unmatched, r)
+	if allow := unmatched.header.Get("Allow"); allow != "" {
+		w.Header().Set("Allow", allow)
+	}
+	middleware.ApplyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.WriteError(w, r, apperror.FromStatus(unmatched.status, ""))
+	}), middleware.SetCSPHeaders, middleware.InstrumentRoute("unmatched")).ServeHTTP(w, r)
+}
+
+type unmatchedWriter struct {
+	header http.Header
+	status int
+}
+
+func (uw *unmatchedWriter) Header() http.Header         { return uw.header }
+func (uw *unmatchedWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (uw *unmatchedWriter) WriteHeader(status int)      { uw.status = status }
+
+// public is the group of pages and endpoints anyone may use
+func public(rt *Router) *Router {
+	return rt.Group(
+		middleware.SetCSPHeaders,
+		middleware.CORSMiddleware,
+		middleware.ErrorHandler,
+	)
+}
+
+// members is the group of routes that need a session. Requests that change data also need the CSRF token
+func members(rt *Router, db *sql.DB) *Router {
+	return rt.Group(
+		middleware.SetCSPHeaders,
+		middleware.AuthMiddleware,
+		middleware.CORSMiddleware,
+		middleware.ErrorHandler,
+		middleware.VerifyCSRFMiddleware(db),
+	)
+}
+
+// staff is the group of routes only users holding one of roles may use
+func staff(rt *Router, db *sql.DB, roles ...string) *Router {
+	return rt.Group(
+		middleware.SetCSPHeaders,
+		middleware.RequireRole(db, roles...),
+		middleware.AuthMiddleware,
+		middleware.ErrorHandler,
+		middleware.VerifyCSRFMiddleware(db),
+	)
+}
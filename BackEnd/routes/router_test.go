@@ -0,0 +1,121 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/database"
+)
+
+func TestRouter(t *testing.T) {
+	// trace records the order middleware runs in
+	trace := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Trace", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.PathValue("id")))
+	})
+
+	rt := NewRouter()
+	group := rt.Group(trace("inner"), trace("outer"))
+	group.Handle("GET /posts/{id}", echo, trace("route"))
+	group.Handle("DELETE /posts/{id}", echo)
+	rt.Redirect("GET /viewPost", "/posts/{id}")
+	rt.Redirect("POST /old/{id}", "/new/{id}/items")
+
+	tests := []struct {
+		method   string
+		target   string
+		status   int
+		body     string
+		trace    string
+		allow    string
+		location string
+	}{
+		{method: "GET", target: "/posts/3", status: 200, body: "GET 3", trace: "outer,inner,route"},
+		{method: "HEAD", target: "/posts/3", status: 200, trace: "outer,inner,route"},
+		{method: "DELETE", target: "/posts/3", status: 200, body: "DELETE 3", trace: "outer,inner"},
+		{method: "PUT", target: "/posts/3", status: 405, allow: "DELETE, GET, HEAD"},
+		{method: "GET", target: "/posts", status: 404},
+		{method: "GET", target: "/nothing", status: 404},
+		{method: "GET", target: "/viewPost?id=3&comment=5", status: 301, location: "/posts/3?comment=5"},
+		{method: "GET", target: "/viewPost", status: 404},
+		{method: "POST", target: "/old/7", status: 308, location: "/new/7/items"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rt.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.body)
+			}
+			if got := strings.Join(rec.Header().Values("X-Trace"), ","); got != tt.trace {
+				t.Errorf("middleware ran as %q, want %q", got, tt.trace)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("Allow = %q, want %q", got, tt.allow)
+			}
+			if got := rec.Header().Get("Location"); got != tt.location {
+				t.Errorf("Location = %q, want %q", got, tt.location)
+			}
+		})
+	}
+}
+
+// TestLegacyRedirects checks that the URLs the forum served before its routes moved still lead to
+// a route accepting the same method
+func TestLegacyRedirects(t *testing.T) {
+	db, err := database.Init("Test")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	rt := NewRouter()
+	Register(rt, db, time.Second)
+
+	tests := []struct {
+		method   string
+		target   string
+		status   int
+		location string
+	}{
+		{method: "GET", target: "/viewPost?id=3", status: 301, location: "/posts/3"},
+		{method: "PUT", target: "/updatePost?id=3", status: 308, location: "/posts/3"},
+		{method: "DELETE", target: "/deletePost?id=3", status: 308, location: "/posts/3"},
+		{method: "POST", target: "/comment/3", status: 308, location: "/posts/3/comments"},
+		{method: "POST", target: "/updateComment?id=5", status: 308, location: "/comments/5"},
+		{method: "DELETE", target: "/deleteComment?id=5", status: 308, location: "/comments/5"},
+		{method: "GET", target: "/messages/view?id=4", status: 301, location: "/messages/4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rt.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			location := rec.Header().Get("Location")
+			if location != tt.location {
+				t.Fatalf("Location = %q, want %q", location, tt.location)
+			}
+
+			// The client repeats the request at the new URL, which must have a route for the method
+			if _, pattern := rt.mux.Handler(httptest.NewRequest(tt.method, location, nil)); pattern == "" {
+				t.Errorf("no route serves %s %s", tt.method, location)
+			}
+		})
+	}
+}
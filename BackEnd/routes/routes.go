@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Register registers every route of the forum on rt. Readiness checks give up after readyTimeout
+func Register(rt *Router, db *sql.DB, readyTimeout time.Duration) {
+	HomeRoute(rt, db)
+	ServeStaticFolder(rt)
+	UserRegAndLogin(rt, db)
+	PostRoutes(rt, db)
+	CommentRoute(rt, db)
+	LikesRoutes(rt, db)
+	PollRoutes(rt, db)
+	AdminRoutes(rt, db)
+	ModerationRoutes(rt, db)
+	TrashRoutes(rt, db)
+	BookmarkRoutes(rt, db)
+	SubscriptionRoutes(rt, db)
+	MessageRoutes(rt, db)
+	BlockRoutes(rt, db)
+	MentionRoutes(rt, db)
+	AnswerRoutes(rt, db)
+	ReactionRoutes(rt, db)
+	APIRoutes(rt, db)
+	MetricsRoutes(rt, db)
+	HealthRoutes(rt, db, readyTimeout)
+}
@@ -3,16 +3,14 @@ package routes
 import (
 	"net/http"
 
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
 	"github.com/Raymond9734/forum.git/BackEnd/middleware"
+	"github.com/Raymond9734/forum.git/BackEnd/storage"
 )
 
-func ServeStaticFolder() {
-	http.Handle("/static/", middleware.ApplyMiddleware(
-		http.StripPrefix("/static/", http.FileServer(http.Dir("./FrontEnd/static"))),
-		middleware.SetCSPHeaders,
-	))
-	http.Handle("/uploads/", middleware.ApplyMiddleware(
-		http.StripPrefix("/uploads/", http.FileServer(http.Dir("./uploads"))),
-		middleware.SetCSPHeaders,
-	))
+func ServeStaticFolder(rt *Router) {
+	static := rt.Group(middleware.SetCSPHeaders)
+
+	static.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.Dir("./FrontEnd/static"))))
+	static.Handle("GET "+storage.PublicPath, handlers.UploadsHandler(storage.Default))
 }
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/controllers"
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
+	"github.com/Raymond9734/forum.git/BackEnd/middleware"
+)
+
+func SubscriptionRoutes(rt *Router, db *sql.DB) {
+	SubscriptionController := controllers.NewSubscriptionController(db)
+
+	subscribeLimiter := middleware.NewRateLimiter(30, time.Minute) // 30 subscription changes per minute
+
+	authed := members(rt, db)
+	authed.Handle("POST /subscribe", handlers.SubscribeHandler(SubscriptionController), subscribeLimiter.RateLimit)
+	authed.Handle("GET /subscriptions", handlers.SubscriptionsPageHandler(SubscriptionController))
+}
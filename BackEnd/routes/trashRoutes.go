@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"database/sql"
+
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
+)
+
+func TrashRoutes(rt *Router, db *sql.DB) {
+	authed := members(rt, db)
+	authed.Handle("GET /trash", handlers.TrashPageHandler(db))
+	authed.Handle("POST /trash/restore", handlers.RestoreTrashHandler(db))
+}
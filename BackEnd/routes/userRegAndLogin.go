@@ -10,7 +10,7 @@ import (
 	"github.com/Raymond9734/forum.git/BackEnd/middleware"
 )
 
-func UserRegAndLogin(db *sql.DB) {
+func UserRegAndLogin(rt *Router, db *sql.DB) {
 	AuthController := controllers.NewAuthController(db)
 
 	// Strict rate limit for authentication attempts
@@ -19,49 +19,13 @@ func UserRegAndLogin(db *sql.DB) {
 	// Less strict rate limit for page views
 	pageLimiter := middleware.NewRateLimiter(30, time.Minute) // 30 requests per minute
 
-	http.Handle("/login", middleware.ApplyMiddleware(
-		handlers.LoginHandler(AuthController),
-		middleware.SetCSPHeaders,
-		middleware.CORSMiddleware,
-		authLimiter.RateLimit,
-		middleware.ErrorHandler,
-		middleware.ValidatePathAndMethod("/login", http.MethodPost),
-	))
-
-	http.Handle("/register", middleware.ApplyMiddleware(
-		handlers.RegisterHandler(AuthController),
-		middleware.SetCSPHeaders,
-		middleware.CORSMiddleware,
-		authLimiter.RateLimit,
-		middleware.ErrorHandler,
-		middleware.ValidatePathAndMethod("/register", http.MethodPost),
-	))
-
-	http.Handle("/login_Page", middleware.ApplyMiddleware(
-		http.HandlerFunc(handlers.LoginPageHandler),
-		middleware.SetCSPHeaders,
-		middleware.CORSMiddleware,
-		pageLimiter.RateLimit,
-		middleware.ErrorHandler,
-		middleware.ValidatePathAndMethod("/login_Page", http.MethodGet),
-	))
-
-	http.Handle("/checkLoginStatus", middleware.ApplyMiddleware(
-		http.HandlerFunc(handlers.CheckLoginHandler),
-		middleware.SetCSPHeaders,
-		middleware.CORSMiddleware,
-		pageLimiter.RateLimit,
-		middleware.ErrorHandler,
-		middleware.ValidatePathAndMethod("/checkLoginStatus", http.MethodGet),
-	))
-
-	http.Handle("/logout", middleware.ApplyMiddleware(
-		http.HandlerFunc(handlers.LogoutHandler),
-		middleware.SetCSPHeaders,
-		middleware.CORSMiddleware,
+	pages := public(rt)
+	pages.Handle("POST /login", handlers.LoginHandler(AuthController), authLimiter.RateLimit)
+	pages.Handle("POST /register", handlers.RegisterHandler(AuthController), authLimiter.RateLimit)
+	pages.Handle("GET /login_Page", http.HandlerFunc(handlers.LoginPageHandler), pageLimiter.RateLimit)
+	pages.Handle("GET /checkLoginStatus", http.HandlerFunc(handlers.CheckLoginHandler), pageLimiter.RateLimit)
+	pages.Handle("POST /logout", http.HandlerFunc(handlers.LogoutHandler),
 		pageLimiter.RateLimit,
-		middleware.ErrorHandler,
 		middleware.VerifyCSRFMiddleware(db),
-		middleware.ValidatePathAndMethod("/logout", http.MethodPost),
-	))
+	)
 }
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Raymond9734/forum.git/BackEnd/config"
+	"github.com/Raymond9734/forum.git/BackEnd/logger"
+)
+
+// PublicPath is the URL prefix under which the application serves stored objects.
+// Paths saved in the database always use this prefix so that switching drivers
+// does not invalidate existing posts.
+const PublicPath = "/uploads/"
+
+// PrivatePrefix marks keys that may only be fetched through a signed URL
+const PrivatePrefix = "private/"
+
+// ErrNotExist is returned by Get when the requested object does not exist
+var ErrNotExist = errors.New("storage: object does not exist")
+
+// Storage is implemented by every upload backend
+type Storage interface {
+	// Put stores the content of r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object stored under key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// URL returns the address browsers should use to fetch a public object
+	URL(key string) string
+	// SignedURL returns a URL granting read access to key until ttl elapses
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// Verifier is implemented by drivers whose signed URLs are served by the application itself
+type Verifier interface {
+	Verify(key, expires, signature string) bool
+}
+
+// Default is the storage backend used by the upload helpers
+var Default Storage
+
+// PathFor returns the application path of the object stored under key
+func PathFor(key string) string {
+	return PublicPath + key
+}
+
+// KeyFromPath extracts the storage key from a path returned by PathFor
+func KeyFromPath(path string) string {
+	path = strings.TrimPrefix(path, "/")
+	return strings.TrimPrefix(path, strings.TrimPrefix(PublicPath, "/"))
+}
+
+// IsPrivate reports whether key may only be fetched through a signed URL
+func IsPrivate(key string) bool {
+	return strings.HasPrefix(key, PrivatePrefix)
+}
+
+// validKey rejects keys that could escape the storage root
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("storage: invalid key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("storage: invalid key %q", key)
+		}
+	}
+	return nil
+}
+
+// Init creates the storage backend selected by the environment and makes it the default
+func Init() (Storage, error) {
+	signingKey := []byte(config.String("FORUM_STORAGE_SIGNING_KEY", ""))
+	if len(signingKey) == 0 {
+		// A random key works for a single instance; every instance must share the key otherwise
+		logger.Warning("FORUM_STORAGE_SIGNING_KEY is not set, signed upload URLs will not survive a restart")
+		signingKey = make([]byte, 32)
+		if _, err := rand.Read(signingKey); err != nil {
+			return nil, fmt.Errorf("failed to generate signing key: %w", err)
+		}
+		signingKey = []byte(hex.EncodeToString(signingKey))
+	}
+
+	var store Storage
+	switch driver := config.String("FORUM_STORAGE_DRIVER", "local"); driver {
+	case "local":
+		store = NewLocalStorage(config.String("FORUM_UPLOAD_DIR", "uploads"), signingKey)
+	case "s3":
+		s3, err := NewS3Storage(S3Config{
+			Endpoint:  config.String("FORUM_S3_ENDPOINT", ""),
+			Region:    config.String("FORUM_S3_REGION", "us-east-1"),
+			Bucket:    config.String("FORUM_S3_BUCKET", ""),
+			AccessKey: config.String("FORUM_S3_ACCESS_KEY", ""),
+			SecretKey: config.String("FORUM_S3_SECRET_KEY", ""),
+			PublicURL: config.String("FORUM_S3_PUBLIC_URL", ""),
+		})
+		if err != nil {
+			return nil, err
+		}
+		store = s3
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+
+	Default = store
+	return store, nil
+}
+
+// probeKey is the object CheckWritable writes; it is removed again straight away
+const probeKey = PrivatePrefix + "healthcheck/probe"
+
+// CheckWritable stores and removes a small object to find out whether store accepts uploads
+func CheckWritable(ctx context.Context, store Storage) error {
+	if store == nil {
+		return errors.New("storage: not initialized")
+	}
+	if err := store.Put(ctx, probeKey, strings.NewReader("ok"), 2, "text/plain"); err != nil {
+		return err
+	}
+	return store.Delete(ctx, probeKey)
+}
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is a minimal MinIO-style stand-in that keeps objects in memory
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	bucket  string
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	return &fakeS3{objects: make(map[string][]byte), bucket: bucket}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	presigned := r.URL.Query().Get("X-Amz-Signature") != ""
+	if !presigned && !strings.HasPrefix(auth, s3Algorithm+" Credential=test-key/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	prefix := "/" + f.bucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[key] = body
+	case http.MethodGet:
+		body, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func testStorageRoundTrip(t *testing.T, store Storage) {
+	ctx := context.Background()
+	content := "hello uploads"
+
+	if err := store.Put(ctx, "dir/file name.txt", strings.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	object, err := store.Get(ctx, "dir/file name.txt")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	got, _ := io.ReadAll(object)
+	object.Close()
+	if string(got) != content {
+		t.Errorf("Get() = %q, want %q", got, content)
+	}
+
+	if err := store.Delete(ctx, "dir/file name.txt"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, "dir/file name.txt"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotExist", err)
+	}
+	if err := store.Delete(ctx, "dir/file name.txt"); err != nil {
+		t.Errorf("Delete() of missing object error = %v", err)
+	}
+
+	if err := store.Put(ctx, "../escape.txt", strings.NewReader(content), int64(len(content)), ""); err == nil {
+		t.Errorf("Put() with path traversal key succeeded")
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	store := NewLocalStorage(t.TempDir(), []byte("secret"))
+	testStorageRoundTrip(t, store)
+
+	if got := store.URL("a.png"); got != "/uploads/a.png" {
+		t.Errorf("URL() = %q, want /uploads/a.png", got)
+	}
+}
+
+func TestLocalStorage_SignedURL(t *testing.T) {
+	store := NewLocalStorage(t.TempDir(), []byte("secret"))
+
+	signed, err := store.SignedURL("private/a.png", time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL() error = %v", err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("SignedURL() returned invalid URL: %v", err)
+	}
+	query := u.Query()
+
+	tests := []struct {
+		name      string
+		key       string
+		expires   string
+		signature string
+		want      bool
+	}{
+		{"Valid signature", "private/a.png", query.Get("expires"), query.Get("signature"), true},
+		{"Different key", "private/b.png", query.Get("expires"), query.Get("signature"), false},
+		{"Tampered expiry", "private/a.png", "99999999999", query.Get("signature"), false},
+		{"Expired", "private/a.png", "1", store.sign("private/a.png", "1"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := store.Verify(tt.key, tt.expires, tt.signature); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestS3Storage(t *testing.T) {
+	fake := newFakeS3("forum")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := NewS3Storage(S3Config{
+		Endpoint:  server.URL,
+		Bucket:    "forum",
+		AccessKey: "test-key",
+		SecretKey: "test-secret",
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage() error = %v", err)
+	}
+	testStorageRoundTrip(t, store)
+
+	// Without a public URL objects are proxied through the application
+	if got := store.URL("a.png"); got != "/uploads/a.png" {
+		t.Errorf("URL() = %q, want /uploads/a.png", got)
+	}
+
+	// Presigned URLs are fetched directly from the bucket
+	if err := store.Put(context.Background(), "private/a.txt", strings.NewReader("secret"), 6, "text/plain"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	signed, err := store.SignedURL("private/a.txt", time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL() error = %v", err)
+	}
+	if !strings.HasPrefix(signed, server.URL+"/forum/private/a.txt?") || !strings.Contains(signed, "X-Amz-Expires=60") {
+		t.Errorf("SignedURL() = %q", signed)
+	}
+	resp, err := http.Get(signed)
+	if err != nil {
+		t.Fatalf("GET signed URL error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "secret" {
+		t.Errorf("GET signed URL = %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestKeyFromPath(t *testing.T) {
+	tests := map[string]string{
+		"/uploads/User1_1.png": "User1_1.png",
+		"uploads/User1_1.png":  "User1_1.png",
+		PathFor("private/x"):   "private/x",
+	}
+	for path, want := range tests {
+		if got := KeyFromPath(path); got != want {
+			t.Errorf("KeyFromPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
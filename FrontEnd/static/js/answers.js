@@ -0,0 +1,42 @@
+// Accepting a comment as the answer of a Q&A post
+document.addEventListener('DOMContentLoaded', function() {
+    const container = document.querySelector('.comments-container[data-post-id]');
+    if (!container || container.dataset.canAccept !== 'true') {
+        return;
+    }
+
+    // Buttons are delegated so that comments loaded later get them too
+    document.addEventListener('click', async function(event) {
+        const button = event.target.closest('.accept-answer-button');
+        if (!button) {
+            return;
+        }
+        event.preventDefault();
+        button.disabled = true;
+
+        const accepted = button.classList.contains('active');
+        try {
+            const response = await fetch('/answer/accept', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/x-www-form-urlencoded',
+                    'X-CSRF-Token': document.querySelector('meta[name="csrf-token"]').content
+                },
+                body: new URLSearchParams({
+                    'post_id': container.dataset.postId,
+                    'comment_id': accepted ? 0 : button.dataset.commentId
+                })
+            });
+            const data = await response.json();
+            if (!response.ok) {
+                throw new Error(data.detail || 'Failed to accept answer');
+            }
+            // Reload so that the accepted answer is pinned above the comments
+            showToast(accepted ? 'Answer unaccepted' : 'Answer accepted');
+            setTimeout(() => window.location.reload(), 500);
+        } catch (error) {
+            showToast(error.message);
+            button.disabled = false;
+        }
+    });
+});
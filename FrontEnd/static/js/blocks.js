@@ -0,0 +1,73 @@
+document.addEventListener('DOMContentLoaded', function() {
+    function notify(message) {
+        const toast = document.getElementById('toast');
+        const toastMessage = document.getElementById('toastMessage');
+        if (!toast || !toastMessage) {
+            return;
+        }
+        toastMessage.textContent = message;
+        toast.classList.add('show');
+        setTimeout(() => toast.classList.remove('show'), 3000);
+    }
+
+    async function updateBlockList(username, action) {
+        const response = await fetch('/block', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/x-www-form-urlencoded',
+                'X-CSRF-Token': document.querySelector('meta[name="csrf-token"]').content
+            },
+            body: new URLSearchParams({ 'username': username, 'action': action })
+        });
+        const data = await response.json();
+        if (!response.ok) {
+            throw new Error(data.detail || 'Failed to update block list');
+        }
+    }
+
+    const confirmations = {
+        'block': username => `Block ${username}? You will no longer see their posts and comments, and they will not be able to reply to you or message you.`,
+        'mute': username => `Mute ${username}? You will no longer see their posts and comments.`
+    };
+    const done = {
+        'block': 'blocked',
+        'mute': 'muted',
+        'unblock': 'unblocked',
+        'unmute': 'unmuted'
+    };
+
+    document.querySelectorAll('.block-user-button').forEach(button => {
+        button.addEventListener('click', async function() {
+            const username = button.getAttribute('data-username');
+            const action = button.getAttribute('data-action') || 'block';
+            if (confirmations[action] && !confirm(confirmations[action](username))) {
+                return;
+            }
+            try {
+                await updateBlockList(username, action);
+                if (button.closest('.blocked-item')) {
+                    button.closest('.blocked-item').remove();
+                } else if (action === 'block' || action === 'mute') {
+                    window.location.reload();
+                    return;
+                }
+                notify(`${username} has been ${done[action]}`);
+            } catch (error) {
+                notify(error.message);
+            }
+        });
+    });
+
+    const blockUserForm = document.getElementById('blockUserForm');
+    if (blockUserForm) {
+        blockUserForm.addEventListener('submit', async function(e) {
+            e.preventDefault();
+            try {
+                await updateBlockList(blockUserForm.elements['username'].value.trim(), blockUserForm.elements['action'].value);
+                window.location.reload();
+            } catch (error) {
+                notify(error.message);
+            }
+        });
+    }
+});
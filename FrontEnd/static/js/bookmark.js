@@ -0,0 +1,98 @@
+document.addEventListener('DOMContentLoaded', function() {
+    function notify(message) {
+        const toast = document.getElementById('toast');
+        const toastMessage = document.getElementById('toastMessage');
+        if (!toast || !toastMessage) {
+            return;
+        }
+        toastMessage.textContent = message;
+        toast.classList.add('show');
+        setTimeout(() => toast.classList.remove('show'), 3000);
+    }
+
+    function postForm(url, params) {
+        return fetch(url, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/x-www-form-urlencoded',
+                'X-CSRF-Token': document.querySelector('meta[name="csrf-token"]').content
+            },
+            body: new URLSearchParams(params)
+        });
+    }
+
+    function toggleBookmark(type, id) {
+        return postForm('/bookmark', { 'type': type, 'id': id }).then(async response => {
+            const data = await response.json();
+            if (!response.ok) {
+                throw new Error(data.detail || 'Failed to update bookmark');
+            }
+            return data.bookmarked;
+        });
+    }
+
+    // Save/unsave buttons on posts and comments
+    document.querySelectorAll('.bookmark-button').forEach(button => {
+        button.addEventListener('click', function(e) {
+            e.preventDefault();
+            e.stopPropagation();
+            button.disabled = true;
+
+            toggleBookmark(button.getAttribute('data-bookmark-type'), button.getAttribute('data-bookmark-id'))
+                .then(bookmarked => {
+                    const icon = button.querySelector('i');
+                    button.classList.toggle('active', bookmarked);
+                    button.title = bookmarked ? 'Unsave' : 'Save';
+                    icon.classList.toggle('fa-solid', bookmarked);
+                    icon.classList.toggle('fa-regular', !bookmarked);
+                    notify(bookmarked ? 'Saved' : 'Removed from saved');
+                })
+                .catch(error => notify(error.message))
+                .finally(() => { button.disabled = false; });
+        });
+    });
+
+    // Remove and move buttons on the saved page
+    document.querySelectorAll('.saved-item .remove-bookmark-button').forEach(button => {
+        button.addEventListener('click', function() {
+            const item = button.closest('.saved-item');
+            button.disabled = true;
+
+            toggleBookmark(item.getAttribute('data-type'), item.getAttribute('data-target-id'))
+                .then(() => {
+                    item.remove();
+                    notify('Removed from saved');
+                })
+                .catch(error => {
+                    notify(error.message);
+                    button.disabled = false;
+                });
+        });
+    });
+
+    document.querySelectorAll('.saved-item .move-bookmark-button').forEach(button => {
+        button.addEventListener('click', async function() {
+            const item = button.closest('.saved-item');
+            const folder = prompt('Move to folder (leave empty for none):', button.getAttribute('data-folder'));
+            if (folder === null) {
+                return;
+            }
+
+            try {
+                const response = await postForm('/bookmark/folder', {
+                    'id': item.getAttribute('data-bookmark-id'),
+                    'folder': folder
+                });
+                const data = await response.json();
+                if (response.ok) {
+                    window.location.reload();
+                } else {
+                    notify(data.detail || 'Failed to move bookmark');
+                }
+            } catch (error) {
+                console.error('Error moving bookmark:', error);
+                notify('An error occurred while moving the bookmark');
+            }
+        });
+    });
+});
@@ -0,0 +1,209 @@
+// Lazy loading of comment pages and reply subtrees on the post page
+document.addEventListener('DOMContentLoaded', function() {
+    const container = document.querySelector('.comments-container[data-post-id]');
+    if (!container) {
+        return;
+    }
+    const postId = container.dataset.postId;
+    const sort = container.dataset.sort;
+    const isAuthenticated = container.dataset.authenticated === 'true';
+    const canReply = container.dataset.canReply === 'true';
+    const canAccept = container.dataset.canAccept === 'true';
+
+    function escapeHTML(text) {
+        const div = document.createElement('div');
+        div.textContent = text;
+        return div.innerHTML;
+    }
+
+    // linkMentions mirrors the server side rendering of post and comment content
+    function linkMentions(text) {
+        return escapeHTML(text).replace(/(^|[^A-Za-z0-9_@])@([A-Za-z0-9_]{3,20})(?![A-Za-z0-9_])/g,
+            (match, before, name) => `${before}<a href="/profile?username=${encodeURIComponent(name)}" class="mention">@${name}</a>`);
+    }
+
+    function permalink(commentId) {
+        return `/posts/${postId}?comment=${commentId}#comment-${commentId}`;
+    }
+
+    function plural(count) {
+        return `${count} more ${count === 1 ? 'reply' : 'replies'}`;
+    }
+
+    // renderComment builds the markup of the comments template for a comment loaded as JSON
+    function renderComment(comment) {
+        const element = document.createElement('div');
+        const placeholder = comment.isDeleted || comment.isHidden;
+        element.className = 'comment' + (comment.isDeleted ? ' deleted' : '') + (comment.isHidden ? ' deleted hidden-author' : '') + (comment.isAccepted ? ' accepted' : '');
+        element.id = `comment-${comment.ID}`;
+        element.dataset.commentId = comment.ID;
+
+        let quote = '';
+        if (comment.quote) {
+            quote = `
+                <blockquote class="comment-quote">
+                    <a href="${permalink(comment.quote.id)}" class="quote-source"><i class="fa-solid fa-quote-left"></i> ${escapeHTML(comment.quote.author)} wrote:</a>
+                    <div class="quote-excerpt">${escapeHTML(comment.quote.excerpt)}</div>
+                </blockquote>`;
+        }
+
+        let actions = '';
+        if (canReply && !placeholder) {
+            actions = `
+                <div class="comment-actions">
+                    <button class="reply-button" onclick="showReplyForm(this)" data-comment-id="${comment.ID}">Reply</button>
+                    <button class="reply-button quote-button" onclick="quoteReply(this)" data-comment-id="${comment.ID}">Quote</button>
+                </div>`;
+        }
+
+        let accept = '';
+        if (canAccept && !placeholder) {
+            const state = comment.isAccepted ? 'solid' : 'regular';
+            accept = `
+                <button type="button" class="accept-answer-button${comment.isAccepted ? ' active' : ''}" data-comment-id="${comment.ID}" title="${comment.isAccepted ? 'Unaccept answer' : 'Accept as answer'}">
+                    <i class="fa-${state} fa-circle-check"></i>
+                </button>`;
+        }
+
+        let reactions = '';
+        if (comment.reactions && comment.reactions.length > 0) {
+            const buttons = comment.reactions.map(reaction => `
+                <button type="button" class="reaction-button${reaction.reacted ? ' active' : ''}" data-reaction="${escapeHTML(reaction.reaction)}">
+                    ${escapeHTML(reaction.reaction)} <span class="reaction-count">${reaction.count}</span>
+                </button>`).join('');
+            reactions = `
+                <div class="reactions" data-reaction-type="comment" data-reaction-id="${comment.ID}">
+                    ${buttons}
+                    <button type="button" class="reactors-button" title="Who reacted"><i class="fa-solid fa-users"></i></button>
+                    <ul class="reactors-list hidden"></ul>
+                </div>`;
+        }
+
+        element.innerHTML = `
+            <div class="comment-header">
+                <div class="post-author-info">
+                    <div class="author-initial">${escapeHTML(comment.Author.slice(0, 1))}</div>
+                    <span class="comment-author">${escapeHTML(comment.Author)}</span>
+                    ${placeholder ? '' : `<span class="author-reputation" title="Reputation">${comment.authorReputation}</span>`}
+                    ${comment.isAccepted ? '<span class="accepted-badge"><i class="fa-solid fa-circle-check"></i> Accepted answer</span>' : ''}
+                </div>
+                <div class="comment-meta">
+                    <span class="timestamp" data-timestamp="${escapeHTML(comment.Timestamp)}"></span>
+                </div>
+            </div>
+            ${quote}
+            <div class="comment-content" id="comment-content-${comment.ID}">${linkMentions(comment.Content)}</div>
+            <div class="comment-footer">
+                <div class="vote-buttons">
+                    <button class="vote-button comment-vote" data-vote="up" data-comment-id="${comment.ID}">
+                        <i class="fa-regular fa-thumbs-up"></i>
+                    </button>
+                    <div class="counter" id="comment-likes-${comment.ID}">${comment.Likes}</div>
+                    <button class="vote-button comment-vote" data-vote="down" data-comment-id="${comment.ID}">
+                        <i class="fa-regular fa-thumbs-down"></i>
+                    </button>
+                    <div class="counter" id="comment-dislikes-${comment.ID}">${comment.Dislikes}</div>
+                </div>
+                ${reactions}
+                ${actions}
+                ${accept}
+                ${comment.isDeleted ? '' : `<a href="${permalink(comment.ID)}" class="comment-permalink" title="Link to this comment"><i class="fa-solid fa-link"></i></a>`}
+            </div>
+            <div class="reply-input-container" id="reply-form-${comment.ID}">
+                <blockquote class="comment-quote reply-quote hidden" id="reply-quote-${comment.ID}"></blockquote>
+                <textarea class="reply-input" id="replyText-${comment.ID}" placeholder="Write a reply..."></textarea>
+                <div class="reply-buttons">
+                    <button class="button button-primary" onclick="submitReply(this)" data-comment-id="${comment.ID}" data-post-id="${postId}">Submit</button>
+                    <button class="button button-secondary" onclick="cancelReply(this)" data-comment-id="${comment.ID}">Cancel</button>
+                </div>
+            </div>`;
+
+        element.querySelectorAll('.comment-vote').forEach(button => {
+            button.addEventListener('click', function(event) {
+                event.stopPropagation();
+                handleCommentVote(event, isAuthenticated);
+            });
+        });
+
+        if (comment.moreReplies > 0) {
+            element.appendChild(loadRepliesButton(comment.ID, 0, comment.moreReplies));
+        }
+        return element;
+    }
+
+    function loadRepliesButton(commentId, offset, count) {
+        const button = document.createElement('button');
+        button.type = 'button';
+        button.className = 'load-replies';
+        button.dataset.commentId = commentId;
+        button.dataset.offset = offset;
+        button.textContent = `Show ${plural(count)}`;
+        return button;
+    }
+
+    async function fetchPage(parentId, offset) {
+        const params = new URLSearchParams({ post: postId, sort: sort, offset: offset });
+        if (parentId) {
+            params.set('parent', parentId);
+        }
+        const response = await fetch(`/comments?${params}`);
+        const data = await response.json();
+        if (!response.ok) {
+            throw new Error(data.detail || 'Failed to load comments');
+        }
+        return data;
+    }
+
+    // appendComments adds the comments that are not on the page yet
+    function appendComments(target, comments) {
+        comments.forEach(comment => {
+            if (!document.getElementById(`comment-${comment.ID}`)) {
+                target.appendChild(renderComment(comment));
+            }
+        });
+        if (window.updateTimestamps) {
+            window.updateTimestamps();
+        }
+    }
+
+    document.addEventListener('click', async function(event) {
+        const button = event.target.closest('.load-replies, .load-more-comments');
+        if (!button) {
+            return;
+        }
+        button.disabled = true;
+
+        const parentId = button.dataset.commentId || 0;
+        const offset = parseInt(button.dataset.offset, 10) || 0;
+        try {
+            const page = await fetchPage(parentId, offset);
+            if (parentId) {
+                const comment = button.closest('.comment');
+                let replies = comment.querySelector(':scope > .nested-comments');
+                if (!replies) {
+                    replies = document.createElement('div');
+                    replies.className = 'nested-comments';
+                    comment.insertBefore(replies, button);
+                }
+                appendComments(replies, page.comments);
+            } else {
+                appendComments(container, page.comments);
+            }
+
+            if (page.hasMore) {
+                button.dataset.offset = page.nextOffset;
+                if (parentId) {
+                    const left = parseInt(button.textContent.replace(/\D+/g, ''), 10) - page.comments.length;
+                    button.textContent = `Show ${plural(Math.max(left, 1))}`;
+                }
+                button.disabled = false;
+            } else {
+                button.remove();
+            }
+        } catch (error) {
+            console.error('Error loading comments:', error);
+            showToast('Failed to load comments');
+            button.disabled = false;
+        }
+    });
+});
@@ -1,6 +1,4 @@
 const logoutButton = document.getElementById('logoutButton');
-const postsContainer = document.querySelector(".posts-container");
-const posts = postsContainer.querySelectorAll(".post");
 
 if (logoutButton) {
     logoutButton.addEventListener('click', function () {
@@ -40,53 +38,29 @@ if (logoutButton) {
 }
 
 document.addEventListener("DOMContentLoaded", () => {
-    // Select all sidebar links
-    const communityLinks = document.querySelectorAll(".sidebar .sidebar-link");
-    // Select the posts container
-
-    // Function to filter posts on the homepage
-    const filterPosts = (selectedCategory) => {
-        
-        posts.forEach(post => {
-            const postCategory = post.getAttribute("data-category")?.toLowerCase() || "";
-            if (selectedCategory === "all" || selectedCategory === "home" || postCategory.includes(selectedCategory)) {
-                post.style.display = "block";
-            } else {
-                post.style.display = "none";
-            }
-        });
-    };
-
-    // Add click event listener to each sidebar link
-    communityLinks.forEach(link => {
-        link.addEventListener("click", (event) => {
-            event.preventDefault();
-
-            // Get the clicked category name
-            const selectedCategory = link.textContent.trim().toLowerCase();
-            const currentPath = window.location.pathname;
-
-            if (currentPath === "/") {
-                // If on the homepage, directly filter posts
-                filterPosts(selectedCategory);
-                history.pushState(null, "", "/");
-            } else if (currentPath === "/viewPost") {
-                // If on the viewPost page, redirect to the homepage
-                sessionStorage.setItem("filterCategory", selectedCategory);
-                window.location.href = "/";
-            }
-        });
-    });
+    // Show the number of unread private messages next to the messages link
+    const unreadMessages = document.getElementById("unreadMessages");
+    if (unreadMessages) {
+        fetch("/messages/unread")
+            .then(response => response.ok ? response.json() : { unread: 0 })
+            .then(data => {
+                if (data.unread > 0) {
+                    unreadMessages.textContent = data.unread;
+                    unreadMessages.classList.remove("hidden");
+                }
+            })
+            .catch(error => console.error("Error fetching unread messages:", error));
+    }
 
-    // On homepage, check for saved category in sessionStorage
+    // Highlight the sidebar link of the category the homepage shows
     if (window.location.pathname === "/") {
-        const savedCategory = sessionStorage.getItem("filterCategory");
-        if (savedCategory) {
-            filterPosts(savedCategory);
-            sessionStorage.removeItem("filterCategory");
-        } else {
-            filterPosts("all");
-        }
+        const category = new URLSearchParams(window.location.search).get("category") || "";
+        document.querySelectorAll(".sidebar .sidebar-link").forEach(link => {
+            const linkCategory = new URL(link.href).searchParams.get("category") || "";
+            if (category !== "" && linkCategory === category.toLowerCase()) {
+                link.classList.add("active");
+            }
+        });
     }
 });
 
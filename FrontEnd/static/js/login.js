@@ -98,8 +98,8 @@ document.getElementById('signupForm').querySelector('button').addEventListener('
     })
     .then(response => response.json())
     .then(data => {
-        if (data.error) {
-            showToast(data.error); 
+        if (data.detail) {
+            showToast(data.detail); 
         } else {
             console.log('Success:', data);
             window.location.href = data.redirect
@@ -134,8 +134,8 @@ document.getElementById('loginForm').querySelector('button').addEventListener('c
     })
     .then(response => response.json())
     .then(data => {
-        if (data.error) {
-            showToast(data.error); 
+        if (data.detail) {
+            showToast(data.detail); 
         } else {
             console.log('Success:', data);
             // Redirect to homepage after successful login
@@ -0,0 +1,131 @@
+document.addEventListener('DOMContentLoaded', function() {
+    const editors = '.main-comment-input, .reply-input, .edit-input';
+    const list = document.createElement('ul');
+    list.className = 'mention-suggestions hidden';
+    document.body.appendChild(list);
+
+    let active = null;
+    let selected = 0;
+    let lastPrefix = '';
+
+    // mentionAt returns the @prefix being typed just before the caret, if any
+    function mentionAt(textarea) {
+        const before = textarea.value.slice(0, textarea.selectionStart);
+        const match = before.match(/(^|[^A-Za-z0-9_@])@([A-Za-z0-9_]{1,20})$/);
+        return match ? match[2] : null;
+    }
+
+    function hide() {
+        list.classList.add('hidden');
+        list.innerHTML = '';
+        active = null;
+        lastPrefix = '';
+    }
+
+    function highlight(index) {
+        const items = list.querySelectorAll('li');
+        if (items.length === 0) {
+            return;
+        }
+        selected = (index + items.length) % items.length;
+        items.forEach((item, i) => item.classList.toggle('selected', i === selected));
+    }
+
+    function insert(username) {
+        const textarea = active;
+        const caret = textarea.selectionStart;
+        const before = textarea.value.slice(0, caret).replace(/@[A-Za-z0-9_]*$/, '@' + username + ' ');
+        textarea.value = before + textarea.value.slice(caret);
+        textarea.selectionStart = textarea.selectionEnd = before.length;
+        textarea.focus();
+        hide();
+    }
+
+    function show(textarea, usernames) {
+        list.innerHTML = '';
+        if (usernames.length === 0) {
+            list.classList.add('hidden');
+            return;
+        }
+        usernames.forEach(username => {
+            const item = document.createElement('li');
+            item.textContent = '@' + username;
+            item.addEventListener('mousedown', function(event) {
+                event.preventDefault();
+                insert(username);
+            });
+            list.appendChild(item);
+        });
+        const rect = textarea.getBoundingClientRect();
+        list.style.left = (rect.left + window.scrollX) + 'px';
+        list.style.top = (rect.bottom + window.scrollY) + 'px';
+        list.classList.remove('hidden');
+        highlight(0);
+    }
+
+    async function suggest(textarea) {
+        const prefix = mentionAt(textarea);
+        if (!prefix) {
+            hide();
+            return;
+        }
+        if (prefix === lastPrefix && active === textarea) {
+            return;
+        }
+        active = textarea;
+        lastPrefix = prefix;
+        try {
+            const response = await fetch('/api/users/suggest?prefix=' + encodeURIComponent(prefix));
+            if (!response.ok) {
+                return;
+            }
+            const data = await response.json();
+            // Ignore responses that arrive after the user kept typing
+            if (active === textarea && mentionAt(textarea) === prefix) {
+                show(textarea, data.usernames || []);
+            }
+        } catch (error) {
+            console.error('Error fetching username suggestions:', error);
+        }
+    }
+
+    document.addEventListener('input', function(event) {
+        if (event.target.matches && event.target.matches(editors)) {
+            suggest(event.target);
+        }
+    });
+
+    document.addEventListener('keydown', function(event) {
+        if (list.classList.contains('hidden') || event.target !== active) {
+            return;
+        }
+        switch (event.key) {
+        case 'ArrowDown':
+            event.preventDefault();
+            highlight(selected + 1);
+            break;
+        case 'ArrowUp':
+            event.preventDefault();
+            highlight(selected - 1);
+            break;
+        case 'Enter':
+        case 'Tab': {
+            const item = list.querySelectorAll('li')[selected];
+            if (item) {
+                event.preventDefault();
+                insert(item.textContent.slice(1));
+            }
+            break;
+        }
+        case 'Escape':
+            hide();
+            break;
+        }
+    });
+
+    document.addEventListener('focusout', function(event) {
+        if (event.target === active) {
+            hide();
+        }
+    });
+});
@@ -0,0 +1,156 @@
+document.addEventListener('DOMContentLoaded', function() {
+    function showToast(message) {
+        const toast = document.getElementById('toast');
+        const toastMessage = document.getElementById('toastMessage');
+        toastMessage.textContent = message;
+        toast.classList.add('show');
+        setTimeout(() => toast.classList.remove('show'), 3000);
+    }
+
+    // postForm sends params url encoded, or a FormData as a multipart form for attachments
+    async function postForm(url, params) {
+        const headers = {'X-CSRF-Token': document.querySelector('meta[name="csrf-token"]').content};
+        if (!(params instanceof FormData)) {
+            headers['Content-Type'] = 'application/x-www-form-urlencoded';
+            params = new URLSearchParams(params);
+        }
+        const response = await fetch(url, {
+            method: 'POST',
+            headers: headers,
+            body: params
+        });
+        let data = {};
+        try {
+            data = await response.json();
+        } catch (e) {
+            // Rate limited and other plain text responses
+        }
+        if (!response.ok) {
+            throw new Error(data.detail || 'Something went wrong, please try again');
+        }
+        return data;
+    }
+
+    // Inbox: start a new conversation
+    const newConversationForm = document.getElementById('newConversationForm');
+    if (newConversationForm) {
+        newConversationForm.addEventListener('submit', async function(e) {
+            e.preventDefault();
+            try {
+                const data = await postForm('/messages/new', {
+                    'to': newConversationForm.elements['to'].value,
+                    'subject': newConversationForm.elements['subject'].value,
+                    'content': newConversationForm.elements['content'].value
+                });
+                window.location.href = '/messages/' + data.conversationID;
+            } catch (error) {
+                showToast(error.message);
+            }
+        });
+    }
+
+    const conversation = document.getElementById('conversation');
+    if (!conversation) {
+        return;
+    }
+    const conversationID = conversation.getAttribute('data-conversation-id');
+    const userID = conversation.getAttribute('data-user-id');
+    const messageList = document.getElementById('messageList');
+
+    function renderMessage(message) {
+        const element = document.createElement('div');
+        element.className = 'message' + (String(message.senderId) === userID ? ' own' : '');
+        element.setAttribute('data-message-id', message.id);
+
+        const meta = document.createElement('div');
+        meta.className = 'message-meta';
+        const sender = document.createElement('span');
+        sender.className = 'message-sender';
+        sender.textContent = message.sender;
+        const time = document.createElement('span');
+        time.className = 'small';
+        time.textContent = new Date(message.createdAt).toLocaleString();
+        meta.append(sender, ' ', time);
+
+        const content = document.createElement('div');
+        content.className = 'message-content';
+        content.textContent = message.content;
+
+        element.append(meta, content);
+        if (message.attachmentUrl) {
+            const attachment = document.createElement('a');
+            attachment.className = 'message-attachment';
+            attachment.href = message.attachmentUrl;
+            attachment.target = '_blank';
+            attachment.rel = 'noopener';
+            attachment.innerHTML = '<i class="fa-solid fa-paperclip"></i> Attachment';
+            element.appendChild(attachment);
+        }
+        return element;
+    }
+
+    async function fetchMessages(before) {
+        const response = await fetch(`/messages/history?id=${conversationID}&before=${before}`);
+        if (!response.ok) {
+            throw new Error('Failed to load messages');
+        }
+        return response.json();
+    }
+
+    function lastMessageID() {
+        const messages = messageList.querySelectorAll('.message');
+        return messages.length ? Number(messages[messages.length - 1].getAttribute('data-message-id')) : 0;
+    }
+
+    // Append messages that arrived since the newest one shown
+    async function refresh() {
+        try {
+            const last = lastMessageID();
+            const messages = await fetchMessages(0);
+            messages.filter(m => m.id > last).forEach(m => messageList.appendChild(renderMessage(m)));
+        } catch (error) {
+            console.error('Error refreshing messages:', error);
+        }
+    }
+
+    messageList.scrollTop = messageList.scrollHeight;
+    setInterval(refresh, 15000);
+
+    const loadOlder = document.getElementById('loadOlderMessages');
+    if (loadOlder) {
+        loadOlder.addEventListener('click', async function() {
+            const first = messageList.querySelector('.message');
+            try {
+                const messages = await fetchMessages(first ? first.getAttribute('data-message-id') : 0);
+                messages.reverse().forEach(m => messageList.insertBefore(renderMessage(m), messageList.firstChild));
+                if (messages.length < 50) {
+                    loadOlder.remove();
+                }
+            } catch (error) {
+                showToast(error.message);
+            }
+        });
+    }
+
+    const sendForm = document.getElementById('sendMessageForm');
+    sendForm.addEventListener('submit', async function(e) {
+        e.preventDefault();
+        const button = sendForm.querySelector('button');
+        button.disabled = true;
+        try {
+            const form = new FormData(sendForm);
+            form.append('conversation_id', conversationID);
+            if (!form.get('attachment').size) {
+                form.delete('attachment');
+            }
+            await postForm('/messages/send', form);
+            sendForm.reset();
+            await refresh();
+            messageList.scrollTop = messageList.scrollHeight;
+        } catch (error) {
+            showToast(error.message);
+        } finally {
+            button.disabled = false;
+        }
+    });
+});
@@ -0,0 +1,84 @@
+document.addEventListener('DOMContentLoaded', function() {
+    const pollElement = document.getElementById('poll');
+    if (!pollElement) return;
+
+    const postId = pollElement.getAttribute('data-post-id');
+    const pollForm = document.getElementById('pollForm');
+
+    // Render the tallies returned by /poll and /pollVote
+    function renderPoll(poll) {
+        pollElement.setAttribute('data-results-visible', poll.resultsVisible);
+        poll.options.forEach(option => {
+            const label = pollElement.querySelector(`.poll-option[data-option-id="${option.id}"]`);
+            if (!label) return;
+            label.classList.toggle('chosen', option.chosen);
+            label.querySelector('input').checked = option.chosen;
+            label.querySelector('input').disabled = poll.hasVoted || poll.isClosed;
+            label.querySelector('.poll-bar').style.width = poll.resultsVisible ? `${option.percent}%` : '0';
+            label.querySelector('.poll-option-result').textContent =
+                poll.resultsVisible ? `${option.percent}% (${option.votes})` : '';
+        });
+
+        const status = pollElement.querySelector('.poll-status');
+        if (poll.resultsVisible) {
+            status.textContent = `${poll.totalVoters} voter${poll.totalVoters === 1 ? '' : 's'}` +
+                (poll.isClosed ? ' · Poll closed' : '');
+        }
+
+        const voteButton = pollElement.querySelector('.poll-vote-button');
+        if (voteButton && (poll.hasVoted || poll.isClosed)) {
+            voteButton.remove();
+        }
+    }
+
+    async function refreshPoll() {
+        try {
+            const response = await fetch(`/poll?post_id=${postId}`);
+            if (response.ok) {
+                renderPoll(await response.json());
+            }
+        } catch (error) {
+            console.error('Error refreshing poll:', error);
+        }
+    }
+
+    pollForm.addEventListener('submit', async function(e) {
+        e.preventDefault();
+
+        const chosen = Array.from(pollForm.querySelectorAll('input[name="option_id"]:checked'));
+        if (chosen.length === 0) {
+            showToast('Choose an option first');
+            return;
+        }
+
+        const body = new URLSearchParams({ 'post_id': postId });
+        chosen.forEach(input => body.append('option_id', input.value));
+
+        try {
+            const response = await fetch('/pollVote', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/x-www-form-urlencoded',
+                    'X-CSRF-Token': document.querySelector('meta[name="csrf-token"]').content
+                },
+                body: body
+            });
+            const data = await response.json();
+            if (response.ok) {
+                renderPoll(data);
+            } else {
+                showToast(data.detail || 'Failed to record vote');
+            }
+        } catch (error) {
+            console.error('Error voting in poll:', error);
+            showToast('An error occurred while voting');
+        }
+    });
+
+    // Keep visible results live
+    setInterval(function() {
+        if (pollElement.getAttribute('data-results-visible') === 'true') {
+            refreshPoll();
+        }
+    }, 30000);
+});
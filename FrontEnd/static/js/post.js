@@ -4,6 +4,10 @@ document.addEventListener('DOMContentLoaded', function() {
     const mediaTab = document.getElementById('media-tab');
     const textContent = document.getElementById('text-content');
     const mediaContent = document.getElementById('media-content');
+    const pollTab = document.getElementById('poll-tab');
+    const pollContent = document.getElementById('poll-content');
+    const pollOptions = document.getElementById('poll-options');
+    const maxPollOptions = 10;
     const categorySelect = document.getElementById('category-select');
     const selectedCategories = document.getElementById('selected-categories');
     const selectedCats = new Set();
@@ -12,18 +16,34 @@ document.addEventListener('DOMContentLoaded', function() {
     let uploadedFiles = new Set();
 
     // Tab switching
-    textTab.addEventListener('click', () => {
-        textTab.classList.add('active');
-        mediaTab.classList.remove('active');
-        textContent.classList.add('active');
-        mediaContent.classList.remove('active');
+    const tabs = [
+        [textTab, textContent],
+        [mediaTab, mediaContent],
+        [pollTab, pollContent]
+    ];
+    tabs.forEach(([tab, content]) => {
+        tab.addEventListener('click', () => {
+            tabs.forEach(([otherTab, otherContent]) => {
+                otherTab.classList.toggle('active', otherTab === tab);
+                otherContent.classList.toggle('active', otherContent === content);
+            });
+        });
     });
 
-    mediaTab.addEventListener('click', () => {
-        mediaTab.classList.add('active');
-        textTab.classList.remove('active');
-        mediaContent.classList.add('active');
-        textContent.classList.remove('active');
+    // Poll options
+    document.getElementById('add-poll-option').addEventListener('click', () => {
+        const count = pollOptions.querySelectorAll('.poll-option-input').length;
+        if (count >= maxPollOptions) {
+            showToast(`A poll can have at most ${maxPollOptions} options`);
+            return;
+        }
+        const input = document.createElement('input');
+        input.type = 'text';
+        input.className = 'input-field poll-option-input';
+        input.placeholder = `Option ${count + 1}`;
+        input.maxLength = 200;
+        pollOptions.appendChild(input);
+        input.focus();
     });
 
     // Category handling
@@ -136,9 +156,17 @@ document.addEventListener('DOMContentLoaded', function() {
             return;
         }
 
-        // Check if at least one of content or file is provided
-        if (!content && (!fileInput || fileInput.files.length === 0)) {
-            showToast('Please provide either text content or an image');
+        const pollOptionValues = Array.from(pollOptions.querySelectorAll('.poll-option-input'))
+            .map(input => input.value.trim())
+            .filter(value => value !== '');
+        if (pollOptionValues.length === 1) {
+            showToast('A poll needs at least two options');
+            return;
+        }
+
+        // Check if at least one of content, file or poll is provided
+        if (!content && (!fileInput || fileInput.files.length === 0) && pollOptionValues.length === 0) {
+            showToast('Please provide either text content, an image or a poll');
             return;
         }
     
@@ -148,6 +176,17 @@ document.addEventListener('DOMContentLoaded', function() {
         formData.append('content', content);
         formData.append('category', categories.join(",")); 
 
+        // Append the poll if options were given
+        if (pollOptionValues.length > 0) {
+            pollOptionValues.forEach(option => formData.append('poll-option', option));
+            formData.append('poll-question', document.getElementById('poll-question').value.trim());
+            formData.append('poll-multiple', document.getElementById('poll-multiple').checked);
+            const closesAt = document.getElementById('poll-closes-at').value;
+            if (closesAt) {
+                formData.append('poll-closes-at', new Date(closesAt).toISOString());
+            }
+        }
+
 
         // Append the file if selected
         if (fileInput.files.length > 0) {
@@ -172,7 +211,7 @@ document.addEventListener('DOMContentLoaded', function() {
             if (response.ok) {
                 window.location.href = '/';
             } else {
-                showToast(data.error || 'Failed to create post');
+                showToast(data.detail || 'Failed to create post');
             }
         } catch (error) {
             console.error('Error:', error);
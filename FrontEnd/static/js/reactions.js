@@ -0,0 +1,116 @@
+// Emoji reactions on posts and comments; listeners are delegated so that comments loaded later work too
+document.addEventListener('DOMContentLoaded', function() {
+    function notify(message) {
+        const toast = document.getElementById('toast');
+        const toastMessage = document.getElementById('toastMessage');
+        if (!toast || !toastMessage) {
+            return;
+        }
+        toastMessage.textContent = message;
+        toast.classList.add('show');
+        setTimeout(() => toast.classList.remove('show'), 3000);
+    }
+
+    function updateCounts(bar, reactions) {
+        reactions.forEach(reaction => {
+            bar.querySelectorAll('.reaction-button').forEach(button => {
+                if (button.dataset.reaction !== reaction.reaction) {
+                    return;
+                }
+                button.classList.toggle('active', reaction.reacted);
+                button.querySelector('.reaction-count').textContent = reaction.count;
+            });
+        });
+    }
+
+    async function react(bar, button) {
+        const csrf = document.querySelector('meta[name="csrf-token"]');
+        if (!csrf || !csrf.content) {
+            notify('Please log in to react');
+            return;
+        }
+
+        button.disabled = true;
+        try {
+            const response = await fetch('/react', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/x-www-form-urlencoded',
+                    'X-CSRF-Token': csrf.content
+                },
+                body: new URLSearchParams({
+                    'type': bar.dataset.reactionType,
+                    'id': bar.dataset.reactionId,
+                    'reaction': button.dataset.reaction
+                })
+            });
+            const data = await response.json();
+            if (!response.ok) {
+                notify(data.detail || 'Failed to update reaction');
+                return;
+            }
+            updateCounts(bar, data.reactions);
+            const list = bar.querySelector('.reactors-list');
+            if (!list.classList.contains('hidden')) {
+                showReactors(bar);
+            }
+        } catch (error) {
+            console.error('Error reacting:', error);
+            notify('An error occurred while reacting');
+        } finally {
+            button.disabled = false;
+        }
+    }
+
+    async function showReactors(bar) {
+        const list = bar.querySelector('.reactors-list');
+        const params = new URLSearchParams({ type: bar.dataset.reactionType, id: bar.dataset.reactionId });
+        try {
+            const response = await fetch(`/reactions?${params}`);
+            const data = await response.json();
+            if (!response.ok) {
+                notify(data.detail || 'Failed to load reactions');
+                return;
+            }
+            list.innerHTML = '';
+            if (data.reactors.length === 0) {
+                const item = document.createElement('li');
+                item.textContent = 'No reactions yet';
+                list.appendChild(item);
+            }
+            data.reactors.forEach(reactor => {
+                const item = document.createElement('li');
+                const link = document.createElement('a');
+                link.href = `/profile?username=${encodeURIComponent(reactor.username)}`;
+                link.textContent = reactor.username;
+                item.append(`${reactor.reaction} `, link);
+                list.appendChild(item);
+            });
+            list.classList.remove('hidden');
+        } catch (error) {
+            console.error('Error loading reactions:', error);
+            notify('An error occurred while loading reactions');
+        }
+    }
+
+    document.addEventListener('click', function(event) {
+        const button = event.target.closest('.reaction-button, .reactors-button');
+        if (!button) {
+            return;
+        }
+        event.preventDefault();
+        event.stopPropagation();
+
+        const bar = button.closest('.reactions');
+        if (button.classList.contains('reaction-button')) {
+            react(bar, button);
+            return;
+        }
+        const list = bar.querySelector('.reactors-list');
+        if (list.classList.contains('hidden')) {
+            showReactors(bar);
+        } else {
+            list.classList.add('hidden');
+        }
+    });
+});
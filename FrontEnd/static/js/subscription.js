@@ -0,0 +1,76 @@
+document.addEventListener('DOMContentLoaded', function() {
+    function notify(message) {
+        const toast = document.getElementById('toast');
+        const toastMessage = document.getElementById('toastMessage');
+        if (!toast || !toastMessage) {
+            return;
+        }
+        toastMessage.textContent = message;
+        toast.classList.add('show');
+        setTimeout(() => toast.classList.remove('show'), 3000);
+    }
+
+    async function subscribe(params) {
+        const response = await fetch('/subscribe', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/x-www-form-urlencoded',
+                'X-CSRF-Token': document.querySelector('meta[name="csrf-token"]').content
+            },
+            body: new URLSearchParams(params)
+        });
+        const data = await response.json();
+        if (!response.ok) {
+            throw new Error(data.detail || 'Failed to update subscription');
+        }
+        return data.level;
+    }
+
+    const messages = {
+        '': 'Unsubscribed',
+        'all': 'Watching all activity',
+        'replies': 'Watching replies to you',
+        'muted': 'Muted'
+    };
+
+    // Watch level selects on threads and on the subscriptions page
+    document.querySelectorAll('.watch-level').forEach(select => {
+        let previous = select.value;
+        select.addEventListener('change', async function() {
+            const params = { 'level': select.value };
+            if (select.hasAttribute('data-post-id')) {
+                params['post_id'] = select.getAttribute('data-post-id');
+            } else {
+                params['category'] = select.getAttribute('data-category');
+            }
+
+            try {
+                const level = await subscribe(params);
+                previous = level;
+                notify(messages[level]);
+                if (level === '' && select.closest('.subscription-item')) {
+                    select.closest('.subscription-item').remove();
+                }
+            } catch (error) {
+                select.value = previous;
+                notify(error.message);
+            }
+        });
+    });
+
+    const categoryForm = document.getElementById('categorySubscribeForm');
+    if (categoryForm) {
+        categoryForm.addEventListener('submit', async function(e) {
+            e.preventDefault();
+            try {
+                await subscribe({
+                    'category': categoryForm.elements['category'].value,
+                    'level': categoryForm.elements['level'].value
+                });
+                window.location.reload();
+            } catch (error) {
+                notify(error.message);
+            }
+        });
+    }
+});
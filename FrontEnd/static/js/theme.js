@@ -137,7 +137,7 @@ document.querySelectorAll('.delete-post-btn').forEach(button => {
       if (!confirm('Are you sure you want to delete this comment?')) return;
 
       try {
-          const response = await fetch(`/deletePost?id=${postId}`, {
+          const response = await fetch(`/posts/${postId}`, {
               method: 'DELETE',
               headers: {
                   'X-CSRF-Token': document.querySelector('meta[name="csrf-token"]').getAttribute('content')
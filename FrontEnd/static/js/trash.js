@@ -0,0 +1,42 @@
+document.addEventListener('DOMContentLoaded', function() {
+    function showToast(message) {
+        const toast = document.getElementById('toast');
+        const toastMessage = document.getElementById('toastMessage');
+        toastMessage.textContent = message;
+        toast.classList.add('show');
+        setTimeout(() => toast.classList.remove('show'), 3000);
+    }
+
+    document.querySelectorAll('.trash-item .restore-button').forEach(button => {
+        button.addEventListener('click', async function() {
+            const item = button.closest('.trash-item');
+            button.disabled = true;
+
+            try {
+                const response = await fetch('/trash/restore', {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/x-www-form-urlencoded',
+                        'X-CSRF-Token': document.querySelector('meta[name="csrf-token"]').content
+                    },
+                    body: new URLSearchParams({
+                        'type': item.getAttribute('data-type'),
+                        'id': item.getAttribute('data-id')
+                    })
+                });
+                const data = await response.json();
+                if (response.ok) {
+                    item.remove();
+                    showToast('Restored successfully');
+                } else {
+                    showToast(data.detail || 'Failed to restore item');
+                    button.disabled = false;
+                }
+            } catch (error) {
+                console.error('Error restoring item:', error);
+                showToast('An error occurred while restoring the item');
+                button.disabled = false;
+            }
+        });
+    });
+});
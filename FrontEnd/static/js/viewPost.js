@@ -48,6 +48,9 @@ document.addEventListener('DOMContentLoaded', function() {
         });
     }
 
+    // Lazily loaded comments refresh their timestamps through this
+    window.updateTimestamps = updateTimestamps;
+
     // Initial update of timestamps
     updateTimestamps();
     // Update timestamps every minute
@@ -104,32 +107,6 @@ document.addEventListener('DOMContentLoaded', function() {
             });
         }
     });
-
-    // Hide reply buttons when max depth is reached
-    document.querySelectorAll('.comment').forEach(comment => {
-        // Calculate comment depth by counting parent comments
-        let depth = 0;
-        let parent = comment;
-        while (parent.parentElement.closest('.comment')) {
-            depth++;
-            parent = parent.parentElement.closest('.comment');
-        }
-        
-        // Hide reply button and form if max depth reached
-        if (depth >= 4) {
-            // Hide reply button
-            const replyButton = comment.querySelector('.reply-button');
-            if (replyButton) {
-                replyButton.remove(); 
-            }
-            
-            // Hide reply form container
-            const replyForm = comment.querySelector('.reply-input-container');
-            if (replyForm) {
-                replyForm.remove(); 
-            }
-        }
-    });
 });
 
 // Move submitComment outside DOMContentLoaded to make it globally available
@@ -156,7 +133,7 @@ window.submitComment = async function(button) {
     }
 
     try {
-        const response = await fetch(`/comment/${postId}`, {
+        const response = await fetch(`/posts/${postId}/comments`, {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
@@ -179,7 +156,7 @@ window.submitComment = async function(button) {
             window.location.reload();
         } else {
             const data = await response.json();
-            showToast(data.error || 'Failed to post comment');
+            showToast(data.detail || 'Failed to post comment');
             button.disabled = false;  // Re-enable the button on error
         }
     } catch (error) {
@@ -205,11 +182,46 @@ window.showReplyForm = function(button) {
     replyForm.style.display = replyForm.style.display === 'block' ? 'none' : 'block';
 };
 
+// quoteReply opens the reply form with an excerpt of the comment: the text selected in it, or all of it
+window.quoteReply = function(button) {
+    const commentId = button.getAttribute('data-comment-id');
+    const contentDiv = document.getElementById(`comment-content-${commentId}`);
+    const selection = window.getSelection();
+    let excerpt = '';
+    if (selection && selection.rangeCount > 0 && contentDiv.contains(selection.anchorNode) && contentDiv.contains(selection.focusNode)) {
+        excerpt = selection.toString().trim();
+    }
+    if (!excerpt) {
+        excerpt = contentDiv.textContent.trim();
+    }
+
+    const quote = document.getElementById(`reply-quote-${commentId}`);
+    quote.textContent = excerpt;
+    quote.dataset.quote = excerpt;
+    quote.classList.remove('hidden');
+
+    const replyForm = document.getElementById(`reply-form-${commentId}`);
+    if (replyForm.style.display !== 'block') {
+        showReplyForm(button);
+    }
+    document.getElementById(`replyText-${commentId}`).focus();
+};
+
+function clearQuote(commentId) {
+    const quote = document.getElementById(`reply-quote-${commentId}`);
+    if (quote) {
+        quote.textContent = '';
+        delete quote.dataset.quote;
+        quote.classList.add('hidden');
+    }
+}
+
 window.cancelReply = function(button) {
     const commentId = button.getAttribute('data-comment-id');
     const replyForm = document.getElementById(`reply-form-${commentId}`);
     const replyInput = document.getElementById(`replyText-${commentId}`);
     replyInput.value = '';
+    clearQuote(commentId);
     replyForm.style.display = 'none';
 };
 
@@ -224,7 +236,7 @@ window.submitReply = async function(button) {
     }
 
     try {
-        const response = await fetch(`/comment/${postId}`, {
+        const response = await fetch(`/posts/${postId}/comments`, {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
@@ -232,7 +244,8 @@ window.submitReply = async function(button) {
             },
             body: JSON.stringify({ 
                 content: content,
-                parentId: parseInt(commentId, 10)
+                parentId: parseInt(commentId, 10),
+                quote: document.getElementById(`reply-quote-${commentId}`).dataset.quote || ''
             })
         });
 
@@ -240,7 +253,7 @@ window.submitReply = async function(button) {
             location.reload();
         } else {
             const data = await response.json();
-            showToast(data.error || 'Failed to post reply');
+            showToast(data.detail || 'Failed to post reply');
         }
     } catch (error) {
         console.error('Error:', error);
@@ -253,7 +266,7 @@ async function deleteComment(commentId) {
     if (!confirm('Are you sure you want to delete this comment?')) return;
 
     try {
-        const response = await fetch(`/deleteComment?id=${commentId}`, {
+        const response = await fetch(`/comments/${commentId}`, {
             method: 'DELETE',
             headers: {
                 'X-CSRF-Token': document.querySelector('input[name="csrf_token"]').value
@@ -311,8 +324,8 @@ async function saveEdit(commentId) {
     const editedContent = document.getElementById(`edit-${commentId}`).value;
 
     try {
-        const response = await fetch(`/updateComment?id=${commentId}`, {
-            method: 'POST',
+        const response = await fetch(`/comments/${commentId}`, {
+            method: 'PUT',
             headers: {
                 'Content-Type': 'application/json',
                 'X-CSRF-Token': document.querySelector('input[name="csrf_token"]').value
@@ -327,7 +340,7 @@ async function saveEdit(commentId) {
             showToast('Comment updated successfully');
         } else {
             const data = await response.json();
-            showToast(data.error || 'Failed to save the edited comment');
+            showToast(data.detail || 'Failed to save the edited comment');
         }
     } catch (error) {
         console.error('Error:', error);
@@ -353,4 +366,42 @@ document.addEventListener('DOMContentLoaded', () => {
             button.addEventListener('click', () => editComment(commentId));
         }
     });
-});
\ No newline at end of file
+});
+// Moderator thread controls
+document.addEventListener('DOMContentLoaded', () => {
+    const moderation = document.querySelector('.thread-moderation');
+    if (!moderation) return;
+
+    moderation.querySelectorAll('button[data-action]').forEach(button => {
+        button.addEventListener('click', async () => {
+            const body = new URLSearchParams({
+                'post_id': moderation.getAttribute('data-post-id'),
+                'action': button.getAttribute('data-action')
+            });
+            const scope = moderation.querySelector('.thread-pin-scope');
+            if (button.getAttribute('data-action') === 'pin' && scope) {
+                body.append('category', scope.value);
+            }
+
+            try {
+                const response = await fetch('/moderation/thread', {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/x-www-form-urlencoded',
+                        'X-CSRF-Token': document.querySelector('meta[name="csrf-token"]').content
+                    },
+                    body: body
+                });
+                if (response.ok) {
+                    window.location.reload();
+                } else {
+                    const data = await response.json();
+                    showToast(data.detail || 'Failed to update thread');
+                }
+            } catch (error) {
+                console.error('Error updating thread:', error);
+                showToast('An error occurred while updating the thread');
+            }
+        });
+    });
+});
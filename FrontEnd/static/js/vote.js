@@ -82,6 +82,9 @@ function handleVote(voteType) {
                 }
                 // showToast(`Post ${voteType}d successfully!`);
                 toggleButtonStates(postId, voteType);
+            } else {
+                const data = await response.json().catch(() => ({}));
+                showToast(data.detail || `Failed to ${voteType} the post`);
             }
         } catch (error) {
             console.error('Error:', error);
@@ -124,7 +127,8 @@ async function handleCommentVote(event, isAuthenticated) {
             toggleCommentButtonStates(commentId, voteType);
             // showToast('Vote recorded successfully');
         } else {
-            showToast('Failed to vote');
+            const data = await response.json().catch(() => ({}));
+            showToast(data.detail || 'Failed to vote');
         }
     } catch (error) {
         console.error('Error:', error);
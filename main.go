@@ -11,26 +11,52 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/Raymond9734/forum.git/BackEnd/config"
 	"github.com/Raymond9734/forum.git/BackEnd/controllers"
 	"github.com/Raymond9734/forum.git/BackEnd/database"
+	"github.com/Raymond9734/forum.git/BackEnd/handlers"
 	"github.com/Raymond9734/forum.git/BackEnd/logger"
+	"github.com/Raymond9734/forum.git/BackEnd/metrics"
+	"github.com/Raymond9734/forum.git/BackEnd/middleware"
 	"github.com/Raymond9734/forum.git/BackEnd/routes"
+	"github.com/Raymond9734/forum.git/BackEnd/storage"
 )
 
 func main() {
 	// Initialize logger
-	if err := logger.Init(); err != nil {
+	if err := logger.Init(logger.Options{
+		Dir:         config.String("FORUM_LOG_DIR", "logs"),
+		Level:       config.String("FORUM_LOG_LEVEL", "info"),
+		MaxSize:     config.Bytes("FORUM_LOG_MAX_SIZE", 100<<20),
+		RotateEvery: config.Duration("FORUM_LOG_ROTATE_EVERY", 24*time.Hour),
+		MaxBackups:  config.Int("FORUM_LOG_MAX_BACKUPS", 14),
+		MaxAge:      config.Duration("FORUM_LOG_MAX_AGE", 0),
+	}); err != nil {
 		log.Fatal(err)
 	}
+	defer logger.Close()
 
 	logger.Info("Starting application...")
 
 	db, err := database.Init("Development")
 	if err != nil {
+		logger.Error("Failed to initialize database: %v", err)
 		fmt.Println("An error occured while initializing Database")
 		os.Exit(1)
 	}
-	log.Println("Database initialized successfully")
+	logger.Info("Database initialized successfully")
+
+	// Grant the admin role to the configured usernames
+	if err := controllers.PromoteAdmins(db, config.List("FORUM_ADMIN_USERS")); err != nil {
+		logger.Error("Failed to promote admins: %v", err)
+	}
+
+	// Initialize upload storage
+	if _, err := storage.Init(); err != nil {
+		logger.Error("Failed to initialize upload storage: %v", err)
+		fmt.Println("An error occured while initializing upload storage")
+		os.Exit(1)
+	}
 
 	// Create a context that cancels on interrupt signals (e.g., Ctrl+C)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -52,6 +78,30 @@ func main() {
 		controllers.CleanupExpiredSessions(ctx, db)
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		controllers.CollectOrphanedUploads(ctx, db)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		controllers.ArchiveInactiveThreads(ctx, db)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		controllers.PurgeDeletedContent(ctx, db)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		controllers.DetectVoteManipulation(ctx, db)
+	}()
+
 	// Update your server configuration
 	server := &http.Server{
 		Addr:              ":8080",          // Listen on port 8080
@@ -62,18 +112,31 @@ func main() {
 		MaxHeaderBytes:    1 << 20,          // Max size of request headers (1 MB)
 	}
 
-	routes.HomeRoute(db)
-	routes.ServeStaticFolder()
-	routes.UserRegAndLogin(db)
-	routes.PostRoutes(db)
-	routes.CommentRoute(db)
-	routes.LikesRoutes(db)
+	router := routes.NewRouter()
+	server.Handler = middleware.RequestID(router)
+
+	routes.Register(router, db, config.Duration("FORUM_READY_TIMEOUT", 2*time.Second))
+	metrics.RegisterDBStats(db)
+
+	// Serve the metrics on a listener of their own for scrapers, if one is configured
+	var metricsServer *http.Server
+	if addr := config.String("FORUM_METRICS_ADDR", ""); addr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /metrics", metrics.Handler())
+		metricsServer = &http.Server{Addr: addr, Handler: metricsMux, ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			logger.Info("Metrics served at http://%s/metrics", addr)
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("Metrics server failed: %v", err)
+			}
+		}()
+	}
 
 	// Run the server in a goroutine
 	go func() {
-		log.Println("Server running at http://localhost:8080")
+		logger.Info("Server running at http://localhost:8080")
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("Server failed to start: %v\n", err)
+			logger.Error("Server failed to start: %v", err)
 		}
 	}()
 
@@ -82,10 +145,20 @@ func main() {
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
 
+	// Fail readiness first, giving load balancers time to stop sending requests before the server closes
+	handlers.BeginShutdown()
+	if delay := config.Duration("FORUM_SHUTDOWN_DELAY", 0); delay > 0 {
+		logger.Info("Draining for %s before shutting down...", delay)
+		time.Sleep(delay)
+	}
+
 	// Shutdown the server gracefully
-	log.Println("Shutting down server...")
+	logger.Info("Shutting down server...")
 	if err := server.Shutdown(ctx); err != nil {
-		log.Printf("Server shutdown error: %v\n", err)
+		logger.Error("Server shutdown error: %v", err)
+	}
+	if metricsServer != nil {
+		metricsServer.Shutdown(ctx)
 	}
 
 	// Cancel the context to signal cleanup tasks to stop
@@ -94,5 +167,5 @@ func main() {
 	// Wait for cleanup tasks to finish
 	wg.Wait()
 
-	log.Println("Application stopped gracefully")
+	logger.Info("Application stopped gracefully")
 }